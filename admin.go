@@ -0,0 +1,163 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// A user of the server and its status, returned by the admin API
+type UserResponse struct {
+	Name string `json:"name"`
+	StatusResponse
+}
+
+// The time spent by the users of the server, returned by the admin API
+type TeamReportResponse struct {
+	Since        string                `json:"since"`
+	Until        string                `json:"until"`
+	TotalSeconds int64                 `json:"total_seconds"`
+	Projects     []TeamProjectResponse `json:"projects"`
+	People       []TeamPersonResponse  `json:"people"`
+}
+
+type TeamProjectResponse struct {
+	Name    string           `json:"name"`
+	Seconds int64            `json:"seconds"`
+	People  map[string]int64 `json:"people"`
+	Tickets []ReportTicket   `json:"tickets"`
+}
+
+type TeamPersonResponse struct {
+	Name     string            `json:"name"`
+	Seconds  int64             `json:"seconds"`
+	Projects []ProjectResponse `json:"projects"`
+}
+
+type ProjectResponse struct {
+	Name    string `json:"name"`
+	Seconds int64  `json:"seconds"`
+}
+
+// The roles of the users of mate serve, each one having the rights of the previous ones
+var ROLES = []string{"user", "reporter", "admin"}
+
+// Only lets the requests of the tokens with the given role or a higher one through
+func requireRole(role string, handler APIHandler) APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if indexOf(ROLES, serverRole) < indexOf(ROLES, role) {
+			writeError(w, http.StatusForbidden, "reserved to the "+role+"s of the server")
+			return nil
+		}
+		return handler(w, r)
+	}
+}
+
+// Returns the position of a string in a slice, -1 if missing
+func indexOf(s []string, e string) int {
+	for i, a := range s {
+		if a == e {
+			return i
+		}
+	}
+	return -1
+}
+
+// Reads the entries of every user of the server, keyed by name, their timers being applied first
+func getUsersRecords(accounts ServerAccounts) (map[string][]Record, error) {
+	members := make(map[string][]Record)
+	for _, name := range accounts.names() {
+		err := runAsUser(name, func() error {
+			if err := reconcileTimer(); err != nil {
+				return err
+			}
+			records, err := getRecords()
+			members[name] = records
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return members, nil
+}
+
+// Answers with the users and their status
+func handleTeamUsers(accounts ServerAccounts) APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		members, err := getUsersRecords(accounts)
+		if err != nil {
+			return err
+		}
+		users := []UserResponse{}
+		for _, name := range accounts.names() {
+			users = append(users, UserResponse{name, getStatus(members[name])})
+		}
+		writeJSON(w, http.StatusOK, users)
+		return nil
+	}
+}
+
+// Answers with the time spent per project and per user between the since and until parameters
+// A user only gets the titles of its own tickets and of the users sharing theirs ([sharing] of the config)
+func handleTeamReport(accounts ServerAccounts) APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		start, end, err := getTeamPeriod(r.URL.Query().Get("since"), r.URL.Query().Get("until"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return nil
+		}
+		viewer := serverUser
+		members, err := getUsersRecords(accounts)
+		if err != nil {
+			return err
+		}
+		report := computeTeamReport(members, start, end)
+		if serverRole == "user" {
+			report = report.hideTitles(func(person string) bool {
+				return person == viewer || getConfigBool("sharing."+person, false)
+			})
+		}
+		writeJSON(w, http.StatusOK, getTeamReportResponse(report))
+		return nil
+	}
+}
+
+func getTeamReportResponse(report TeamReport) TeamReportResponse {
+	response := TeamReportResponse{
+		Since:        formatAPIDate(report.first),
+		Until:        formatAPIDate(report.last),
+		TotalSeconds: int64(report.total.Seconds()),
+		Projects:     []TeamProjectResponse{},
+		People:       []TeamPersonResponse{},
+	}
+	for _, project := range report.projects {
+		projectResponse := TeamProjectResponse{project.name, int64(project.total.Seconds()), make(map[string]int64), []ReportTicket{}}
+		for _, ticket := range project.tickets {
+			projectResponse.Tickets = append(projectResponse.Tickets, ReportTicket{ticket.title, int64(ticket.duration.Seconds())})
+		}
+		for _, person := range report.people {
+			for _, personProject := range person.projects {
+				if personProject.name == project.name {
+					projectResponse.People[person.name] = int64(personProject.total.Seconds())
+				}
+			}
+		}
+		response.Projects = append(response.Projects, projectResponse)
+	}
+	for _, person := range report.people {
+		personResponse := TeamPersonResponse{person.name, int64(person.total.Seconds()), []ProjectResponse{}}
+		for _, project := range person.projects {
+			personResponse.Projects = append(personResponse.Projects, ProjectResponse{project.name, int64(project.total.Seconds())})
+		}
+		response.People = append(response.People, personResponse)
+	}
+	return response
+}
+
+// Formats a day of the API, empty for a zero time
+func formatAPIDate(day time.Time) string {
+	if day.IsZero() {
+		return ""
+	}
+	return day.Format("2006-01-02")
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Loads the accounts of a server with a user, a reporter and an admin
+func useTestAccounts(t *testing.T) ServerAccounts {
+	config["users.alice"] = "alice-token"
+	config["users.bob"] = "bob-token"
+	config["users.carol"] = "carol-token"
+	config["roles.bob"] = "reporter"
+	config["roles.carol"] = "admin"
+	accounts, err := loadServerAccounts("owner-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return accounts
+}
+
+func TestRequireTokenRoles(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		path       string
+		reporter   bool
+		wantStatus int
+		wantUser   string
+		wantRole   string
+	}{
+		{"no token", "", "/api/status", false, http.StatusUnauthorized, "", ""},
+		{"wrong token", "nope", "/api/status", false, http.StatusUnauthorized, "", ""},
+		{"owner", "owner-token", "/api/status", false, http.StatusOK, "", "admin"},
+		{"user", "alice-token", "/api/status", false, http.StatusOK, "alice", "user"},
+		{"user as itself", "alice-token", "/api/status?user=alice", false, http.StatusOK, "alice", "user"},
+		{"user as another", "alice-token", "/api/status?user=bob", false, http.StatusForbidden, "", ""},
+		{"reporter as another", "bob-token", "/api/status?user=alice", false, http.StatusForbidden, "", ""},
+		{"admin as another", "carol-token", "/api/status?user=alice", false, http.StatusOK, "alice", "admin"},
+		{"owner as another", "owner-token", "/api/status?user=bob", false, http.StatusOK, "bob", "admin"},
+		{"admin as unknown", "carol-token", "/api/status?user=dave", false, http.StatusNotFound, "", ""},
+		{"user on reporter endpoint", "alice-token", "/api/team/users", true, http.StatusForbidden, "", ""},
+		{"reporter on reporter endpoint", "bob-token", "/api/team/users", true, http.StatusOK, "bob", "reporter"},
+		{"admin on reporter endpoint", "carol-token", "/api/team/users", true, http.StatusOK, "carol", "admin"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, "")
+			accounts := useTestAccounts(t)
+			var served bool
+			var gotUser, gotRole string
+			var handler APIHandler = func(w http.ResponseWriter, r *http.Request) error {
+				served, gotUser, gotRole = true, serverUser, serverRole
+				w.WriteHeader(http.StatusOK)
+				return nil
+			}
+			if test.reporter {
+				handler = requireRole("reporter", handler)
+			}
+
+			request := httptest.NewRequest("GET", test.path, nil)
+			if test.token != "" {
+				request.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			response := httptest.NewRecorder()
+			requireToken(accounts, handler)(response, request)
+			if response.Code != test.wantStatus {
+				t.Fatalf("got status %d, want %d", response.Code, test.wantStatus)
+			}
+			if served != (test.wantStatus == http.StatusOK) {
+				t.Fatalf("got the handler run %v", served)
+			}
+			if served && (gotUser != test.wantUser || gotRole != test.wantRole) {
+				t.Errorf("got user %q with role %q, want %q with %q", gotUser, gotRole, test.wantUser, test.wantRole)
+			}
+			if serverUser != "" || serverRole != "" {
+				t.Errorf("got user %q with role %q left after the request", serverUser, serverRole)
+			}
+		})
+	}
+}
+
+func TestServerStartFiresOwnerEventsOnly(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		wantFired bool
+	}{
+		{"owner", "owner-token", true},
+		{"user", "alice-token", false},
+		{"reporter", "bob-token", false},
+		{"admin", "carol-token", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, "")
+			posts := useWebhookServer(t)
+			accounts := useTestAccounts(t)
+
+			request := httptest.NewRequest("POST", "/api/start", strings.NewReader(`{"title": "A"}`))
+			request.Header.Set("Authorization", "Bearer "+test.token)
+			response := httptest.NewRecorder()
+			requireToken(accounts, requireMethod("POST", handleStart))(response, request)
+			if response.Code != http.StatusOK {
+				t.Fatalf("got status %d: %s", response.Code, response.Body)
+			}
+			if posted := atomic.LoadInt32(posts) != 0; posted != test.wantFired {
+				t.Errorf("got the webhook posted %v, want %v", posted, test.wantFired)
+			}
+		})
+	}
+}
+
+func TestHideTitles(t *testing.T) {
+	members := map[string][]Record{
+		"alice": {{parseTestTime(t, "2026/10/14 09:00:00"), "ALPHA-1 Secret"}, {parseTestTime(t, "2026/10/14 10:00:00"), STOP_TOKEN}},
+		"bob":   {{parseTestTime(t, "2026/10/14 09:00:00"), "BETA-2 Shared"}, {parseTestTime(t, "2026/10/14 11:00:00"), STOP_TOKEN}},
+	}
+	useMemoryStore(t, "")
+	report := computeTeamReport(members, time.Time{}, time.Time{}).hideTitles(func(person string) bool { return person == "bob" })
+
+	titles := make(map[string]string)
+	for _, project := range report.projects {
+		for _, ticket := range project.tickets {
+			titles[project.name] = ticket.title
+		}
+	}
+	want := map[string]string{"ALPHA": PRIVATE_TITLE, "BETA": "BETA-2 Shared"}
+	for project, title := range want {
+		if titles[project] != title {
+			t.Errorf("project %s: got %q, want %q", project, titles[project], title)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A canonical title, and the pattern of the titles reported under it
+type TitleAlias struct {
+	pattern *regexp.Regexp
+	title   string
+}
+
+// Compiles the aliases of the [aliases] section, a regular expression (quoted if need be) for each canonical title:
+//
+//	[aliases]
+//	"(?i)^proj-123\b" = "PROJ-123"
+//
+// The patterns are tried in their alphabetical order
+func compileAliases(options map[string]string) (aliases []TitleAlias, err error) {
+	var keys []string
+	for key := range options {
+		if strings.HasPrefix(key, "aliases.") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		pattern, err := regexp.Compile(strings.TrimPrefix(key, "aliases."))
+		if err != nil {
+			return nil, fmt.Errorf("%s: Invalid pattern (%v)", key, err)
+		}
+		aliases = append(aliases, TitleAlias{pattern, options[key]})
+	}
+	return aliases, nil
+}
+
+// Returns the aliases of the config, validated by loadConfig
+func getAliases() []TitleAlias {
+	aliases, _ := compileAliases(config)
+	return aliases
+}
+
+// Returns the canonical title of a ticket, or the title itself if it matches no alias
+// This is done at report time, the database keeping the titles as they were typed
+func normalizeTitle(title string, aliases []TitleAlias) string {
+	if title == STOP_TOKEN {
+		return title
+	}
+	for _, alias := range aliases {
+		if alias.pattern.MatchString(title) {
+			return alias.title
+		}
+	}
+	return title
+}
+
+// Returns the records with the canonical title of their ticket
+func normalizeRecords(records []Record) []Record {
+	aliases := getAliases()
+	if len(aliases) == 0 {
+		return records
+	}
+	normalized := make([]Record, len(records))
+	for i, r := range records {
+		normalized[i] = Record{r.timestamp, normalizeTitle(r.title, aliases)}
+	}
+	return normalized
+}
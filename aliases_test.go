@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCompileAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]string
+		wantErr bool
+		want    []string
+	}{
+		{"no aliases", map[string]string{"target": "8h"}, false, nil},
+		{"in the alphabetical order", map[string]string{"aliases.b": "B", "aliases.a": "A"}, false, []string{"A", "B"}},
+		{"invalid pattern", map[string]string{"aliases.(": "A"}, true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			aliases, err := compileAliases(test.options)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if len(aliases) != len(test.want) {
+				t.Fatalf("got %v, want %v", aliases, test.want)
+			}
+			for i, alias := range aliases {
+				if alias.title != test.want[i] {
+					t.Errorf("got %v, want %v", aliases, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	aliases, err := compileAliases(map[string]string{
+		`aliases.(?i)^proj-123\b`: "PROJ-123",
+		"aliases.(?i)standup":     "Standup",
+		"aliases.^Daily":          "Daily meeting",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"no match", "Fix login", "Fix login"},
+		{"match", "proj-123 fix the tests", "PROJ-123"},
+		{"canonical title", "PROJ-123", "PROJ-123"},
+		{"word boundary", "PROJ-1234", "PROJ-1234"},
+		// "(?i)standup" sorts before "^Daily"
+		{"first pattern winning", "Daily standup", "Standup"},
+		{"stop", STOP_TOKEN, STOP_TOKEN},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := normalizeTitle(test.title, aliases); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const API_TIMEOUT = time.Second * 30
+
+// Sends a JSON request to a REST API and decodes its JSON response into out (unless nil)
+// The headers (e.g. Authorization) are added to the request
+func callJSONAPI(method string, url string, headers map[string]string, body interface{}, out interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(content)
+	}
+
+	request, err := http.NewRequest(method, url, payload)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	client := http.Client{Timeout: API_TIMEOUT}
+	start := time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		err = redactURLError(err)
+		debugHTTPCall(method, url, 0, start, err)
+		return err
+	}
+	defer response.Body.Close()
+	debugHTTPCall(method, url, response.StatusCode, start, nil)
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("%s %s: %s %s", method, redactURL(url), response.Status, redactHeaders(strings.TrimSpace(string(message)), headers))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(out)
+}
+
+// Sends a request with a raw body (nil for none) and returns the status, the headers and the body of the response
+// Unlike callJSONAPI, error statuses are returned rather than turned into errors
+func callRawAPI(method string, url string, headers map[string]string, body []byte) (int, http.Header, []byte, error) {
+	var payload io.Reader
+	if body != nil {
+		payload = bytes.NewReader(body)
+	}
+	request, err := http.NewRequest(method, url, payload)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	client := http.Client{Timeout: API_TIMEOUT}
+	start := time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		err = redactURLError(err)
+		debugHTTPCall(method, url, 0, start, err)
+		return 0, nil, nil, err
+	}
+	defer response.Body.Close()
+	debugHTTPCall(method, url, response.StatusCode, start, nil)
+	content, err := io.ReadAll(response.Body)
+	return response.StatusCode, response.Header, content, err
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const AUDIT_NAME = ".mate.audit.csv"
+const AUDIT_HEADER = "operation,at,command,change,timestamp,title\n"
+
+// Prefix of the lines encrypted one by one, as the audit log is only appended to
+const AUDIT_ENCRYPTED_PREFIX = "enc:"
+
+// The operation the changes are audited under: the command, or a request of "mate serve"
+var auditOperation = ""
+var auditCommand = ""
+var auditAt time.Time
+
+// A change of the database in the audit log
+type AuditChange struct {
+	operation string
+	at        time.Time
+	command   string
+	added     bool
+	record    Record
+}
+
+func getAuditPath() string {
+	return getHomeFilePath(AUDIT_NAME)
+}
+
+// Starts a new operation, the following changes being audited (and undone) together
+func beginAuditOperation(command string) {
+	auditOperation = strconv.FormatInt(time.Now().UnixNano(), 36)
+	auditCommand = command
+	auditAt = getNow()
+}
+
+// Returns the entries of from missing from to, each entry counting as many times as it appears
+func subtractRecords(from []Record, to []Record) (missing []Record) {
+	counts := make(map[string]int)
+	for _, r := range to {
+		counts[formatRecord(r)]++
+	}
+	for _, r := range from {
+		if line := formatRecord(r); counts[line] > 0 {
+			counts[line]--
+		} else {
+			missing = append(missing, r)
+		}
+	}
+	return
+}
+
+// Appends the changes between two versions of the database to the audit log
+func auditChanges(previous []Record, next []Record) error {
+	removed, added := subtractRecords(previous, next), subtractRecords(next, previous)
+	if len(removed) == 0 && len(added) == 0 {
+		return nil
+	}
+	if auditOperation == "" {
+		beginAuditOperation("mate " + strings.Join(os.Args[1:], " "))
+	}
+
+	var lines strings.Builder
+	if _, err := files.Size(getAuditPath()); os.IsNotExist(err) {
+		lines.WriteString(AUDIT_HEADER)
+	}
+	for _, change := range []struct {
+		name    string
+		records []Record
+	}{{"-", removed}, {"+", added}} {
+		for _, r := range change.records {
+			line := formatRecordFields(auditOperation, auditAt.Format(TIME_FORMAT), auditCommand, change.name,
+				r.timestamp.Format(TIME_FORMAT), r.title)
+			if isEncryptionEnabled() {
+				encrypted, err := encryptDatabase([]byte(line))
+				if err != nil {
+					return err
+				}
+				line = AUDIT_ENCRYPTED_PREFIX + base64.StdEncoding.EncodeToString(encrypted) + "\n"
+			}
+			lines.WriteString(line)
+		}
+	}
+
+	if err := files.AppendFile(getAuditPath(), []byte(lines.String()), 0644); err != nil {
+		return fmt.Errorf("Cannot write the audit log: %w", err)
+	}
+	return nil
+}
+
+// Reads the audit log, oldest change first
+func readAuditLog() (changes []AuditChange, err error) {
+	f, err := files.Open(getAuditPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read the audit log: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, AUDIT_ENCRYPTED_PREFIX) {
+			encrypted, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, AUDIT_ENCRYPTED_PREFIX))
+			if err != nil {
+				return nil, fmt.Errorf("Cannot read the audit log: %w", err)
+			}
+			decrypted, err := decryptDatabase(encrypted)
+			if err != nil {
+				return nil, err
+			}
+			line = string(decrypted)
+		}
+		fields, err := csv.NewReader(strings.NewReader(line)).Read()
+		if err != nil || len(fields) != 6 || fields[0] == "operation" {
+			continue
+		}
+		at, err := time.Parse(TIME_FORMAT, fields[1])
+		if err != nil {
+			continue
+		}
+		timestamp, err := time.Parse(TIME_FORMAT, fields[4])
+		if err != nil {
+			continue
+		}
+		changes = append(changes, AuditChange{fields[0], at, fields[2], fields[3] == "+", Record{timestamp, fields[5]}})
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Cannot read the audit log: %w", err)
+	}
+	return changes, nil
+}
+
+// Groups the changes by operation, in order
+func groupAuditChanges(changes []AuditChange) (operations [][]AuditChange) {
+	for _, change := range changes {
+		if len(operations) != 0 && operations[len(operations)-1][0].operation == change.operation {
+			operations[len(operations)-1] = append(operations[len(operations)-1], change)
+			continue
+		}
+		operations = append(operations, []AuditChange{change})
+	}
+	return
+}
+
+// Prints the last operations of the audit log, with the entries they added (+) and removed (-)
+func showHistory(limit int) error {
+	changes, err := readAuditLog()
+	if err != nil {
+		return err
+	}
+	operations := groupAuditChanges(changes)
+	if len(operations) == 0 {
+		fmt.Println("Nothing to show (yet)")
+		return nil
+	}
+	if len(operations) > limit {
+		operations = operations[len(operations)-limit:]
+	}
+	for _, operation := range operations {
+		fmt.Printf("%s\t%s\n", operation[0].at.Format(TIME_FORMAT), operation[0].command)
+		for _, change := range operation {
+			sign := "-"
+			if change.added {
+				sign = "+"
+			}
+			fmt.Printf("  %s %s %s\n", sign, change.record.timestamp.Format(TIME_FORMAT), getEntryName(change.record))
+		}
+	}
+	return nil
+}
+
+// Reverts the last operation of the audit log, which is audited in turn (undoing twice redoes)
+// Nothing is done if the entries it added were changed since
+func undoLastOperation() error {
+	changes, err := readAuditLog()
+	if err != nil {
+		return err
+	}
+	operations := groupAuditChanges(changes)
+	if len(operations) == 0 {
+		return withExitCode(EXIT_NO_DATA, errors.New("Nothing to undo"))
+	}
+	operation := operations[len(operations)-1]
+
+	var added, removed []Record
+	for _, change := range operation {
+		if change.added {
+			added = append(added, change.record)
+		} else {
+			removed = append(removed, change.record)
+		}
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	if len(subtractRecords(added, records)) != 0 {
+		return fmt.Errorf("Cannot undo \"%s\": its entries were changed since", operation[0].command)
+	}
+
+	beginAuditOperation("mate undo (" + operation[0].command + ")")
+	records = append(subtractRecords(records, added), removed...)
+	sortRecords(records)
+	if err = writeRecords(records); err != nil {
+		return err
+	}
+	fmt.Printf("Undone: %s (%d entries removed, %d restored)\n", operation[0].command, len(added), len(removed))
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The commands of the bots, / being replaced by the prefix the user typed (! where / is taken by the chat)
+const BOT_HELP = `Commands:
+/start Ticket title - starts a ticket
+/stop - stops the running ticket
+/status - shows the running ticket and the time worked today
+/today - shows the time spent per ticket today`
+
+// How long a bot waits before polling again when its chat service cannot be reached
+const BOT_RETRY_DELAY = time.Second * 10
+
+// A command sent to a bot from an allowed chat
+type BotMessage struct {
+	chat string
+	text string
+}
+
+// The bot of a chat service, for mate bot
+type ChatBot interface {
+	// Waits for the next commands of the allowed chats, those of the other chats being logged and dropped
+	poll() ([]BotMessage, error)
+	send(chat string, text string) error
+	// The allowed chats, which the daily summary is sent to
+	chats() []string
+}
+
+// Tells if a message of a chat is a command for the bots, starting with / or !
+func isBotCommand(text string) bool {
+	return strings.HasPrefix(text, "/") || strings.HasPrefix(text, "!")
+}
+
+// Runs a command sent to a chat bot, e.g. "/start PROJ-123 Fix login", returning the answer
+// A failure is answered too, for the bot to carry on
+func runBotCommand(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !isBotCommand(fields[0]) {
+		return BOT_HELP
+	}
+	prefix := fields[0][:1]
+	help := strings.ReplaceAll(BOT_HELP, "/", prefix)
+	// In groups, Telegram suffixes the commands with the name of the bot (/stop@mate_bot)
+	command := strings.ToLower(strings.SplitN(fields[0][1:], "@", 2)[0])
+	argument := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), fields[0]))
+
+	beginAuditOperation("mate bot: " + strings.TrimSpace(text))
+	answer, err := func() (string, error) {
+		if err := reconcileTimer(); err != nil {
+			return "", err
+		}
+		records, err := getRecords()
+		if err != nil {
+			return "", err
+		}
+		switch command {
+		case "start":
+			// Sent by Telegram when a chat with the bot is opened
+			if argument == "" {
+				return help, nil
+			}
+			err = startTicket(expandTitle(argument, records))
+		case "stop":
+			err = stopTicket(false)
+		case "status":
+		case "today":
+			if summary := formatDaySummary(records, getNow().Truncate(time.Hour*24)); summary != "" {
+				return summary, nil
+			}
+			return "Nothing tracked today", nil
+		default:
+			return "Unknown command " + fields[0] + "\n" + help, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if records, err = getRecords(); err != nil {
+			return "", err
+		}
+		return formatStatus("plain", getStatus(records)), nil
+	}()
+	if err != nil {
+		// Without the commands to run next, meant for a terminal
+		return "Error: " + strings.TrimSuffix(strings.SplitN(err.Error(), "\n", 2)[0], " Run:")
+	}
+	return answer
+}
+
+// Sends the summary of the day to the allowed chats once <service>.summary_at is past, if something was tracked
+func sendBotSummary(service string, bot ChatBot) error {
+	if getConfig(service+".summary_at", "") == "" {
+		return nil
+	}
+	now := getNow()
+	today := now.Truncate(time.Hour * 24)
+	if now.Before(today.Add(getConfigClock(service+".summary_at", ""))) {
+		return nil
+	}
+	records, err := getRecentRecords(today)
+	if err != nil {
+		return err
+	}
+	summary := formatDaySummary(records, today)
+	if summary == "" {
+		return nil
+	}
+	if marked, err := markNotified(service + " summary " + today.Format(DATE_FORMAT)); !marked {
+		return err
+	}
+	for _, chat := range bot.chats() {
+		if err = bot.send(chat, summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Runs the bot of a chat service until interrupted, answering the commands of the allowed chats
+func runBot(service string) error {
+	var bot ChatBot
+	var err error
+	switch service {
+	case "telegram":
+		bot, err = connectTelegramBot()
+	case "matrix":
+		bot, err = connectMatrixBot()
+	case "discord":
+		bot, err = connectDiscordBot()
+	default:
+		return newUsageError("bot", fmt.Sprintf("Unknown chat service \"%s\" (expected telegram, matrix or discord)", service))
+	}
+	if err != nil {
+		return err
+	}
+
+	for {
+		reportDaemonError(sendBotSummary(service, bot))
+		messages, err := bot.poll()
+		if err != nil {
+			reportDaemonError(err)
+			time.Sleep(BOT_RETRY_DELAY)
+			continue
+		}
+		for _, message := range messages {
+			reportDaemonError(bot.send(message.chat, runBotCommand(message.text)))
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import "time"
+
+const DEFAULT_BREAK_DEDUCTION = "30m"
+const DEFAULT_MIN_PAUSE = "15m"
+
+// Computes the break to deduct from a day, as set in the [breaks] section:
+//
+//	[breaks]
+//	after = "6h"      # continuous tracking that requires a break (0 disables)
+//	deduct = "30m"    # break deducted when no pause was taken
+//	min_pause = "15m" # shortest gap counting as a pause
+//
+// The intervals must belong to the same day, in the order of entries
+func computeBreakDeduction(intervals []Interval) time.Duration {
+	after := getConfigDuration("breaks.after", "0")
+	if after == 0 || len(intervals) == 0 {
+		return 0
+	}
+	minPause := getConfigDuration("breaks.min_pause", DEFAULT_MIN_PAUSE)
+
+	blockStart := intervals[0].start
+	for i, in := range intervals {
+		if i != 0 && in.start.Sub(intervals[i-1].end) >= minPause {
+			blockStart = in.start
+		}
+		if in.end.Sub(blockStart) > after {
+			return getConfigDuration("breaks.deduct", DEFAULT_BREAK_DEDUCTION)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const BUDGETS_NAME = ".mate.budgets.csv"
+const BUDGETS_CSV_HEADER = "title,budget\n"
+
+func getBudgetsPath() string {
+	return getHomeFilePath(BUDGETS_NAME)
+}
+
+// Reads the time budgets, keyed by ticket title
+func readBudgets() (map[string]time.Duration, error) {
+	table, err := readTable(getBudgetsPath())
+	if err != nil {
+		return nil, err
+	}
+	return parseBudgets(table), nil
+}
+
+// Reads the time budgets keyed by the canonical titles of the tickets, to compare them with the time spent
+// as reported (see normalizeRecords)
+func readNormalizedBudgets() (map[string]time.Duration, error) {
+	table, err := readTable(getBudgetsPath())
+	if err != nil {
+		return nil, err
+	}
+	return parseBudgets(normalizeTableTitles(table)), nil
+}
+
+func parseBudgets(table map[string]string) map[string]time.Duration {
+	budgets := make(map[string]time.Duration)
+	for title, literal := range table {
+		if budget, err := time.ParseDuration(literal); err == nil {
+			budgets[title] = budget
+		}
+	}
+	return budgets
+}
+
+func writeBudgets(budgets map[string]time.Duration) error {
+	table := make(map[string]string)
+	for title, budget := range budgets {
+		table[title] = budget.String()
+	}
+	return writeTable(getBudgetsPath(), BUDGETS_CSV_HEADER, table)
+}
+
+// Formats the time spent on a ticket against its budget, e.g. "/ 8h0m0s (62%)"
+// An empty string is returned for tickets without a budget
+func formatBudgetProgress(spent time.Duration, budget time.Duration) string {
+	if budget == 0 {
+		return ""
+	}
+	progress := fmt.Sprintf("/ %v (%d%%)", budget, int64(spent*100/budget))
+	if spent > budget {
+		progress += " OVER BUDGET"
+	}
+	return progress
+}
+
+// Sets the budget of a ticket (a zero budget removes it)
+func setBudget(title string, literal string) error {
+	budget, err := time.ParseDuration(literal)
+	if err != nil || budget < 0 {
+		return fmt.Errorf("Invalid duration \"%s\" (expected e.g. 8h)", literal)
+	}
+
+	budgets, err := readBudgets()
+	if err != nil {
+		return err
+	}
+	if budget == 0 {
+		delete(budgets, title)
+		if err = writeBudgets(budgets); err != nil {
+			return err
+		}
+		fmt.Printf("Budget of %s removed\n", title)
+		return nil
+	}
+	budgets[title] = budget
+	if err = writeBudgets(budgets); err != nil {
+		return err
+	}
+	fmt.Printf("Budget of %s set to %v\n", title, budget)
+	return nil
+}
+
+// Prints the progress of the given ticket against its budget, or of all tickets with a budget
+func showBudgets(title string) error {
+	budgets, err := readNormalizedBudgets()
+	if err != nil {
+		return err
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	spent := groupDurations(filterStops(computeEntriesDuration(normalizeRecords(records))))
+
+	if title != "" {
+		title = normalizeTitle(title, getAliases())
+		budget, found := budgets[title]
+		if !found {
+			return fmt.Errorf("No budget for %s. Run:\n$ mate budget \"%s\" 8h", title, title)
+		}
+		fmt.Printf("%s\t%v\t%s\n", title, spent[title], formatBudgetProgress(spent[title], budget))
+		return nil
+	}
+
+	if len(budgets) == 0 {
+		fmt.Println("No budget set. Run:\n$ mate budget \"Ticket title\" 8h")
+		return nil
+	}
+	var titles []string
+	for t := range budgets {
+		titles = append(titles, t)
+	}
+	sort.Strings(titles)
+	for _, t := range titles {
+		fmt.Printf("%s\t%v\t%s\n", t, spent[t], formatBudgetProgress(spent[t], budgets[t]))
+	}
+	return nil
+}
+
+// Warns when the time spent on a ticket exceeds its budget
+func warnAboutBudget(records []Record, title string) error {
+	budgets, err := readNormalizedBudgets()
+	if err != nil {
+		return err
+	}
+	title = normalizeTitle(title, getAliases())
+	budget, found := budgets[title]
+	if !found {
+		return nil
+	}
+	spent := groupDurations(filterStops(computeEntriesDuration(normalizeRecords(records))))[title]
+	if spent > budget {
+		fmt.Printf("Warning: %s is over its budget (%v / %v)\n", title, spent, budget)
+	}
+	return nil
+}
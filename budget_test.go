@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatBudgetProgress(t *testing.T) {
+	tests := []struct {
+		name   string
+		spent  time.Duration
+		budget time.Duration
+		want   string
+	}{
+		{"no budget", time.Hour, 0, ""},
+		{"nothing spent", 0, 8 * time.Hour, "/ 8h0m0s (0%)"},
+		{"within budget", 5 * time.Hour, 8 * time.Hour, "/ 8h0m0s (62%)"},
+		{"exactly the budget", 8 * time.Hour, 8 * time.Hour, "/ 8h0m0s (100%)"},
+		{"over budget", 9 * time.Hour, 8 * time.Hour, "/ 8h0m0s (112%) OVER BUDGET"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := formatBudgetProgress(test.spent, test.budget); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadNormalizedBudgets(t *testing.T) {
+	tests := []struct {
+		name    string
+		budgets map[string]string
+		want    map[string]time.Duration
+	}{
+		{"no budget", map[string]string{}, map[string]time.Duration{}},
+		{"title without alias", map[string]string{"Fix login": "2h"}, map[string]time.Duration{"Fix login": 2 * time.Hour}},
+		{"aliased title", map[string]string{"proj-123 fix": "2h"}, map[string]time.Duration{"PROJ-123": 2 * time.Hour}},
+		{"canonical title", map[string]string{"PROJ-123": "3h"}, map[string]time.Duration{"PROJ-123": 3 * time.Hour}},
+		{
+			"canonical title winning over an alias",
+			map[string]string{"PROJ-123": "3h", "proj-123 fix": "2h"},
+			map[string]time.Duration{"PROJ-123": 3 * time.Hour},
+		},
+		{"invalid budget", map[string]string{"Fix login": "soon"}, map[string]time.Duration{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, "")
+			config[`aliases.(?i)^proj-123\b`] = "PROJ-123"
+			if err := writeTable(getBudgetsPath(), BUDGETS_CSV_HEADER, test.budgets); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readNormalizedBudgets()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			for title, budget := range test.want {
+				if got[title] != budget {
+					t.Errorf("%s: got %v, want %v", title, got[title], budget)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const CACHE_NAME = ".mate.cache.json"
+
+// Title under which the mandatory breaks deducted from a day are counted
+const BREAK_TOKEN = "mate:BREAK"
+
+// The time worked per day and per ticket, for the reports over the whole history
+type DailyTotals struct {
+	firstDay time.Time                           // Day of the first entry, zero if there is none
+	days     map[string]map[string]time.Duration // Per day (DATE_FORMAT), per ticket, BREAK_TOKEN included
+}
+
+// The daily totals of the days before until, as cached on the disk
+// It is valid as long as the config and the database up to size are unchanged (see getCacheKey)
+type DailyTotalsCache struct {
+	Key      string                      `json:"key"`
+	Size     int64                       `json:"size"`
+	Until    string                      `json:"until"`
+	FirstDay string                      `json:"first_day"`
+	Days     map[string]map[string]int64 `json:"days"` // In seconds
+}
+
+func getCachePath() string {
+	return getHomeFilePath(CACHE_NAME)
+}
+
+// Returns the daily totals of the database, parsing only the entries since the cache was computed
+// The days before today are cached, so that the next reports only parse the entries of today
+func getDailyTotals() (DailyTotals, error) {
+	totals := DailyTotals{days: make(map[string]map[string]time.Duration)}
+	builder := DailyTotalsBuilder{totals: &totals}
+	// The cache is not encrypted, the titles of an encrypted database are not written to it
+	if isEncryptionEnabled() {
+		err := forEachRecord(builder.add)
+		builder.finish()
+		return totals, err
+	}
+
+	cache, found, err := readDailyTotalsCache()
+	if err != nil {
+		return totals, err
+	}
+	count := 0
+	countAndAdd := func(r Record) error {
+		count++
+		return builder.add(r)
+	}
+	if found {
+		builder.since, _ = time.Parse(DATE_FORMAT, cache.Until)
+		totals.firstDay, _ = time.Parse(DATE_FORMAT, cache.FirstDay)
+		for day, tickets := range cache.Days {
+			totals.days[day] = make(map[string]time.Duration)
+			for title, seconds := range tickets {
+				totals.days[day][title] = time.Duration(seconds) * time.Second
+			}
+		}
+		records, err := getRecentRecords(builder.since)
+		if err != nil {
+			return totals, err
+		}
+		for _, r := range records {
+			if err = countAndAdd(r); err != nil {
+				return totals, err
+			}
+		}
+	} else if err = forEachRecord(countAndAdd); err != nil {
+		return totals, err
+	}
+	builder.finish()
+	debugf("Daily totals of %s: %d days cached until %s, %d entries read", getDbPath(), len(cache.Days), cache.Until, count)
+
+	today := getNow().Truncate(time.Hour * 24)
+	if count != 0 && (!found || builder.since.Before(today)) {
+		return totals, writeDailyTotalsCache(totals, today)
+	}
+	return totals, nil
+}
+
+// Adds up the entries one at a time, in order, only the intervals of the current day being kept
+// The days before since are left out, being already counted
+type DailyTotalsBuilder struct {
+	totals    *DailyTotals
+	since     time.Time
+	last      *Record
+	day       string
+	intervals []Interval
+}
+
+func (b *DailyTotalsBuilder) add(r Record) error {
+	if b.totals.firstDay.IsZero() {
+		b.totals.firstDay = r.timestamp.Truncate(time.Hour * 24)
+	}
+	if b.last != nil && b.last.title != STOP_TOKEN {
+		b.addInterval(Interval{b.last.timestamp, r.timestamp, b.last.title})
+	}
+	b.last = &r
+	return nil
+}
+
+// Adds the running ticket, if any, and the last day
+func (b *DailyTotalsBuilder) finish() {
+	if b.last != nil && b.last.title != STOP_TOKEN {
+		b.addInterval(Interval{b.last.timestamp, getRunningEnd(b.last.timestamp), b.last.title})
+	}
+	b.flushDay()
+}
+
+func (b *DailyTotalsBuilder) addInterval(in Interval) {
+	for _, part := range splitIntervalAtMidnight(in) {
+		if key := part.start.Format(DATE_FORMAT); key != b.day {
+			b.flushDay()
+			b.day = key
+		}
+		b.intervals = append(b.intervals, part)
+	}
+}
+
+// Counts the intervals of the current day, minus its mandatory breaks
+func (b *DailyTotalsBuilder) flushDay() {
+	day, _ := time.Parse(DATE_FORMAT, b.day)
+	if len(b.intervals) != 0 && !day.Before(b.since) {
+		tickets := b.totals.days[b.day]
+		if tickets == nil {
+			tickets = make(map[string]time.Duration)
+			b.totals.days[b.day] = tickets
+		}
+		for _, in := range b.intervals {
+			tickets[in.title] += in.end.Sub(in.start)
+		}
+		if deduction := computeBreakDeduction(b.intervals); deduction != 0 {
+			tickets[BREAK_TOKEN] = deduction
+		}
+	}
+	b.intervals = nil
+}
+
+// Returns the time worked per day, keyed by DATE_FORMAT, minus the mandatory breaks (as computeTotalsPerDay)
+func (s *DailyTotals) totalsPerDay() map[string]time.Duration {
+	totals := make(map[string]time.Duration)
+	for day, tickets := range s.days {
+		for title, duration := range tickets {
+			if title == BREAK_TOKEN {
+				totals[day] -= duration
+			} else {
+				totals[day] += duration
+			}
+		}
+	}
+	return totals
+}
+
+// Returns the time spent per ticket over the whole history, under the canonical titles (see getAliases)
+func (s *DailyTotals) ticketTotals() map[string]time.Duration {
+	aliases := getAliases()
+	totals := make(map[string]time.Duration)
+	for _, tickets := range s.days {
+		for title, duration := range tickets {
+			if title != BREAK_TOKEN {
+				totals[normalizeTitle(title, aliases)] += duration
+			}
+		}
+	}
+	return totals
+}
+
+// Returns the key of the cache: a hash of the config, which the breaks and auto_stop depend on,
+// and of the database up to size, so that the cache is dropped when a past entry is edited by hand
+func getCacheKey(size int64) (string, error) {
+	config, err := os.ReadFile(getConfigPath())
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("Cannot read %s: %w", getConfigPath(), err)
+	}
+	database, err := files.ReadAt(getDbPath(), 0, size)
+	if err != nil {
+		return "", newDatabaseError("read", err)
+	}
+	hash := sha256.New()
+	hash.Write(config)
+	hash.Write(database)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Reads the cache, not found if it is missing or outdated
+func readDailyTotalsCache() (cache DailyTotalsCache, found bool, err error) {
+	content, err := files.ReadFile(getCachePath())
+	if os.IsNotExist(err) {
+		return cache, false, nil
+	}
+	if err != nil {
+		return cache, false, fmt.Errorf("Cannot read the cache: %w", err)
+	}
+	// A corrupted cache is computed again
+	if json.Unmarshal(content, &cache) != nil {
+		return DailyTotalsCache{}, false, nil
+	}
+	if err = pullGitDatabase(); err != nil {
+		return cache, false, err
+	}
+	if err = ensureCSVExists(); err != nil {
+		return cache, false, err
+	}
+	size, err := files.Size(getDbPath())
+	if err != nil {
+		return cache, false, newDatabaseError("read", err)
+	}
+	if size < cache.Size {
+		return DailyTotalsCache{}, false, nil
+	}
+	key, err := getCacheKey(cache.Size)
+	if err != nil || key != cache.Key {
+		return DailyTotalsCache{}, false, err
+	}
+	return cache, true, nil
+}
+
+// Caches the totals of the days before until
+func writeDailyTotalsCache(totals DailyTotals, until time.Time) error {
+	size, err := files.Size(getDbPath())
+	if err != nil {
+		return newDatabaseError("read", err)
+	}
+	key, err := getCacheKey(size)
+	if err != nil {
+		return err
+	}
+	cache := DailyTotalsCache{Key: key, Size: size, Until: until.Format(DATE_FORMAT),
+		FirstDay: totals.firstDay.Format(DATE_FORMAT), Days: make(map[string]map[string]int64)}
+	for day, tickets := range totals.days {
+		if t, _ := time.Parse(DATE_FORMAT, day); !t.Before(until) {
+			continue
+		}
+		cache.Days[day] = make(map[string]int64)
+		for title, duration := range tickets {
+			cache.Days[day][title] = int64(duration / time.Second)
+		}
+	}
+	content, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("Cannot write the cache: %w", err)
+	}
+	if err = files.WriteFile(getCachePath(), content, 0644); err != nil {
+		return fmt.Errorf("Cannot write the cache: %w", err)
+	}
+	return nil
+}
+
+// Drops the cache, once entries of the days it covers are changed
+func invalidateDailyTotalsCache() error {
+	if err := files.Remove(getCachePath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Cannot remove the cache: %w", err)
+	}
+	return nil
+}
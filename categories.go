@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The categories of activity, given as a hashtag of the title (start --category) or by the [rules]
+var CATEGORIES = []string{"development", "meeting", "review", "support", "admin"}
+
+const UNCATEGORIZED = "(uncategorized)"
+
+// Returns an error if the category is not one of CATEGORIES
+func checkCategory(category string) error {
+	if !contains(CATEGORIES, category) {
+		return fmt.Errorf("Invalid category \"%s\" (expected %s or %s)", category,
+			strings.Join(CATEGORIES[:len(CATEGORIES)-1], ", "), CATEGORIES[len(CATEGORIES)-1])
+	}
+	return nil
+}
+
+// Returns the category of a ticket: the one given by the rules, else its first hashtag naming a category, if any
+func getCategory(title string) string {
+	if category := matchRules(title).category; category != "" {
+		return category
+	}
+	for _, tag := range getTags(title) {
+		if contains(CATEGORIES, tag) {
+			return tag
+		}
+	}
+	return ""
+}
+
+// Appends the category to a title as a hashtag, unless it already has it
+func withCategory(title string, category string) string {
+	if category == "" || contains(getTags(title), category) {
+		return title
+	}
+	return title + " #" + category
+}
+
+// Prints the time spent per category, with its share of the total
+func showReportByCategory(min time.Duration) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	tickets := groupDurations(filterStops(computeEntriesDuration(records)))
+	if len(tickets) == 0 {
+		fmt.Println("Nothing to show (yet)")
+		return nil
+	}
+
+	perCategory := make(map[string]time.Duration)
+	var total time.Duration
+	for title, duration := range tickets {
+		category := getCategory(title)
+		if category == "" {
+			category = UNCATEGORIZED
+		}
+		perCategory[category] += duration
+		total += duration
+	}
+	perCategory = foldTotalsUnder(perCategory, min)
+
+	for _, category := range append(append([]string{}, CATEGORIES...), UNCATEGORIZED) {
+		if duration, found := perCategory[category]; found {
+			fmt.Printf("%s\t%v\t%s\n", category, duration, formatShare(duration, total))
+			delete(perCategory, category)
+		}
+	}
+	// What is left is the total folded by --min
+	for other, duration := range perCategory {
+		fmt.Printf("%s\t%v\t%s\n", other, duration, formatShare(duration, total))
+	}
+	return nil
+}
+
+// Formats the share of a duration in a total, e.g. "25%"
+func formatShare(duration time.Duration, total time.Duration) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.0f%%", float64(duration)*100/float64(total))
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// An option of a command: a flag (--name), or an option taking a value (--name value or --name=value)
+type CommandOption struct {
+	name  string
+	value string // Name of the value in the help, empty for a flag
+	help  string
+}
+
+// A command of the CLI
+type Command struct {
+	name      string
+	aliases   []string
+	arguments string // Usage of the positional arguments, e.g. "[date]"
+	summary   string
+	minArgs   int
+	maxArgs   int
+	options   []CommandOption
+	run       func(in *Invocation) error
+}
+
+// The arguments and options a command was invoked with
+type Invocation struct {
+	command *Command
+	args    []string
+	values  map[string]string
+	flags   map[string]bool
+}
+
+// Options available to every command
+var GLOBAL_OPTIONS = []CommandOption{
+	{"profile", "name", "use the database and config of a profile (or $MATE_PROFILE)"},
+	{"remote", "URL", "control a \"mate serve\" (or remote in the config)"},
+	{"force", "", "change entries locked by mate lock"},
+	{"quiet", "", "print nothing but errors, for scripts checking the exit code"},
+	{"verbose", "", "log the database reads and the HTTP calls on stderr (or MATE_DEBUG=1)"},
+	{"help", "", "show the help of the command"},
+}
+
+// Returns the value of an option, empty if it was not given
+func (in *Invocation) option(name string) string {
+	return in.values[name]
+}
+
+// Returns an option holding a positive duration (e.g. 1h30m), 0 if it was not given
+func (in *Invocation) duration(name string) (time.Duration, error) {
+	literal := in.option(name)
+	if literal == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(literal)
+	if err != nil || duration <= 0 {
+		return 0, in.fail(fmt.Sprintf("Invalid duration \"%s\" (expected e.g. 1h30m)", literal))
+	}
+	return duration, nil
+}
+
+func (in *Invocation) flag(name string) bool {
+	return in.flags[name]
+}
+
+// Returns a positional argument, empty if it was not given
+func (in *Invocation) arg(i int) string {
+	if i < len(in.args) {
+		return in.args[i]
+	}
+	return ""
+}
+
+// Returns an error for a wrong usage of the command, pointing to its help
+func (in *Invocation) fail(message string) error {
+	return newUsageError(in.command.name, message)
+}
+
+func findCommand(commands []*Command, name string) *Command {
+	for _, command := range commands {
+		if command.name == name || contains(command.aliases, name) {
+			return command
+		}
+	}
+	return nil
+}
+
+func findOption(options []CommandOption, name string) (CommandOption, bool) {
+	for _, option := range options {
+		if option.name == name {
+			return option, true
+		}
+	}
+	return CommandOption{}, false
+}
+
+// Returns the position of the command name in the arguments (after the program name), or -1
+// The values of the global options are skipped
+func findCommandName(args []string) int {
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			if i+1 < len(args) {
+				return i + 1
+			}
+			return -1
+		}
+		if !strings.HasPrefix(arg, "--") {
+			return i
+		}
+		if option, found := findOption(GLOBAL_OPTIONS, strings.TrimPrefix(arg, "--")); found && option.value != "" {
+			i++
+		}
+	}
+	return -1
+}
+
+// Parses the arguments of a command, options being accepted anywhere (before "--")
+func parseInvocation(command *Command, args []string) (*Invocation, error) {
+	in := &Invocation{command, nil, map[string]string{}, map[string]bool{}}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			in.args = append(in.args, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "--") {
+			in.args = append(in.args, arg)
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(arg, "--"), "=", 2)
+		name, value, hasValue := parts[0], "", len(parts) == 2
+		if hasValue {
+			value = parts[1]
+		}
+		option, found := findOption(command.options, name)
+		if !found {
+			option, found = findOption(GLOBAL_OPTIONS, name)
+		}
+		if !found {
+			return nil, newUsageError(command.name, fmt.Sprintf("Unknown option --%s for mate %s", name, command.name))
+		}
+		switch {
+		case option.value == "" && hasValue:
+			return nil, newUsageError(command.name, fmt.Sprintf("The --%s option does not take a value", name))
+		case option.value == "":
+			in.flags[name] = true
+		case hasValue:
+			in.values[name] = value
+		case i+1 < len(args):
+			in.values[name] = args[i+1]
+			i++
+		default:
+			return nil, newUsageError(command.name, fmt.Sprintf("The --%s option requires a value (%s)", name, option.value))
+		}
+	}
+
+	if in.flags["help"] {
+		return in, nil
+	}
+	switch {
+	case len(in.args) < command.minArgs:
+		return nil, newUsageError(command.name, fmt.Sprintf("Missing arguments: mate %s %s", command.name, command.arguments))
+	case len(in.args) > command.maxArgs && command.maxArgs == 0:
+		return nil, newUsageError(command.name, "The "+command.name+" command does not take any argument")
+	case len(in.args) > command.maxArgs:
+		return nil, newUsageError(command.name, fmt.Sprintf("Too many arguments: mate %s %s (use quotes for long titles)", command.name, command.arguments))
+	}
+	return in, nil
+}
+
+// Formats an option as in the usage, e.g. "--since date"
+func formatOption(option CommandOption) string {
+	if option.value == "" {
+		return "--" + option.name
+	}
+	return "--" + option.name + " " + option.value
+}
+
+func getCommandUsage(command *Command) string {
+	usage := command.name
+	if len(command.aliases) != 0 {
+		usage += " (" + strings.Join(command.aliases, ", ") + ")"
+	}
+	if command.arguments != "" {
+		usage += " " + command.arguments
+	}
+	for _, option := range command.options {
+		usage += " [" + formatOption(option) + "]"
+	}
+	return usage
+}
+
+// Prints the help of a command, with its options
+func showCommandHelp(command *Command) {
+	fmt.Printf("Usage: mate %s\n", getCommandUsage(command))
+	fmt.Println(command.summary)
+	fmt.Println("Options:")
+	for _, option := range append(append([]CommandOption{}, command.options...), GLOBAL_OPTIONS...) {
+		fmt.Printf("  %-24s %s\n", formatOption(option), option.help)
+	}
+}
+
+// Prints the commands
+func showHelp(commands []*Command) {
+	fmt.Println("Please provide a command among:")
+	for _, command := range commands {
+		fmt.Printf("  * %s\n", getCommandUsage(command))
+	}
+	fmt.Println("Any command takes --profile name (or $MATE_PROFILE) to use the database and config of a profile,")
+	fmt.Println("--force to change entries locked by mate lock, --quiet to print nothing but errors,")
+	fmt.Println("and --verbose (or MATE_DEBUG=1) to log the database reads and the HTTP calls on stderr")
+	fmt.Println("With --remote URL (or remote in the config), start, switch, stop, info and log control a \"mate serve\"")
+	fmt.Println("With MATE_NOW=\"YYYY/MM/DD HH:MM\", a command runs as if it were that time (for tests and demos)")
+	fmt.Println("Exit codes:")
+	for _, exitCode := range EXIT_CODES {
+		fmt.Printf("  %d  %s\n", exitCode.code, exitCode.description)
+	}
+	fmt.Println("Run mate <command> --help for the details of a command")
+}
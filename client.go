@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+const CLIENTS_NAME = ".mate.clients.csv"
+const CLIENTS_CSV_HEADER = "title,client\n"
+const NO_CLIENT = "(no client)"
+
+// Ticket keys such as PROJ-123, whose project is PROJ
+var TICKET_KEY_PATTERN = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9]*)-[0-9]+`)
+
+func getClientsPath() string {
+	return getHomeFilePath(CLIENTS_NAME)
+}
+
+// Returns the project of a ticket, from its key prefix (PROJ for "PROJ-123 Fix login")
+func getProject(title string) string {
+	match := TICKET_KEY_PATTERN.FindStringSubmatch(title)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// Records the client of a ticket, as given by start --client
+func setTicketClient(title string, client string) error {
+	return mergeTable(getClientsPath(), CLIENTS_CSV_HEADER, map[string]string{title: client})
+}
+
+// Returns the client of a ticket: the one given by start --client, or the one of its project in the [clients] section:
+//
+//	[clients]
+//	PROJ = "Acme"
+func getClient(title string, ticketClients map[string]string) string {
+	if client, found := ticketClients[title]; found {
+		return client
+	}
+	if project := getProject(title); project != "" {
+		return getConfig("clients."+project, "")
+	}
+	return ""
+}
+
+// Prints the time spent per client
+func showReportByClient(min time.Duration) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	tickets := groupDurations(filterStops(computeEntriesDuration(normalizeRecords(records))))
+	if len(tickets) == 0 {
+		fmt.Println("Nothing to show (yet)")
+		return nil
+	}
+
+	ticketClients, err := readTable(getClientsPath())
+	if err != nil {
+		return err
+	}
+	ticketClients = normalizeTableTitles(ticketClients)
+	perClient := make(map[string]time.Duration)
+	for title, duration := range tickets {
+		client := getClient(title, ticketClients)
+		if client == "" {
+			client = NO_CLIENT
+		}
+		perClient[client] += duration
+	}
+	perClient = foldTotalsUnder(perClient, min)
+
+	var clients []string
+	for client := range perClient {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	for _, client := range clients {
+		fmt.Printf("%s\t%v\n", client, perClient[client])
+	}
+	return nil
+}
+
+// Returns the table keyed by the canonical titles of the tickets, as the titles are recorded as given
+// The value of a canonical title recorded as such wins over the ones of its aliases
+func normalizeTableTitles(table map[string]string) map[string]string {
+	aliases := getAliases()
+	if len(aliases) == 0 {
+		return table
+	}
+	normalized := make(map[string]string, len(table))
+	for title, value := range table {
+		if canonical := normalizeTitle(title, aliases); canonical == title {
+			normalized[title] = value
+		} else if _, found := table[canonical]; !found {
+			normalized[canonical] = value
+		}
+	}
+	return normalized
+}
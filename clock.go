@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// The source of the current time of mate, in local time
+type Clock interface {
+	Now() time.Time
+}
+
+// The clock of the system
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// A clock stopped at a given time, so that the results of a command only depend on the database
+type FixedClock struct {
+	now time.Time
+}
+
+func (c FixedClock) Now() time.Time {
+	return c.now
+}
+
+// The clock every computation of mate reads the current time from (see getNow)
+var clock Clock = SystemClock{}
+
+// Stops the clock at $MATE_NOW if it is set (YYYY/MM/DD HH:MM:SS, or "YYYY/MM/DD HH:MM"),
+// to test mate or to script a demo
+func setClockFromEnv() error {
+	literal := os.Getenv("MATE_NOW")
+	if literal == "" {
+		return nil
+	}
+	for _, layout := range []string{TIME_FORMAT, "2006/01/02 15:04", time.RFC3339} {
+		if now, err := time.ParseInLocation(layout, literal, time.Local); err == nil {
+			clock = FixedClock{now}
+			debugf("The clock is stopped at %s (MATE_NOW)", now.Format(TIME_FORMAT))
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid MATE_NOW \"%s\" (expected \"YYYY/MM/DD HH:MM:SS\")", literal)
+}
@@ -0,0 +1,667 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var SINCE_OPTION = CommandOption{"since", "date", "first day included"}
+var UNTIL_OPTION = CommandOption{"until", "date", "last day included"}
+var MIN_OPTION = CommandOption{"min", "duration", "fold what lasted less (e.g. 2m) into \"other\""}
+
+// Returns the commands of the CLI, in the order of the help
+func getCommands() []*Command {
+	return []*Command{
+		{
+			name: "start", aliases: []string{"s"}, maxArgs: 1,
+			arguments: "[\"Ticket title\" | PROJ-123 | gh:owner/repo#123 | #42 | !17]",
+			summary:   "Starts a ticket, or restarts the last one without a title",
+			options: []CommandOption{
+				{"from-pr", "", "start the pull request of the current branch"},
+				{"git", "", "start the ticket of the current branch"},
+				{"for", "1h30m", "stop the ticket after a duration"},
+				{"client", "Name", "set the client of the ticket"},
+				{"category", "name", "set the category of the ticket (" + strings.Join(CATEGORIES, ", ") + ")"},
+				{"url", "URL", "set the URL of the ticket, for mate open"},
+			},
+			run: runStart,
+		},
+		{
+			name: "meet", arguments: "\"Meeting title\"", minArgs: 1, maxArgs: 1,
+			summary: "Starts a meeting, stopped after meetings.length of the config if set",
+			options: []CommandOption{
+				{"for", "1h30m", "stop the meeting after a duration, instead of meetings.length"},
+				{"client", "Name", "set the client of the meeting"},
+			},
+			run: func(in *Invocation) error {
+				// A start in the meeting category
+				in.values["category"] = "meeting"
+				if length := getConfigDuration("meetings.length", "0"); in.option("for") == "" && length != 0 {
+					in.values["for"] = length.String()
+				}
+				return runStart(in)
+			},
+		},
+		{
+			name: "switch", arguments: "[\"Ticket title\"]", maxArgs: 1,
+			summary: "Starts another ticket if one is running",
+			options: []CommandOption{{"git", "", "switch to the ticket of the current branch"}},
+			run: func(in *Invocation) error {
+				if in.flag("git") == (len(in.args) == 1) {
+					return in.fail("The switch command takes a title or --git")
+				}
+				ticket := in.arg(0)
+				if in.flag("git") {
+					var err error
+					if ticket, err = getCurrentBranchTicket(); err != nil {
+						return fmt.Errorf("Cannot find the ticket of the branch: %w", err)
+					}
+				}
+				return switchTicket(ticket)
+			},
+		},
+		{
+			name: "hook", arguments: "install|print", minArgs: 1, maxArgs: 1,
+			summary: "Installs (or prints) the git hook switching tickets on checkout",
+			run: func(in *Invocation) error {
+				switch in.arg(0) {
+				case "install":
+					return installHook()
+				case "print":
+					printHook()
+					return nil
+				}
+				return in.fail("The hook command takes install or print")
+			},
+		},
+		{
+			name: "profile", arguments: "list|create|switch [name]", minArgs: 1, maxArgs: 2,
+			summary: "Lists, creates or switches the profiles, each with its own database and config",
+			run: func(in *Invocation) error {
+				switch {
+				case in.arg(0) == "list" && len(in.args) == 1:
+					showProfiles()
+					return nil
+				case in.arg(0) == "create" && len(in.args) == 2:
+					return createProfile(in.arg(1))
+				case in.arg(0) == "switch" && len(in.args) == 2:
+					return switchProfile(in.arg(1))
+				}
+				return in.fail("The profile command takes list, create name or switch name")
+			},
+		},
+		{
+			name: "secret", arguments: "list|set|delete [config key]", minArgs: 1, maxArgs: 2,
+			summary: "Stores config values (e.g. jira.token) in the keyring of the OS",
+			run: func(in *Invocation) error {
+				switch {
+				case in.arg(0) == "list" && len(in.args) == 1:
+					showSecrets()
+					return nil
+				case in.arg(0) == "set" && len(in.args) == 2:
+					return setSecret(in.arg(1))
+				case in.arg(0) == "delete" && len(in.args) == 2:
+					return deleteSecret(in.arg(1))
+				}
+				return in.fail("The secret command takes list, set key or delete key")
+			},
+		},
+		{
+			name: "stop", aliases: []string{"x"},
+			summary: "Stops the running ticket",
+			options: []CommandOption{
+				{"eod", "", "stop the ticket at the end of its day"},
+				{"close", "", "close the GitHub issue of the ticket"},
+			},
+			run: func(in *Invocation) error {
+				if !in.flag("close") {
+					return stopTicket(in.flag("eod"))
+				}
+				records, err := getRecords()
+				if err != nil {
+					return err
+				}
+				reference, found := getGitHubReference(getLastTitle(records))
+				if !found {
+					return errors.New("The last ticket is not a GitHub issue (expected a title such as \"owner/repo#123 Fix login\")")
+				}
+				if err = stopTicket(in.flag("eod")); err != nil {
+					return err
+				}
+				return closeGitHubIssue(reference)
+			},
+		},
+		{
+			name: "log", aliases: []string{"l"},
+			summary: "Shows the time spent per ticket, over all the entries",
+			options: []CommandOption{
+				{"by-client", "", "group the tickets by client"},
+				{"by-category", "", "group the tickets by category, with their share of the time"},
+				MIN_OPTION,
+			},
+			run: func(in *Invocation) error {
+				min, err := in.duration("min")
+				if err != nil {
+					return err
+				}
+				if in.flag("by-client") {
+					return showReportByClient(min)
+				}
+				if in.flag("by-category") {
+					return showReportByCategory(min)
+				}
+				return showReport(min)
+			},
+		},
+		{
+			name: "list", aliases: []string{"ll"},
+			summary: "Lists all the entries, with their durations",
+			options: []CommandOption{MIN_OPTION},
+			run: func(in *Invocation) error {
+				min, err := in.duration("min")
+				if err != nil {
+					return err
+				}
+				return listEntries(min)
+			},
+		},
+		{
+			name: "info", aliases: []string{"i"},
+			summary: "Shows the running ticket and the time left to work today",
+			options: []CommandOption{
+				{"balance", "", "show the overtime balance"},
+				{"assume-stop-at", "HH:MM", "compute the day as if stopped at a time"},
+			},
+			run: func(in *Invocation) error {
+				if err := checkOvernightTicket(); err != nil {
+					return err
+				}
+				return showInfo(in.flag("balance"), in.option("assume-stop-at"))
+			},
+		},
+		{
+			name:    "status",
+			summary: "Shows the running ticket and the time worked today on one line, for a status bar",
+			options: []CommandOption{
+				{"tmux", "", "format it for the status line of tmux"},
+				{"waybar", "", "print the JSON of Waybar and i3blocks"},
+				{"prompt", "", "print a short status for a shell prompt, nothing when not working"},
+			},
+			run: func(in *Invocation) error {
+				format := "plain"
+				for _, name := range []string{"tmux", "waybar", "prompt"} {
+					if in.flag(name) {
+						if format != "plain" {
+							return in.fail("Choose one format among --tmux, --waybar and --prompt")
+						}
+						format = name
+					}
+				}
+				return showStatus(format)
+			},
+		},
+		{
+			name: "recent", arguments: "[query]", maxArgs: 1,
+			summary: "Lists the tickets of the last 30 days, the last one first, for a launcher to start them",
+			options: []CommandOption{
+				{"alfred", "", "print the JSON of an Alfred Script Filter"},
+				{"raycast", "", "print the JSON items of a Raycast script command"},
+			},
+			run: func(in *Invocation) error {
+				switch {
+				case in.flag("alfred") && in.flag("raycast"):
+					return in.fail("Choose one format among --alfred and --raycast")
+				case in.flag("alfred"):
+					return showRecentTickets(in.arg(0), "alfred")
+				case in.flag("raycast"):
+					return showRecentTickets(in.arg(0), "raycast")
+				}
+				return showRecentTickets(in.arg(0), "plain")
+			},
+		},
+		{
+			name: "week", aliases: []string{"w"}, arguments: "[date]", maxArgs: 1,
+			summary: "Shows the time worked per day of a week",
+			run:     func(in *Invocation) error { return showWeek(in.arg(0)) },
+		},
+		{
+			name: "month", aliases: []string{"m"}, arguments: "[date]", maxArgs: 1,
+			summary: "Shows the time worked per week of a month",
+			run:     func(in *Invocation) error { return showMonth(in.arg(0)) },
+		},
+		{
+			name: "off", arguments: "[date]", maxArgs: 1,
+			summary: "Marks a day off, or lists the days off without a date",
+			options: []CommandOption{
+				{"type", "vacation|sick|holiday", "type of the day off"},
+				{"remove", "", "remove the day off"},
+			},
+			run: func(in *Invocation) error {
+				if len(in.args) == 0 {
+					return listDaysOff()
+				}
+				offType := in.option("type")
+				if offType == "" {
+					offType = OFF_TYPES[0]
+				}
+				return setDayOff(in.arg(0), offType, in.flag("remove"))
+			},
+		},
+		{
+			name: "report", arguments: "[date]", maxArgs: 1,
+			summary: "Shows the time spent per project and ticket during a week",
+			options: []CommandOption{
+				{"email", "", "send the report by email (HTML and plain text), through the SMTP server of the config"},
+				{"to", "a@b.com,c@d.com", "recipients of the email, instead of email.to"},
+			},
+			run: func(in *Invocation) error {
+				if in.option("to") != "" && !in.flag("email") {
+					return in.fail("The --to option requires --email")
+				}
+				return showWeekReport(in.arg(0), in.flag("email"), in.option("to"))
+			},
+		},
+		{
+			name: "team", arguments: "report", minArgs: 1, maxArgs: 1,
+			summary: "Merges the exports of a team into a report per project and per person, for the current week by default",
+			options: []CommandOption{
+				{"inputs", "alice.json,bob=https://...", "JSON exports (mate export --format json) or mate servers, named after the files or name="},
+				{"server", "https://...", "a mate server with [users], remote_token being the token of a reporter for all the titles"},
+				SINCE_OPTION, UNTIL_OPTION,
+			},
+			run: func(in *Invocation) error {
+				if in.arg(0) != "report" {
+					return in.fail("The team command takes report")
+				}
+				if in.option("inputs") == "" && in.option("server") == "" {
+					return in.fail("Missing the exports of the team: --inputs alice.json,bob.json or --server https://...")
+				}
+				return showTeamReport(in.option("inputs"), in.option("server"), in.option("since"), in.option("until"))
+			},
+		},
+		{
+			name: "timesheet", arguments: "[date]", maxArgs: 1,
+			summary: "Shows the time spent per ticket and day of a week",
+			options: []CommandOption{
+				// The week is the only period supported for now, hence an optional --week
+				{"week", "", "show a week (default)"},
+				{"csv", "", "print as CSV"},
+			},
+			run: func(in *Invocation) error { return showTimesheet(in.arg(0), in.flag("csv")) },
+		},
+		{
+			name:    "export",
+			summary: "Exports the entries",
+			options: []CommandOption{
+				{"format", "csv|tsv|xlsx|json|ics|timeclock|obsidian", "format of the export"},
+				{"dir", "path", "directory of the daily notes, for the obsidian format"},
+				SINCE_OPTION, UNTIL_OPTION,
+			},
+			run: func(in *Invocation) error {
+				format := in.option("format")
+				if format == "" {
+					format = EXPORT_FORMATS[0]
+				}
+				return exportEntries(format, in.option("since"), in.option("until"), in.option("dir"))
+			},
+		},
+		{
+			name: "import", arguments: "[file]", maxArgs: 1,
+			summary: "Imports entries from a file (\"-\" for stdin), or from Watson or Timewarrior",
+			options: []CommandOption{
+				{"format", "json|ics|csv", "format of the file, csv for a .csv file or with --map"},
+				{"map", "start=column,...", "columns of the start, end, duration, title and date, for the csv format"},
+				{"from", "watson|timewarrior", "time tracker to migrate from"},
+				{"dry-run", "", "show the entries to import, without importing them"},
+			},
+			run: func(in *Invocation) error {
+				format := in.option("format")
+				if in.option("from") != "" {
+					format = in.option("from")
+				}
+				if format == "" && (in.option("map") != "" || strings.HasSuffix(strings.ToLower(in.arg(0)), ".csv")) {
+					format = "csv"
+				}
+				if format == "" {
+					format = IMPORT_FORMATS[0]
+				}
+				if len(in.args) == 0 && !contains([]string{"watson", "timewarrior"}, format) {
+					return in.fail("The import command takes a file")
+				}
+				return importEntries(format, in.arg(0), in.option("map"), in.flag("dry-run"))
+			},
+		},
+		{
+			name: "merge-db", arguments: "other.csv", minArgs: 1, maxArgs: 1,
+			summary: "Merges another database, e.g. a conflicted copy",
+			options: []CommandOption{{"dry-run", "", "show the entries to merge, without merging them"}},
+			run:     func(in *Invocation) error { return mergeDatabaseFile(in.arg(0), in.flag("dry-run")) },
+		},
+		{
+			name: "diff", arguments: "other.csv [another.csv]", minArgs: 1, maxArgs: 2,
+			summary: "Shows the entries differing between the database and another one, or between two databases",
+			run:     func(in *Invocation) error { return showDatabaseDiff(in.args) },
+		},
+		{
+			name: "sync", arguments: "[toggl | repository URL]", maxArgs: 1,
+			summary: "Syncs the database with sync.url, with Toggl, or with a git repository (--git)",
+			options: []CommandOption{{"git", "", "store the database in a git repository"}, SINCE_OPTION, UNTIL_OPTION},
+			run: func(in *Invocation) error {
+				switch {
+				case in.flag("git"):
+					return setupGitDatabase(in.arg(0))
+				case len(in.args) == 0:
+					return syncDatabase()
+				case in.arg(0) == "toggl":
+					return syncToggl(in.option("since"), in.option("until"))
+				}
+				return in.fail("The sync command takes no service, or toggl")
+			},
+		},
+		{
+			name: "push", arguments: "clockify|harvest|jira", minArgs: 1, maxArgs: 1,
+			summary: "Pushes the completed entries to a service",
+			options: []CommandOption{
+				SINCE_OPTION, UNTIL_OPTION,
+				{"dry-run", "", "only show what would be pushed"},
+				{"confirm", "", "ask before pushing each entry"},
+			},
+			run: func(in *Invocation) error {
+				return pushEntries(in.arg(0), in.option("since"), in.option("until"), in.flag("dry-run"), in.flag("confirm"))
+			},
+		},
+		{
+			name:    "standup",
+			summary: "Shows what was done on the previous worked day and today",
+			options: []CommandOption{{"markdown", "", "print as Markdown"}},
+			run:     func(in *Invocation) error { return showStandup(in.flag("markdown")) },
+		},
+		{
+			name: "post", arguments: "summary", minArgs: 1, maxArgs: 1,
+			summary: "Posts the summary of a day to a chat",
+			options: []CommandOption{
+				{"channel", "#name", "channel to post to"},
+				{"date", "date", "day of the summary"},
+			},
+			run: func(in *Invocation) error {
+				if in.arg(0) != "summary" {
+					return in.fail("The post command takes summary")
+				}
+				return postSummary(in.option("date"), in.option("channel"))
+			},
+		},
+		{
+			name: "budget", arguments: "[\"Ticket title\" [8h]]", maxArgs: 2,
+			summary: "Shows the budgets of the tickets, or sets the one of a ticket",
+			run: func(in *Invocation) error {
+				if len(in.args) == 2 {
+					return setBudget(in.arg(0), in.arg(1))
+				}
+				return showBudgets(in.arg(0))
+			},
+		},
+		{
+			name: "timeline", aliases: []string{"t"}, arguments: "[date]", maxArgs: 1,
+			summary: "Shows the intervals of a day",
+			run:     func(in *Invocation) error { return showTimeline(in.arg(0)) },
+		},
+		{
+			name: "journal", arguments: "[date]", maxArgs: 1,
+			summary: "Shows the entries, notes and breaks of a day in Markdown",
+			run:     func(in *Invocation) error { return showJournal(in.arg(0)) },
+		},
+		{
+			name: "retro", arguments: "[date]", maxArgs: 1,
+			summary: "Fills the untracked gaps of a day",
+			run:     func(in *Invocation) error { return fillGapsInteractively(in.arg(0)) },
+		},
+		{
+			name: "pomo", aliases: []string{"p"}, arguments: "\"Ticket title\"", minArgs: 1, maxArgs: 1,
+			summary: "Works on a ticket in pomodoros",
+			run: func(in *Invocation) error {
+				if err := checkOvernightTicket(); err != nil {
+					return err
+				}
+				return runPomodoro(in.arg(0))
+			},
+		},
+		{
+			name:    "daemon",
+			summary: "Runs the reminders and automations in the background",
+			run:     func(in *Invocation) error { return runDaemon() },
+		},
+		{
+			name: "bot", arguments: "telegram|matrix|discord", minArgs: 1, maxArgs: 1,
+			summary: "Runs a chat bot answering /start, /stop, /status and /today",
+			run:     func(in *Invocation) error { return runBot(in.arg(0)) },
+		},
+		{
+			name:    "serve",
+			summary: "Serves the REST API and the web dashboard",
+			options: []CommandOption{
+				{"listen", "host:port", "address to listen on"},
+				{"menubar-feed", "", "also serve /api/menubar, a menu bar timer for xbar or SwiftBar"},
+			},
+			run: func(in *Invocation) error { return serve(in.option("listen"), in.flag("menubar-feed")) },
+		},
+		{
+			name: "delete", arguments: "[HH:MM | \"YYYY/MM/DD HH:MM\"]", maxArgs: 1,
+			summary: "Moves an entry to the trash, the last one by default",
+			run:     func(in *Invocation) error { return deleteEntry(in.arg(0)) },
+		},
+		{
+			name:    "open",
+			summary: "Opens the URL of the running ticket, or of the last one, in the browser",
+			options: []CommandOption{{"print", "", "print the URL instead"}},
+			run:     func(in *Invocation) error { return openTicketURL(in.flag("print")) },
+		},
+		{
+			name: "note", arguments: "\"Note\"", minArgs: 1, maxArgs: 1,
+			summary: "Attaches a note to the running entry",
+			run:     func(in *Invocation) error { return addNote(in.arg(0)) },
+		},
+		{
+			name: "show", arguments: "[HH:MM | \"YYYY/MM/DD HH:MM\"]", maxArgs: 1,
+			summary: "Shows an entry with its notes, the last one by default",
+			run:     func(in *Invocation) error { return showEntry(in.arg(0)) },
+		},
+		{
+			name: "split", arguments: "[HH:MM | \"YYYY/MM/DD HH:MM\"]", maxArgs: 1,
+			summary: "Splits an entry in two, the last one by default, the second part going to another ticket",
+			options: []CommandOption{
+				{"at", "HH:MM", "the end of the first part"},
+				{"second-title", "\"Ticket title\"", "the ticket of the second part"},
+			},
+			run: func(in *Invocation) error {
+				if in.option("at") == "" || in.option("second-title") == "" {
+					return in.fail("Missing where to split and the second ticket: --at HH:MM --second-title \"Ticket title\"")
+				}
+				return splitEntry(in.arg(0), in.option("at"), in.option("second-title"))
+			},
+		},
+		{
+			name: "trash", arguments: "[list | restore id]", maxArgs: 2,
+			summary: "Lists or restores the deleted entries, kept for 30 days",
+			run: func(in *Invocation) error {
+				switch {
+				case len(in.args) == 0 || in.arg(0) == "list" && len(in.args) == 1:
+					return showTrash()
+				case in.arg(0) == "restore" && len(in.args) == 2:
+					return restoreTrash(in.arg(1))
+				}
+				return in.fail("The trash command takes list or restore id")
+			},
+		},
+		{
+			name: "history", arguments: "[number of operations]", maxArgs: 1,
+			summary: "Shows the last changes of the database",
+			run: func(in *Invocation) error {
+				limit := 20
+				if len(in.args) == 1 {
+					var err error
+					if limit, err = strconv.Atoi(in.arg(0)); err != nil || limit <= 0 {
+						return in.fail("The history command takes a number of operations")
+					}
+				}
+				return showHistory(limit)
+			},
+		},
+		{
+			name:    "undo",
+			summary: "Reverts the last change of the database",
+			run:     func(in *Invocation) error { return undoLastOperation() },
+		},
+		{
+			name:    "verify",
+			summary: "Checks that the database was not modified outside of mate",
+			options: []CommandOption{{"reseal", "", "accept the database as it is"}},
+			run:     func(in *Invocation) error { return verifyDatabase(in.flag("reseal")) },
+		},
+		{
+			name:    "doctor",
+			summary: "Checks the lines of the database, the malformed ones being skipped by the other commands",
+			options: []CommandOption{{"fix-lines", "", "move the malformed lines to " + QUARANTINE_NAME}},
+			run:     func(in *Invocation) error { return runDoctor(in.flag("fix-lines")) },
+		},
+		{
+			name: "rename", arguments: "\"old title\" \"new title\"", minArgs: 2, maxArgs: 2,
+			summary: "Renames a ticket in the whole history",
+			options: []CommandOption{{"yes", "", "do not ask for confirmation"}},
+			run:     func(in *Invocation) error { return renameTicket(in.arg(0), in.arg(1), in.flag("yes")) },
+		},
+		{
+			name: "merge", arguments: "\"title\" [\"title\"...]", minArgs: 1, maxArgs: 100,
+			summary: "Consolidates the entries of several tickets under one title",
+			options: []CommandOption{
+				{"into", "\"Ticket title\"", "the title to keep, which may be one of the merged ones"},
+				{"yes", "", "do not ask for confirmation"},
+			},
+			run: func(in *Invocation) error {
+				if in.option("into") == "" {
+					return in.fail("Missing the title to merge into: --into \"Ticket title\"")
+				}
+				return mergeTickets(in.args, in.option("into"), in.flag("yes"))
+			},
+		},
+		{
+			name:    "categorize",
+			summary: "Applies the [rules] of the config to the entries already saved, adding their tags to the titles",
+			options: []CommandOption{
+				{"dry-run", "", "only show the titles that would change"},
+				{"yes", "", "do not ask for confirmation"},
+			},
+			run: func(in *Invocation) error { return categorizeEntries(in.flag("dry-run"), in.flag("yes")) },
+		},
+		{
+			name:    "compact",
+			summary: "Removes the redundant entries: restarts of the running ticket, repeated STOPs and pauses under a minute",
+			options: []CommandOption{{"dry-run", "", "only show the entries that would be removed"}},
+			run:     func(in *Invocation) error { return compactDatabase(in.flag("dry-run")) },
+		},
+		{
+			name:    "lock",
+			summary: "Locks the entries up to a day, or shows the locked days",
+			options: []CommandOption{UNTIL_OPTION},
+			run:     func(in *Invocation) error { return lockEntries(in.option("until")) },
+		},
+		{
+			name:    "unlock",
+			summary: "Unlocks the entries (with --force)",
+			run:     func(in *Invocation) error { return unlockEntries() },
+		},
+		{
+			name:    "clear",
+			summary: "Moves the entries to the trash, all of them without filters",
+			options: []CommandOption{
+				SINCE_OPTION,
+				{"before", "date", "first day excluded"},
+				{"ticket", "\"Ticket title\"", "only clear a ticket"},
+				{"yes", "", "do not ask for confirmation"},
+			},
+			run: func(in *Invocation) error {
+				return clearEntries(in.option("since"), in.option("before"), in.option("ticket"), in.flag("yes"))
+			},
+		},
+	}
+}
+
+func runStart(in *Invocation) error {
+	timer, err := in.duration("for")
+	if err != nil {
+		return err
+	}
+	if (in.flag("from-pr") || in.flag("git")) && len(in.args) == 1 {
+		return in.fail("The --from-pr and --git options do not take a title")
+	}
+	category := in.option("category")
+	if category != "" {
+		if err = checkCategory(category); err != nil {
+			return in.fail(err.Error())
+		}
+		if !in.flag("from-pr") && !in.flag("git") && len(in.args) == 0 {
+			return in.fail("The --category option requires a title")
+		}
+	}
+
+	if err := checkOvernightTicket(); err != nil {
+		return err
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	project, err := getProjectOptions()
+	if err != nil {
+		return err
+	}
+	switch {
+	case in.flag("from-pr"):
+		var title string
+		if title, err = getPullRequestTitle(); err != nil {
+			return fmt.Errorf("Cannot find the pull request: %w", err)
+		}
+		err = startTicket(withCategory(title, category))
+	case in.flag("git"):
+		var ticket string
+		if ticket, err = getCurrentBranchTicket(); err != nil {
+			return fmt.Errorf("Cannot find the ticket of the branch: %w", err)
+		}
+		err = startTicket(withCategory(expandTitle(ticket, records), category))
+	case len(in.args) == 1:
+		title := applyProjectPrefix(project["prefix"], in.arg(0))
+		err = startTicket(withCategory(expandTitle(title, records), category))
+	default:
+		err = restartLastTicket()
+	}
+	if err != nil {
+		return err
+	}
+
+	if timer != 0 {
+		if err = scheduleStop(timer); err != nil {
+			return err
+		}
+	}
+	client := in.option("client")
+	if client == "" {
+		client = project["client"]
+	}
+	if client == "" && in.option("url") == "" {
+		return nil
+	}
+	if records, err = getRecords(); err != nil {
+		return err
+	}
+	title := records[len(records)-1].title
+	if client != "" {
+		if err = setTicketClient(title, client); err != nil {
+			return err
+		}
+	}
+	if in.option("url") != "" {
+		return setTicketURL(title, in.option("url"))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Longest pause between two entries of a ticket for them to be merged by mate compact
+const COMPACT_MAX_GAP = time.Minute
+
+// Removes the redundant entries: an entry of the ticket already running (a restart, or a STOP after a STOP),
+// and a STOP followed within COMPACT_MAX_GAP by the ticket it stopped, the ticket running on instead
+func compactRecords(records []Record) (kept []Record, removed []Record) {
+	for _, r := range records {
+		n := len(kept)
+		switch {
+		case n != 0 && kept[n-1].title == r.title:
+			removed = append(removed, r)
+		case n >= 2 && kept[n-1].title == STOP_TOKEN && kept[n-2].title == r.title && r.timestamp.Sub(kept[n-1].timestamp) < COMPACT_MAX_GAP:
+			removed = append(removed, kept[n-1], r)
+			kept = kept[:n-1]
+		default:
+			kept = append(kept, r)
+		}
+	}
+	return
+}
+
+// Compacts the database, or with dryRun only shows the entries that would be removed
+func compactDatabase(dryRun bool) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	kept, removed := compactRecords(records)
+	if len(removed) == 0 {
+		fmt.Println("Nothing to compact")
+		return nil
+	}
+	for _, r := range removed {
+		fmt.Printf("- %s %s\n", r.timestamp.Format(TIME_FORMAT), getEntryName(r))
+	}
+	if dryRun {
+		fmt.Printf("%d entries would be removed\n", len(removed))
+		return nil
+	}
+	if err = writeRecords(kept); err != nil {
+		return err
+	}
+	fmt.Printf("%d entries removed, %d left (undo with mate undo)\n", len(removed), len(kept))
+	return nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const CONFIG_NAME = ".mate.toml"
+
+// Options of the config file, keyed by "section.key" (or "key" outside of any section)
+var config map[string]string
+
+// Types of the options that are not plain strings, checked when the config is loaded so that
+// reading them cannot fail. A "section.*" key applies to every key of the section, and a type
+// like "a|b" lists the accepted values
+var CONFIG_TYPES = map[string]string{
+	"auto_stop":                  "clock",
+	"balance.initial":            "signed duration",
+	"balance.since":              "date",
+	"end_of_day":                 "clock",
+	"breaks.after":               "duration",
+	"breaks.min_pause":           "duration",
+	"breaks.deduct":              "duration",
+	"clockify.billable":          "bool",
+	"daemon.untracked_reminder":  "duration",
+	"daemon.interval":            "duration",
+	"daemon.idle_after":          "duration",
+	"daemon.idle_action":         "pause|ask",
+	"daemon.suspend_action":      "subtract|ask|keep",
+	"daemon.working_days":        "weekdays",
+	"daemon.working_hours":       "clock range",
+	"daemon.pause_on_lock":       "bool",
+	"daemon.dbus":                "bool",
+	"daemon.track_windows":       "bool",
+	"discord.summary_at":         "clock",
+	"encryption.enabled":         "bool",
+	"gitlab.spend_on_stop":       "bool",
+	"holidays.region":            "holiday region",
+	"hotkeys.stop":               "hotkey",
+	"hotkeys.resume":             "hotkey",
+	"hotkeys.pick":               "hotkey",
+	"integrity.enabled":          "bool",
+	"jobs.weekly_report":         "job schedule",
+	"jobs.daily_post":            "job schedule",
+	"jobs.backup":                "job schedule",
+	"jobs.kept_backups":          "int",
+	"limits.day":                 "duration",
+	"meetings.length":            "duration",
+	"limits.week":                "duration",
+	"matrix.summary_at":          "clock",
+	"notifications.enabled":      "bool",
+	"overlaps.policy":            "reject|warn|trim",
+	"notifications.day_complete": "bool",
+	"notifications.long_ticket":  "duration",
+	"notifications.pomodoro":     "bool",
+	"pomodoro.work":              "duration",
+	"pomodoro.break":             "duration",
+	"pomodoro.long_break":        "duration",
+	"pomodoro.cycles":            "int",
+	"roles.*":                    "user|reporter|admin",
+	"schedule.*":                 "duration",
+	"sharing.*":                  "bool",
+	"status.cache":               "duration",
+	"telegram.summary_at":        "clock",
+	"toggl.workspace_id":         "int",
+}
+
+// The users of mate serve share the config of the owner of the server
+func getConfigPath() string {
+	return getProfilePath(getProfile()) + "/" + CONFIG_NAME
+}
+
+// Reads the config file, a small subset of TOML:
+//
+//	# comment
+//	[section]
+//	key = "value"
+//
+// A missing config file is the same as an empty one
+func loadConfig() error {
+	options, err := parseConfigFile(getConfigPath())
+	if err != nil {
+		return err
+	}
+	for key, value := range options {
+		if value == "" {
+			continue
+		}
+		if _, err = parseTypedValue(getConfigType(key), value); err != nil {
+			return newConfigError(fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+	if _, err = compileAliases(options); err != nil {
+		return newConfigError(err.Error())
+	}
+	if _, err = compileRules(options); err != nil {
+		return newConfigError(err.Error())
+	}
+	if _, err = compileWindowRules(options); err != nil {
+		return newConfigError(err.Error())
+	}
+	config = options
+	return nil
+}
+
+// Returns an error for an invalid or missing option of the config
+func newConfigError(message string) error {
+	return withExitCode(EXIT_CONFIG, fmt.Errorf("%s: %s", getConfigPath(), message))
+}
+
+// Reads a file in the format of the config file, keyed by "section.key"
+func parseConfigFile(path string) (map[string]string, error) {
+	options := make(map[string]string)
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return options, nil
+		}
+		return nil, fmt.Errorf("Cannot read the config: %w", err)
+	}
+	defer f.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, withExitCode(EXIT_CONFIG, fmt.Errorf("%s:%d: expected key = \"value\"", path, lineNumber))
+		}
+		// A quoted key may hold anything but quotes, e.g. a pattern of [aliases]
+		key := strings.TrimSpace(parts[0])
+		if len(key) >= 2 && key[0] == '"' && key[len(key)-1] == '"' {
+			key = key[1 : len(key)-1]
+		}
+		if section != "" {
+			key = section + "." + key
+		}
+		options[key] = parseConfigValue(parts[1])
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Cannot read the config: %w", err)
+	}
+
+	return options, nil
+}
+
+// Removes the quotes around a value, or the trailing comment of an unquoted value
+func parseConfigValue(literal string) string {
+	value := strings.TrimSpace(literal)
+	if len(value) >= 2 && value[0] == '"' {
+		if end := strings.Index(value[1:], "\""); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if comment := strings.Index(value, "#"); comment >= 0 {
+		value = strings.TrimSpace(value[:comment])
+	}
+	return value
+}
+
+// Returns the value of an option, or defaultValue if it is not set
+// Options missing from the config file are looked up in the keyring (see "mate secret set")
+// The config must have been loaded (see loadConfig)
+func getConfig(key string, defaultValue string) string {
+	if value, found := config[key]; found {
+		return value
+	}
+	if value, found := getSecret(key); found {
+		return value
+	}
+	return defaultValue
+}
+
+// Splits a comma separated list of the config, ignoring the blanks
+func splitConfigList(value string) (items []string) {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return
+}
+
+// Parses a time of the day (HH:MM) into the duration elapsed since midnight
+func parseClock(literal string) (time.Duration, error) {
+	clock, err := time.Parse(CLOCK_FORMAT, literal)
+	if err != nil {
+		return 0, errors.New("Invalid time \"" + literal + "\" (expected HH:MM)")
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// Returns the type of an option in CONFIG_TYPES, empty for a string
+func getConfigType(key string) string {
+	if configType, found := CONFIG_TYPES[key]; found {
+		return configType
+	}
+	if dot := strings.Index(key, "."); dot >= 0 {
+		return CONFIG_TYPES[key[:dot]+".*"]
+	}
+	return ""
+}
+
+// Parses the value of an option of the given type
+func parseTypedValue(configType string, literal string) (interface{}, error) {
+	switch configType {
+	case "clock":
+		return parseClock(literal)
+	case "duration":
+		duration, err := time.ParseDuration(literal)
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("Invalid duration \"%s\" (expected e.g. 1h30m)", literal)
+		}
+		return duration, nil
+	case "signed duration":
+		duration, err := time.ParseDuration(literal)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid duration \"%s\" (expected e.g. -2h30m)", literal)
+		}
+		return duration, nil
+	case "date":
+		return parseDate(literal)
+	case "weekdays":
+		return parseWeekdays(literal)
+	case "clock range":
+		start, end, err := parseClockRange(literal)
+		if err != nil {
+			return nil, err
+		}
+		return [2]time.Duration{start, end}, nil
+	case "holiday region":
+		if _, found := HOLIDAY_REGIONS[strings.ToLower(literal)]; !found {
+			return nil, fmt.Errorf("unknown region \"%s\" (expected fr, de or us)", literal)
+		}
+		return strings.ToLower(literal), nil
+	case "hotkey":
+		return parseHotkey(literal)
+	case "job schedule":
+		return parseJobSchedule(literal)
+	case "int":
+		n, err := strconv.Atoi(literal)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("Invalid number \"%s\"", literal)
+		}
+		return n, nil
+	case "bool":
+		switch strings.ToLower(literal) {
+		case "true", "yes", "on", "1":
+			return true, nil
+		case "false", "no", "off", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("Invalid boolean \"%s\" (expected true or false)", literal)
+	}
+	if values := strings.Split(configType, "|"); len(values) > 1 && !contains(values, literal) {
+		last := len(values) - 1
+		return nil, fmt.Errorf("Invalid value \"%s\" (expected %s or %s)", literal, strings.Join(values[:last], ", "), values[last])
+	}
+	return literal, nil
+}
+
+// Returns a typed option, which the key must be declared with in CONFIG_TYPES
+// An empty value is the same as a missing one
+// The value is valid, being checked by loadConfig (or by "mate secret set" for the keyring)
+func getTypedConfig(key string, configType string, defaultValue string) interface{} {
+	if getConfigType(key) != configType {
+		panic(key + " is not declared as a " + configType + " in CONFIG_TYPES")
+	}
+	literal := getConfig(key, "")
+	if literal == "" {
+		literal = defaultValue
+	}
+	value, _ := parseTypedValue(configType, literal)
+	return value
+}
+
+// Returns an option holding a time of the day, as the duration elapsed since midnight
+func getConfigClock(key string, defaultValue string) time.Duration {
+	return getTypedConfig(key, "clock", defaultValue).(time.Duration)
+}
+
+// Returns an option holding a duration (e.g. "1h30m")
+func getConfigDuration(key string, defaultValue string) time.Duration {
+	return getTypedConfig(key, "duration", defaultValue).(time.Duration)
+}
+
+// Returns an option holding a positive integer
+func getConfigInt(key string, defaultValue string) int {
+	return getTypedConfig(key, "int", defaultValue).(int)
+}
+
+// Returns an option holding a boolean (true/false, yes/no, on/off)
+func getConfigBool(key string, defaultValue bool) bool {
+	return getTypedConfig(key, "bool", strconv.FormatBool(defaultValue)).(bool)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Header of an encrypted database, followed by the salt of the key, the nonce and the AES-GCM ciphertext
+const ENCRYPTED_DB_MAGIC = "mate-encrypted-v1\n"
+const ENCRYPTION_SALT_SIZE = 16
+const PBKDF2_ITERATIONS = 600000
+
+// Keys derived from the passphrase, by salt, as the derivation is slow on purpose
+var encryptionKeys = map[string][]byte{}
+var encryptionPassphrase string
+
+// Salt of the database read last, reused when it is written back
+var encryptionSalt []byte
+
+// Derives a key from a passphrase with PBKDF2-HMAC-SHA256 (RFC 8018)
+func deriveKey(passphrase []byte, salt []byte, iterations int, size int) []byte {
+	var key []byte
+	for block := uint32(1); len(key) < size; block++ {
+		mac := hmac.New(sha256.New, passphrase)
+		mac.Write(salt)
+		binary.Write(mac, binary.BigEndian, block)
+		u := mac.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			mac.Reset()
+			mac.Write(u)
+			u = mac.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:size]
+}
+
+func isEncryptionEnabled() bool {
+	return getConfigBool("encryption.enabled", false)
+}
+
+func isEncryptedDatabase(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(ENCRYPTED_DB_MAGIC))
+}
+
+// Reads a secret on the terminal, without echoing it where stty is available
+func readSecret(prompt string) (string, error) {
+	if !isTerminal(os.Stdin) {
+		return "", errors.New("no terminal to type it on")
+	}
+	stty := exec.Command("stty", "-echo")
+	stty.Stdin = os.Stdin
+	if stty.Run() == nil {
+		defer func() {
+			stty = exec.Command("stty", "echo")
+			stty.Stdin = os.Stdin
+			stty.Run()
+			fmt.Println()
+		}()
+	}
+	fmt.Print(prompt)
+	secret, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", errors.New("nothing typed")
+	}
+	return strings.TrimRight(secret, "\r\n"), nil
+}
+
+// Returns the passphrase of the database: $MATE_PASSPHRASE, else encryption.passphrase (e.g. stored with
+// "mate secret set encryption.passphrase"), else the output of encryption.passphrase_command
+// (e.g. "pass show mate"), else the one typed on the terminal
+func getPassphrase() (string, error) {
+	if encryptionPassphrase != "" {
+		return encryptionPassphrase, nil
+	}
+	passphrase := os.Getenv("MATE_PASSPHRASE")
+	if passphrase == "" {
+		passphrase = getConfig("encryption.passphrase", "")
+	}
+	if command := getConfig("encryption.passphrase_command", ""); passphrase == "" && command != "" {
+		cmd := getShellCommand(command)
+		cmd.Stderr = os.Stderr
+		output, err := cmd.Output()
+		if err != nil {
+			return "", errors.New("encryption.passphrase_command: " + err.Error())
+		}
+		passphrase = strings.TrimRight(string(output), "\r\n")
+	}
+	if passphrase == "" {
+		var err error
+		if passphrase, err = readSecret("Database passphrase: "); err != nil {
+			return "", errors.New("no passphrase (set MATE_PASSPHRASE or encryption.passphrase_command): " + err.Error())
+		}
+	}
+	if passphrase == "" {
+		return "", errors.New("empty passphrase")
+	}
+	encryptionPassphrase = passphrase
+	return passphrase, nil
+}
+
+func getEncryptionCipher(salt []byte) (cipher.AEAD, error) {
+	key, found := encryptionKeys[string(salt)]
+	if !found {
+		passphrase, err := getPassphrase()
+		if err != nil {
+			return nil, err
+		}
+		key = deriveKey([]byte(passphrase), salt, PBKDF2_ITERATIONS, 32)
+		encryptionKeys[string(salt)] = key
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptDatabase(content []byte) ([]byte, error) {
+	if encryptionSalt == nil {
+		encryptionSalt = make([]byte, ENCRYPTION_SALT_SIZE)
+		if _, err := rand.Read(encryptionSalt); err != nil {
+			return nil, err
+		}
+	}
+	aead, err := getEncryptionCipher(encryptionSalt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	encrypted := append([]byte(ENCRYPTED_DB_MAGIC), encryptionSalt...)
+	encrypted = append(encrypted, nonce...)
+	return aead.Seal(encrypted, nonce, content, []byte(ENCRYPTED_DB_MAGIC)), nil
+}
+
+func decryptDatabase(content []byte) ([]byte, error) {
+	content = content[len(ENCRYPTED_DB_MAGIC):]
+	if len(content) < ENCRYPTION_SALT_SIZE {
+		return nil, errors.New("truncated encrypted database")
+	}
+	salt, content := content[:ENCRYPTION_SALT_SIZE], content[ENCRYPTION_SALT_SIZE:]
+	aead, err := getEncryptionCipher(salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) < aead.NonceSize() {
+		return nil, errors.New("truncated encrypted database")
+	}
+	decrypted, err := aead.Open(nil, content[:aead.NonceSize()], content[aead.NonceSize():], []byte(ENCRYPTED_DB_MAGIC))
+	if err != nil {
+		return nil, errors.New("cannot decrypt the database (wrong passphrase?)")
+	}
+	encryptionSalt = append([]byte(nil), salt...)
+	return decrypted, nil
+}
+
+// Parses a database, decrypting it if needed
+func parseDatabase(content []byte) ([]Record, error) {
+	if isEncryptedDatabase(content) {
+		var err error
+		if content, err = decryptDatabase(content); err != nil {
+			return nil, err
+		}
+	}
+	return parseRecords(bytes.NewReader(content))
+}
+
+// Formats the records as a database, encrypted if encryption.enabled is set
+func formatDatabase(records []Record) ([]byte, error) {
+	content := []byte(formatRecords(records))
+	if !isEncryptionEnabled() {
+		return content, nil
+	}
+	return encryptDatabase(content)
+}
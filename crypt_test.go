@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Sets the passphrase of the database, forgetting the keys and the salt of the previous tests
+func useTestPassphrase(t *testing.T, passphrase string) {
+	encryptionKeys, encryptionPassphrase, encryptionSalt = map[string][]byte{}, passphrase, nil
+	t.Cleanup(func() {
+		encryptionKeys, encryptionPassphrase, encryptionSalt = map[string][]byte{}, "", nil
+	})
+}
+
+func TestDeriveKey(t *testing.T) {
+	// The PBKDF2-HMAC-SHA256 test vectors of RFC 7914
+	tests := []struct {
+		name       string
+		passphrase string
+		salt       string
+		iterations int
+		size       int
+		want       string
+	}{
+		{"one iteration", "passwd", "salt", 1, 64, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"several blocks", "Password", "NaCl", 80000, 64, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+		{"truncated", "passwd", "salt", 1, 16, "55ac046e56e3089fec1691c22544b605"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := deriveKey([]byte(test.passphrase), []byte(test.salt), test.iterations, test.size)
+			if hex.EncodeToString(got) != test.want {
+				t.Errorf("got %x, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEncryptDatabase(t *testing.T) {
+	content := []byte(CSV_HEADER + "2026/10/14 09:00:00,A\n2026/10/14 10:00:00,mate:STOP\n")
+	tests := []struct {
+		name    string
+		change  func(encrypted []byte) []byte
+		reader  string
+		wantErr bool
+	}{
+		{"round trip", func(encrypted []byte) []byte { return encrypted }, "secret", false},
+		{"wrong passphrase", func(encrypted []byte) []byte { return encrypted }, "guess", true},
+		{"truncated salt", func(encrypted []byte) []byte { return encrypted[:len(ENCRYPTED_DB_MAGIC)+4] }, "secret", true},
+		{"truncated nonce", func(encrypted []byte) []byte { return encrypted[:len(ENCRYPTED_DB_MAGIC)+ENCRYPTION_SALT_SIZE+4] }, "secret", true},
+		{"tampered", func(encrypted []byte) []byte { encrypted[len(encrypted)-1] ^= 1; return encrypted }, "secret", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useTestPassphrase(t, "secret")
+			encrypted, err := encryptDatabase(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !isEncryptedDatabase(encrypted) || bytes.Contains(encrypted, []byte("2026/10/14")) {
+				t.Fatalf("got %q, want it encrypted", encrypted)
+			}
+
+			// Read by another process, without the key derived on the encryption
+			useTestPassphrase(t, test.reader)
+			decrypted, err := decryptDatabase(test.change(encrypted))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && !bytes.Equal(decrypted, content) {
+				t.Errorf("got %q, want %q", decrypted, content)
+			}
+		})
+	}
+}
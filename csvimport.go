@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields of an entry that --map takes columns for
+var CSV_IMPORT_FIELDS = []string{"start", "end", "duration", "title", "date"}
+
+// Layouts tried for the timestamps of a column, the first one parsing all of its values being used
+var CSV_IMPORT_TIME_LAYOUTS = []string{
+	time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04",
+	TIME_FORMAT, "2006/01/02 15:04", "02/01/2006 15:04:05", "02/01/2006 15:04", "01/02/2006 15:04:05",
+	"01/02/2006 15:04", "01/02/2006 3:04 PM", "02.01.2006 15:04:05", "02.01.2006 15:04",
+}
+
+// Layouts tried for the dates of a date column, the start and end columns then holding times from CSV_IMPORT_CLOCK_LAYOUTS
+var CSV_IMPORT_DATE_LAYOUTS = []string{"2006-01-02", DATE_FORMAT, "02/01/2006", "01/02/2006", "02.01.2006", "Jan 2, 2006", "2 Jan 2006"}
+var CSV_IMPORT_CLOCK_LAYOUTS = []string{"15:04:05", "15:04", "3:04 PM", "3:04PM", "3:04:05 PM"}
+
+// Parses a mapping of the fields to the columns, e.g. "start=Start Time,end=End,title=Project+Task"
+// A title may join several columns with "+"
+func parseCSVMapping(literal string) (map[string][]string, error) {
+	mapping := make(map[string][]string)
+	if strings.TrimSpace(literal) == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(literal, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		field := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || !contains(CSV_IMPORT_FIELDS, field) {
+			return nil, fmt.Errorf("Invalid mapping \"%s\" (expected field=column, the fields being %s)", pair, strings.Join(CSV_IMPORT_FIELDS, ", "))
+		}
+		for _, column := range strings.Split(parts[1], "+") {
+			mapping[field] = append(mapping[field], strings.TrimSpace(column))
+		}
+	}
+	return mapping, nil
+}
+
+// Returns the separator of a CSV file from its header: a comma, a semicolon or a tab
+func detectCSVSeparator(header string) rune {
+	separator, count := ',', strings.Count(header, ",")
+	for _, candidate := range []rune{';', '\t'} {
+		if n := strings.Count(header, string(candidate)); n > count {
+			separator, count = candidate, n
+		}
+	}
+	return separator
+}
+
+// Returns the first layout parsing all the values
+func detectTimeLayout(values []string, layouts []string) (string, bool) {
+	for _, layout := range layouts {
+		matches := true
+		for _, value := range values {
+			if _, err := time.Parse(layout, value); value != "" && err != nil {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return layout, true
+		}
+	}
+	return "", false
+}
+
+// Parses a duration as 1h30m, 1:30 (or 1:30:00) or 1.5 hours
+func parseImportDuration(literal string) (time.Duration, error) {
+	if duration, err := time.ParseDuration(literal); err == nil {
+		return duration, nil
+	}
+	if parts := strings.Split(literal, ":"); len(parts) == 2 || len(parts) == 3 {
+		var duration time.Duration
+		for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second}[:len(parts)] {
+			n, err := strconv.Atoi(parts[i])
+			if err != nil {
+				return 0, err
+			}
+			duration += time.Duration(n) * unit
+		}
+		return duration, nil
+	}
+	hours, err := strconv.ParseFloat(strings.Replace(literal, ",", ".", 1), 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(hours * float64(time.Hour)).Round(time.Second), nil
+}
+
+// Converts the rows of a CSV file to periods, its columns being mapped to the fields of the entries
+// Unmapped fields are read from the columns of the same name (as in the csv export), and the formats of the
+// timestamps are recognized per column
+func importCSV(r io.Reader, mappingLiteral string) ([]Interval, error) {
+	mapping, err := parseCSVMapping(mappingLiteral)
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(strings.NewReader(string(content)))
+	reader.Comma = detectCSVSeparator(strings.SplitN(string(content), "\n", 2)[0])
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("the file is empty")
+	}
+
+	positions := make(map[string]int)
+	for i, name := range rows[0] {
+		positions[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	columns := make(map[string][]int)
+	for _, field := range CSV_IMPORT_FIELDS {
+		names, mapped := mapping[field]
+		if !mapped {
+			names = []string{field}
+		}
+		for _, name := range names {
+			position, found := positions[strings.ToLower(name)]
+			if !found && mapped {
+				return nil, fmt.Errorf("no column \"%s\" for the %s (the columns are %s)", name, field, strings.Join(rows[0], ", "))
+			}
+			if found {
+				columns[field] = append(columns[field], position)
+			}
+		}
+	}
+	if columns["start"] == nil || columns["title"] == nil || columns["end"] == nil && columns["duration"] == nil {
+		return nil, errors.New("the start, the title and the end or the duration are needed: map them with --map \"start=column,end=column,title=column\"")
+	}
+
+	rows = rows[1:]
+	cell := func(row []string, field string) string {
+		var values []string
+		for _, position := range columns[field] {
+			if position < len(row) && strings.TrimSpace(row[position]) != "" {
+				values = append(values, strings.TrimSpace(row[position]))
+			}
+		}
+		return strings.Join(values, " ")
+	}
+	layouts := make(map[string]string)
+	for _, field := range []string{"date", "start", "end"} {
+		if columns[field] == nil {
+			continue
+		}
+		candidates := CSV_IMPORT_TIME_LAYOUTS
+		if field == "date" {
+			candidates = CSV_IMPORT_DATE_LAYOUTS
+		} else if columns["date"] != nil {
+			candidates = CSV_IMPORT_CLOCK_LAYOUTS
+		}
+		var values []string
+		for _, row := range rows {
+			values = append(values, cell(row, field))
+		}
+		layout, found := detectTimeLayout(values, candidates)
+		if !found {
+			return nil, fmt.Errorf("cannot recognize the format of the %s column (e.g. \"%s\")", field, values[0])
+		}
+		layouts[field] = layout
+	}
+	parseTime := func(field string, value string, date time.Time) time.Time {
+		t, _ := time.Parse(layouts[field], value)
+		if strings.Contains(layouts[field], "Z07") {
+			return toDbTime(t.Local())
+		}
+		if !date.IsZero() {
+			return date.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second)
+		}
+		return t
+	}
+
+	var periods []Interval
+	for i, row := range rows {
+		if cell(row, "start") == "" && cell(row, "title") == "" {
+			continue
+		}
+		var date time.Time
+		if columns["date"] != nil {
+			if date = parseTime("date", cell(row, "date"), time.Time{}); cell(row, "date") == "" {
+				return nil, fmt.Errorf("line %d: no date", i+2)
+			}
+		}
+		if cell(row, "start") == "" {
+			return nil, fmt.Errorf("line %d: no start", i+2)
+		}
+		start := parseTime("start", cell(row, "start"), date)
+
+		var end time.Time
+		if value := cell(row, "end"); value != "" {
+			if end = parseTime("end", value, date); !end.After(start) && !date.IsZero() {
+				end = end.AddDate(0, 0, 1)
+			}
+		} else if value := cell(row, "duration"); value != "" {
+			duration, err := parseImportDuration(value)
+			if err != nil || duration <= 0 {
+				return nil, fmt.Errorf("line %d: invalid duration \"%s\"", i+2, value)
+			}
+			end = start.Add(duration)
+		} else {
+			return nil, fmt.Errorf("line %d: no end nor duration", i+2)
+		}
+		periods = append(periods, Interval{start, end, buildImportedTitle(cell(row, "title"), nil)})
+	}
+
+	sortPeriods(periods)
+	return periods, nil
+}
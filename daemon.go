@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const DEFAULT_DAEMON_INTERVAL = "1m"
+const DEFAULT_UNTRACKED_REMINDER = "15m"
+const DEFAULT_WORKING_HOURS = "09:00-18:00"
+const DEFAULT_WORKING_DAYS = "mon,tue,wed,thu,fri"
+
+var WEEKDAYS = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Parses a comma separated list of days (e.g. "mon,tue,fri")
+func parseWeekdays(literal string) (weekdays []time.Weekday, err error) {
+	for _, name := range strings.Split(literal, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if len(name) > 3 {
+			name = name[:3]
+		}
+		weekday, found := WEEKDAYS[name]
+		if !found {
+			return nil, errors.New("Invalid day \"" + name + "\" (expected mon, tue, ...)")
+		}
+		weekdays = append(weekdays, weekday)
+	}
+	return
+}
+
+// Parses a range of hours (e.g. "09:00-18:00") into durations since midnight
+func parseClockRange(literal string) (start time.Duration, end time.Duration, err error) {
+	parts := strings.Split(literal, "-")
+	if len(parts) != 2 {
+		return 0, 0, errors.New("Invalid range \"" + literal + "\" (expected HH:MM-HH:MM)")
+	}
+	if start, err = parseClock(strings.TrimSpace(parts[0])); err != nil {
+		return
+	}
+	end, err = parseClock(strings.TrimSpace(parts[1]))
+	return
+}
+
+// Returns the working hours of the given day, as set by daemon.working_hours and daemon.working_days
+// The last value is false if the day is not a working day
+func getWorkingHours(day time.Time) (start time.Time, end time.Time, working bool) {
+	weekdays := getTypedConfig("daemon.working_days", "weekdays", DEFAULT_WORKING_DAYS).([]time.Weekday)
+	hours := getTypedConfig("daemon.working_hours", "clock range", DEFAULT_WORKING_HOURS).([2]time.Duration)
+	start, end = day.Add(hours[0]), day.Add(hours[1])
+
+	for _, weekday := range weekdays {
+		if weekday == day.Weekday() {
+			working = true
+		}
+	}
+	return
+}
+
+// Returns since when no ticket is running, or false if a ticket is running
+func getUntrackedSince(records []Record) (time.Time, bool) {
+	if len(records) == 0 {
+		return time.Time{}, true
+	}
+	last := records[len(records)-1]
+	if last.title != STOP_TOKEN {
+		if isAutoStopped(records) {
+			return getRunningEnd(last.timestamp), true
+		}
+		return time.Time{}, false
+	}
+	return last.timestamp, true
+}
+
+// Reminds to start a ticket when none has been running for daemon.untracked_reminder during working hours
+// The reminder is repeated every daemon.untracked_reminder
+func checkUntrackedTime() error {
+	reminder := getConfigDuration("daemon.untracked_reminder", DEFAULT_UNTRACKED_REMINDER)
+	if reminder == 0 {
+		return nil
+	}
+
+	now := getNow()
+	start, end, working := getWorkingHours(now.Truncate(time.Hour * 24))
+	if !working || now.Before(start) || !now.Before(end) {
+		return nil
+	}
+
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	since, untracked := getUntrackedSince(records)
+	if !untracked {
+		return nil
+	}
+	if since.Before(start) {
+		since = start
+	}
+
+	elapsed := now.Sub(since)
+	if elapsed < reminder {
+		return nil
+	}
+	reminderNumber := int(elapsed / reminder)
+	message := fmt.Sprintf("No ticket running for %v", elapsed.Truncate(time.Minute))
+	notified, err := notifyOnce(fmt.Sprintf("untracked %s %d", since.Format(TIME_FORMAT), reminderNumber), message)
+	if notified {
+		fmt.Printf("%s %s\n", now.Format(CLOCK_FORMAT), message)
+	}
+	return err
+}
+
+// Runs the checks of the daemon, the config being reloaded so that its changes apply without a restart
+func runDaemonChecks(suspendWatcher *SuspendWatcher, idleWatcher *IdleWatcher, windowWatcher *WindowWatcher) error {
+	if err := loadConfig(); err != nil {
+		return err
+	}
+	beginAuditOperation("mate daemon")
+	for _, check := range []func() error{
+		suspendWatcher.check,
+		reconcileTimer,
+		checkNotifications,
+		checkDayCompleteWebhook,
+		checkUntrackedTime,
+		idleWatcher.check,
+		windowWatcher.check,
+		checkScheduledJobs,
+	} {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prints the error of a check, the daemon carrying on
+func reportDaemonError(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %v\n", getNow().Format(CLOCK_FORMAT), err)
+	}
+}
+
+// Runs the periodic checks until interrupted
+func runDaemon() error {
+	interval := getConfigDuration("daemon.interval", DEFAULT_DAEMON_INTERVAL)
+	if interval == 0 {
+		return errors.New("daemon.interval must be a positive duration")
+	}
+	if !getConfigBool("notifications.enabled", false) {
+		fmt.Println("Desktop notifications are disabled (notifications.enabled), reminders will only be printed")
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var idleWatcher IdleWatcher
+	var screenWatcher ScreenWatcher
+	var suspendWatcher SuspendWatcher
+	var windowWatcher WindowWatcher
+	var screenEvents <-chan ScreenEvent
+	if getConfigBool("daemon.pause_on_lock", false) {
+		var err error
+		if screenEvents, err = watchScreen(); err != nil {
+			fmt.Printf("Screen lock detection disabled: %v\n", err)
+		}
+	}
+
+	var tracker *TrackerService
+	var trackerCalls <-chan *DBusMessage
+	if getConfigBool("daemon.dbus", false) {
+		var err error
+		if tracker, err = startTrackerService(); err != nil {
+			fmt.Printf("D-Bus service disabled: %v\n", err)
+		} else {
+			defer tracker.bus.close()
+			trackerCalls = tracker.calls
+			fmt.Printf("D-Bus service %s running\n", DBUS_TRACKER_NAME)
+		}
+	}
+
+	hotkeys, err := watchHotkeys()
+	if err != nil {
+		fmt.Printf("Hotkeys disabled: %v\n", err)
+	} else if hotkeys != nil {
+		fmt.Println("Hotkeys registered on the X11 display")
+	}
+	picked := make(chan string)
+
+	fmt.Printf("mate daemon running (every %v), press Ctrl+C to stop\n", interval)
+	for {
+		reportDaemonError(runDaemonChecks(&suspendWatcher, &idleWatcher, &windowWatcher))
+		if tracker != nil {
+			reportDaemonError(tracker.emitChanges())
+		}
+
+		// The D-Bus calls and the hotkeys are answered between the checks
+		for waiting := true; waiting; {
+			select {
+			case <-ticker.C:
+				waiting = false
+			case event := <-screenEvents:
+				reportDaemonError(screenWatcher.handle(event))
+				waiting = false
+			case call, ok := <-trackerCalls:
+				if !ok {
+					fmt.Println("D-Bus service stopped: the connection to the bus was lost")
+					tracker, trackerCalls = nil, nil
+					continue
+				}
+				reportDaemonError(tracker.handle(call))
+			case action, ok := <-hotkeys:
+				if !ok {
+					fmt.Println("Hotkeys disabled: the connection to the display was lost")
+					hotkeys = nil
+					continue
+				}
+				reportDaemonError(runHotkeyAction(action, picked))
+				waiting = false
+			case title := <-picked:
+				reportDaemonError(startPickedTicket(title))
+				waiting = false
+			case <-interrupt:
+				fmt.Println("mate daemon stopped")
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func equalWeekdays(a []time.Weekday, b []time.Weekday) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseWeekdays(t *testing.T) {
+	tests := []struct {
+		name    string
+		literal string
+		wantErr bool
+		want    []time.Weekday
+	}{
+		{"empty", "", false, nil},
+		{"one day", "mon", false, []time.Weekday{time.Monday}},
+		{"several days", "mon,tue,fri", false, []time.Weekday{time.Monday, time.Tuesday, time.Friday}},
+		{"case, spaces and full names", "Saturday, SUN", false, []time.Weekday{time.Saturday, time.Sunday}},
+		{"empty items", "mon,,wed,", false, []time.Weekday{time.Monday, time.Wednesday}},
+		{"unknown day", "mon,fun", true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseWeekdays(test.literal)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if !equalWeekdays(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseClockRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		literal   string
+		wantErr   bool
+		wantStart time.Duration
+		wantEnd   time.Duration
+	}{
+		{"working hours", "09:00-18:00", false, 9 * time.Hour, 18 * time.Hour},
+		{"spaces and minutes", "08:30 - 17:45", false, 8*time.Hour + 30*time.Minute, 17*time.Hour + 45*time.Minute},
+		{"no separator", "09:00", true, 0, 0},
+		{"too many parts", "09:00-12:00-18:00", true, 0, 0},
+		{"invalid start", "9h-18:00", true, 0, 0},
+		{"invalid end", "09:00-25:00", true, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, end, err := parseClockRange(test.literal)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && (start != test.wantStart || end != test.wantEnd) {
+				t.Errorf("got %v-%v, want %v-%v", start, end, test.wantStart, test.wantEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,626 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	DBUS_METHOD_CALL   = 1
+	DBUS_METHOD_RETURN = 2
+	DBUS_ERROR         = 3
+	DBUS_SIGNAL        = 4
+)
+
+// Codes of the header fields
+const (
+	DBUS_FIELD_PATH         = 1
+	DBUS_FIELD_INTERFACE    = 2
+	DBUS_FIELD_MEMBER       = 3
+	DBUS_FIELD_ERROR_NAME   = 4
+	DBUS_FIELD_REPLY_SERIAL = 5
+	DBUS_FIELD_DESTINATION  = 6
+	DBUS_FIELD_SENDER       = 7
+	DBUS_FIELD_SIGNATURE    = 8
+)
+
+const DBUS_FLAG_NO_REPLY_EXPECTED = 0x1
+
+// The max size of the messages of the bus, which are not expected to carry more than a few titles
+const MAX_DBUS_MESSAGE = 1024 * 1024
+
+// A value of type v, with the signature of its content
+type DBusVariant struct {
+	signature string
+	value     interface{}
+}
+
+// A message of the bus, its body holding a value per complete type of its signature
+type DBusMessage struct {
+	kind        byte
+	flags       byte
+	serial      uint32
+	path        string
+	iface       string
+	member      string
+	errorName   string
+	replySerial uint32
+	destination string
+	sender      string
+	signature   string
+	body        []interface{}
+}
+
+// A minimal connection to the session bus: enough to own a name, answer method calls and emit signals
+type DBusConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	lock   sync.Mutex // Serializes the writes
+	serial uint32
+}
+
+// Connects to the session bus of $DBUS_SESSION_BUS_ADDRESS, authenticated as the current user
+func connectSessionBus() (*DBusConn, error) {
+	address := os.Getenv("DBUS_SESSION_BUS_ADDRESS")
+	var conn net.Conn
+	var err error
+	for _, candidate := range strings.Split(address, ";") {
+		if !strings.HasPrefix(candidate, "unix:") {
+			continue
+		}
+		for _, parameter := range strings.Split(strings.TrimPrefix(candidate, "unix:"), ",") {
+			switch {
+			case strings.HasPrefix(parameter, "path="):
+				conn, err = net.Dial("unix", strings.TrimPrefix(parameter, "path="))
+			case strings.HasPrefix(parameter, "abstract="):
+				conn, err = net.Dial("unix", "@"+strings.TrimPrefix(parameter, "abstract="))
+			}
+		}
+		if conn != nil {
+			break
+		}
+	}
+	if conn == nil {
+		if err == nil {
+			err = fmt.Errorf("no unix address in DBUS_SESSION_BUS_ADDRESS \"%s\"", address)
+		}
+		return nil, err
+	}
+
+	bus := &DBusConn{conn: conn, reader: bufio.NewReader(conn)}
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err = conn.Write([]byte("\x00AUTH EXTERNAL " + uid + "\r\n")); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	line, err := bus.reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "OK ") {
+		conn.Close()
+		return nil, fmt.Errorf("the bus refused the authentication: %s", strings.TrimSpace(line))
+	}
+	if _, err = conn.Write([]byte("BEGIN\r\n")); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if _, err = bus.call("Hello", ""); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return bus, nil
+}
+
+// Calls a method of the bus itself, waiting for its reply
+// The connection must not be read by anything else meanwhile
+func (bus *DBusConn) call(member string, signature string, args ...interface{}) (*DBusMessage, error) {
+	serial, err := bus.send(&DBusMessage{kind: DBUS_METHOD_CALL, path: "/org/freedesktop/DBus", iface: "org.freedesktop.DBus",
+		member: member, destination: "org.freedesktop.DBus", signature: signature, body: args})
+	if err != nil {
+		return nil, err
+	}
+	for {
+		reply, err := bus.read()
+		if err != nil {
+			return nil, err
+		}
+		if reply.replySerial != serial {
+			continue
+		}
+		if reply.kind == DBUS_ERROR {
+			message := reply.errorName
+			if len(reply.body) != 0 {
+				message += ": " + fmt.Sprint(reply.body[0])
+			}
+			return nil, errors.New(message)
+		}
+		return reply, nil
+	}
+}
+
+// Takes a well-known name, failing if another connection owns it
+func (bus *DBusConn) requestName(name string) error {
+	// DBUS_NAME_FLAG_DO_NOT_QUEUE
+	reply, err := bus.call("RequestName", "su", name, uint32(4))
+	if err != nil {
+		return err
+	}
+	if len(reply.body) != 1 || reply.body[0] != uint32(1) {
+		return fmt.Errorf("%s is already owned on the bus", name)
+	}
+	return nil
+}
+
+// Replies to a method call
+func (bus *DBusConn) reply(call *DBusMessage, signature string, args ...interface{}) error {
+	if call.flags&DBUS_FLAG_NO_REPLY_EXPECTED != 0 {
+		return nil
+	}
+	_, err := bus.send(&DBusMessage{kind: DBUS_METHOD_RETURN, replySerial: call.serial, destination: call.sender,
+		signature: signature, body: args})
+	return err
+}
+
+// Replies to a method call with an error, e.g. org.freedesktop.DBus.Error.UnknownMethod
+func (bus *DBusConn) replyError(call *DBusMessage, name string, message string) error {
+	if call.flags&DBUS_FLAG_NO_REPLY_EXPECTED != 0 {
+		return nil
+	}
+	_, err := bus.send(&DBusMessage{kind: DBUS_ERROR, replySerial: call.serial, destination: call.sender,
+		errorName: name, signature: "s", body: []interface{}{message}})
+	return err
+}
+
+// Emits a signal to every connection listening to it
+func (bus *DBusConn) emit(path string, iface string, member string, signature string, args ...interface{}) error {
+	_, err := bus.send(&DBusMessage{kind: DBUS_SIGNAL, path: path, iface: iface, member: member, signature: signature, body: args})
+	return err
+}
+
+func (bus *DBusConn) close() {
+	bus.conn.Close()
+}
+
+// Sends a message, returning its serial
+func (bus *DBusConn) send(message *DBusMessage) (uint32, error) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	bus.serial++
+	message.serial = bus.serial
+
+	body := &DBusEncoder{}
+	types, err := splitDBusSignature(message.signature)
+	if err != nil {
+		return 0, err
+	}
+	if len(types) != len(message.body) {
+		return 0, fmt.Errorf("%d values for the signature \"%s\"", len(message.body), message.signature)
+	}
+	for i, t := range types {
+		if err = body.encode(t, message.body[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	var fields []interface{}
+	for _, field := range []struct {
+		code      byte
+		signature string
+		value     interface{}
+		set       bool
+	}{
+		{DBUS_FIELD_PATH, "o", message.path, message.path != ""},
+		{DBUS_FIELD_INTERFACE, "s", message.iface, message.iface != ""},
+		{DBUS_FIELD_MEMBER, "s", message.member, message.member != ""},
+		{DBUS_FIELD_ERROR_NAME, "s", message.errorName, message.errorName != ""},
+		{DBUS_FIELD_REPLY_SERIAL, "u", message.replySerial, message.replySerial != 0},
+		{DBUS_FIELD_DESTINATION, "s", message.destination, message.destination != ""},
+		{DBUS_FIELD_SIGNATURE, "g", message.signature, message.signature != ""},
+	} {
+		if field.set {
+			fields = append(fields, []interface{}{field.code, DBusVariant{field.signature, field.value}})
+		}
+	}
+
+	header := &DBusEncoder{}
+	header.buffer = append(header.buffer, 'l', message.kind, message.flags, 1)
+	header.encodeUint32(uint32(len(body.buffer)))
+	header.encodeUint32(message.serial)
+	if err = header.encode("a(yv)", fields); err != nil {
+		return 0, err
+	}
+	header.align(8)
+
+	_, err = bus.conn.Write(append(header.buffer, body.buffer...))
+	return message.serial, err
+}
+
+// Reads the next message of the bus
+func (bus *DBusConn) read() (*DBusMessage, error) {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(bus.reader, fixed); err != nil {
+		return nil, err
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if fixed[0] == 'B' {
+		order = binary.BigEndian
+	}
+	bodyLength, fieldsLength := order.Uint32(fixed[4:8]), order.Uint32(fixed[12:16])
+	headerLength := 16 + int(fieldsLength)
+	padding := (8 - headerLength%8) % 8
+	if int(fieldsLength)+padding+int(bodyLength) > MAX_DBUS_MESSAGE {
+		return nil, errors.New("D-Bus message too large")
+	}
+	rest := make([]byte, int(fieldsLength)+padding+int(bodyLength))
+	if _, err := io.ReadFull(bus.reader, rest); err != nil {
+		return nil, err
+	}
+	content := append(fixed, rest...)
+
+	message := &DBusMessage{kind: fixed[1], flags: fixed[2], serial: order.Uint32(fixed[8:12])}
+	header := &DBusDecoder{buffer: content[:headerLength], order: order, offset: 12}
+	fields, err := header.decode("a(yv)")
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields.([]interface{}) {
+		values := field.([]interface{})
+		variant := values[1].(DBusVariant)
+		switch values[0].(byte) {
+		case DBUS_FIELD_PATH:
+			message.path, _ = variant.value.(string)
+		case DBUS_FIELD_INTERFACE:
+			message.iface, _ = variant.value.(string)
+		case DBUS_FIELD_MEMBER:
+			message.member, _ = variant.value.(string)
+		case DBUS_FIELD_ERROR_NAME:
+			message.errorName, _ = variant.value.(string)
+		case DBUS_FIELD_REPLY_SERIAL:
+			message.replySerial, _ = variant.value.(uint32)
+		case DBUS_FIELD_DESTINATION:
+			message.destination, _ = variant.value.(string)
+		case DBUS_FIELD_SENDER:
+			message.sender, _ = variant.value.(string)
+		case DBUS_FIELD_SIGNATURE:
+			message.signature, _ = variant.value.(string)
+		}
+	}
+
+	types, err := splitDBusSignature(message.signature)
+	if err != nil {
+		return nil, err
+	}
+	body := &DBusDecoder{buffer: content[headerLength+padding:], order: order}
+	for _, t := range types {
+		value, err := body.decode(t)
+		if err != nil {
+			return nil, err
+		}
+		message.body = append(message.body, value)
+	}
+	return message, nil
+}
+
+// Splits a signature into its complete types, e.g. "sa{sv}as" into "s", "a{sv}" and "as"
+func splitDBusSignature(signature string) (types []string, err error) {
+	for signature != "" {
+		length, err := getDBusTypeLength(signature)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, signature[:length])
+		signature = signature[length:]
+	}
+	return
+}
+
+// Returns the length of the first complete type of a signature
+func getDBusTypeLength(signature string) (int, error) {
+	if signature == "" {
+		return 0, errors.New("incomplete D-Bus signature")
+	}
+	switch signature[0] {
+	case 'a':
+		length, err := getDBusTypeLength(signature[1:])
+		return 1 + length, err
+	case '(', '{':
+		closing := map[byte]byte{'(': ')', '{': '}'}[signature[0]]
+		for i := 1; i < len(signature); {
+			if signature[i] == closing {
+				return i + 1, nil
+			}
+			length, err := getDBusTypeLength(signature[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += length
+		}
+		return 0, fmt.Errorf("unclosed %c in the D-Bus signature \"%s\"", signature[0], signature)
+	case 'y', 'b', 'n', 'q', 'i', 'u', 'x', 't', 'd', 's', 'o', 'g', 'v', 'h':
+		return 1, nil
+	}
+	return 0, fmt.Errorf("unknown type %c in the D-Bus signature \"%s\"", signature[0], signature)
+}
+
+// Returns the alignment of the values of a type
+func getDBusAlignment(t byte) int {
+	switch t {
+	case 'y', 'g', 'v':
+		return 1
+	case 'n', 'q':
+		return 2
+	case 'x', 't', 'd', '(', '{':
+		return 8
+	}
+	return 4
+}
+
+// Marshals values in little endian, the offsets being those of the message
+type DBusEncoder struct {
+	buffer []byte
+}
+
+func (e *DBusEncoder) align(n int) {
+	for len(e.buffer)%n != 0 {
+		e.buffer = append(e.buffer, 0)
+	}
+}
+
+func (e *DBusEncoder) encodeUint32(n uint32) {
+	e.align(4)
+	e.buffer = append(e.buffer, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(e.buffer[len(e.buffer)-4:], n)
+}
+
+// Marshals a value of a complete type, from its Go counterpart:
+// string for s, o and g, byte, bool, int32, uint32, int64, DBusVariant for v, []interface{} for a struct,
+// []string for as, map[string]DBusVariant for a{sv}, and []interface{} for the other arrays
+func (e *DBusEncoder) encode(t string, value interface{}) error {
+	invalid := fmt.Errorf("invalid value %v for the D-Bus type %s", value, t)
+	switch t[0] {
+	case 'y':
+		b, ok := value.(byte)
+		if !ok {
+			return invalid
+		}
+		e.buffer = append(e.buffer, b)
+	case 'b':
+		b, ok := value.(bool)
+		if !ok {
+			return invalid
+		}
+		n := uint32(0)
+		if b {
+			n = 1
+		}
+		e.encodeUint32(n)
+	case 'i':
+		n, ok := value.(int32)
+		if !ok {
+			return invalid
+		}
+		e.encodeUint32(uint32(n))
+	case 'u':
+		n, ok := value.(uint32)
+		if !ok {
+			return invalid
+		}
+		e.encodeUint32(n)
+	case 'x':
+		n, ok := value.(int64)
+		if !ok {
+			return invalid
+		}
+		e.align(8)
+		e.buffer = append(e.buffer, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint64(e.buffer[len(e.buffer)-8:], uint64(n))
+	case 's', 'o':
+		s, ok := value.(string)
+		if !ok {
+			return invalid
+		}
+		e.encodeUint32(uint32(len(s)))
+		e.buffer = append(append(e.buffer, s...), 0)
+	case 'g':
+		s, ok := value.(string)
+		if !ok || len(s) > 255 {
+			return invalid
+		}
+		e.buffer = append(append(append(e.buffer, byte(len(s))), s...), 0)
+	case 'v':
+		variant, ok := value.(DBusVariant)
+		if !ok {
+			return invalid
+		}
+		if err := e.encode("g", variant.signature); err != nil {
+			return err
+		}
+		return e.encode(variant.signature, variant.value)
+	case '(':
+		values, ok := value.([]interface{})
+		types, err := splitDBusSignature(t[1 : len(t)-1])
+		if !ok || err != nil || len(values) != len(types) {
+			return invalid
+		}
+		e.align(8)
+		for i, fieldType := range types {
+			if err = e.encode(fieldType, values[i]); err != nil {
+				return err
+			}
+		}
+	case 'a':
+		var elements []interface{}
+		switch typed := value.(type) {
+		case []interface{}:
+			elements = typed
+		case []string:
+			for _, s := range typed {
+				elements = append(elements, s)
+			}
+		case map[string]DBusVariant:
+			var keys []string
+			for key := range typed {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				elements = append(elements, []interface{}{key, typed[key]})
+			}
+		default:
+			return invalid
+		}
+		elementType := t[1:]
+		if elementType[0] == '{' {
+			// A dict entry is marshalled as a struct
+			elementType = "(" + elementType[1:len(elementType)-1] + ")"
+		}
+		e.encodeUint32(0)
+		lengthOffset := len(e.buffer) - 4
+		e.align(getDBusAlignment(elementType[0]))
+		start := len(e.buffer)
+		for _, element := range elements {
+			if err := e.encode(elementType, element); err != nil {
+				return err
+			}
+		}
+		binary.LittleEndian.PutUint32(e.buffer[lengthOffset:], uint32(len(e.buffer)-start))
+	default:
+		return fmt.Errorf("unsupported D-Bus type %s", t)
+	}
+	return nil
+}
+
+// Unmarshals values, the offsets being those of the message
+type DBusDecoder struct {
+	buffer []byte
+	order  binary.ByteOrder
+	offset int
+}
+
+func (d *DBusDecoder) align(n int) {
+	d.offset += (n - d.offset%n) % n
+}
+
+func (d *DBusDecoder) next(n int) ([]byte, error) {
+	if d.offset+n > len(d.buffer) {
+		return nil, errors.New("truncated D-Bus message")
+	}
+	bytes := d.buffer[d.offset : d.offset+n]
+	d.offset += n
+	return bytes, nil
+}
+
+// Unmarshals a value of a complete type, as the Go values of DBusEncoder.encode
+// (arrays and dict entries being []interface{})
+func (d *DBusDecoder) decode(t string) (interface{}, error) {
+	switch t[0] {
+	case 'y':
+		b, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0], nil
+	case 'n', 'q':
+		d.align(2)
+		b, err := d.next(2)
+		if err != nil {
+			return nil, err
+		}
+		return d.order.Uint16(b), nil
+	case 'b', 'i', 'u', 'h':
+		d.align(4)
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		n := d.order.Uint32(b)
+		switch t[0] {
+		case 'b':
+			return n != 0, nil
+		case 'i':
+			return int32(n), nil
+		}
+		return n, nil
+	case 'x', 't', 'd':
+		d.align(8)
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return int64(d.order.Uint64(b)), nil
+	case 's', 'o':
+		length, err := d.decode("u")
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(int(length.(uint32)) + 1)
+		if err != nil {
+			return nil, err
+		}
+		return string(b[:len(b)-1]), nil
+	case 'g':
+		length, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(int(length[0]) + 1)
+		if err != nil {
+			return nil, err
+		}
+		return string(b[:len(b)-1]), nil
+	case 'v':
+		signature, err := d.decode("g")
+		if err != nil {
+			return nil, err
+		}
+		if _, err = getDBusTypeLength(signature.(string)); err != nil {
+			return nil, err
+		}
+		value, err := d.decode(signature.(string))
+		return DBusVariant{signature.(string), value}, err
+	case '(', '{':
+		types, err := splitDBusSignature(t[1 : len(t)-1])
+		if err != nil {
+			return nil, err
+		}
+		d.align(8)
+		var values []interface{}
+		for _, fieldType := range types {
+			value, err := d.decode(fieldType)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case 'a':
+		length, err := d.decode("u")
+		if err != nil {
+			return nil, err
+		}
+		d.align(getDBusAlignment(t[1]))
+		end := d.offset + int(length.(uint32))
+		if end > len(d.buffer) {
+			return nil, errors.New("truncated D-Bus message")
+		}
+		elements := []interface{}{}
+		for d.offset < end {
+			element, err := d.decode(t[1:])
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+		}
+		return elements, nil
+	}
+	return nil, fmt.Errorf("unsupported D-Bus type %s", t)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const DBUS_TRACKER_NAME = "org.mate.Tracker"
+const DBUS_TRACKER_PATH = "/org/mate/Tracker"
+const DBUS_TRACKER_INTERFACE = "org.mate.Tracker"
+
+const DBUS_TRACKER_INTROSPECTION = `<!DOCTYPE node PUBLIC "-//freedesktop//DTD D-BUS Object Introspection 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/introspect.dtd">
+<node>
+  <interface name="org.mate.Tracker">
+    <method name="Start"><arg name="title" type="s" direction="in"/></method>
+    <method name="Stop"/>
+    <method name="Status">
+      <arg name="running" type="b" direction="out"/>
+      <arg name="title" type="s" direction="out"/>
+      <arg name="elapsed_seconds" type="x" direction="out"/>
+      <arg name="today_seconds" type="x" direction="out"/>
+    </method>
+    <property name="Running" type="b" access="read"/>
+    <property name="Title" type="s" access="read"/>
+    <property name="Since" type="s" access="read"/>
+    <property name="TodaySeconds" type="x" access="read"/>
+    <property name="TargetSeconds" type="x" access="read"/>
+  </interface>
+  <interface name="org.freedesktop.DBus.Properties">
+    <method name="Get">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="property" type="s" direction="in"/>
+      <arg name="value" type="v" direction="out"/>
+    </method>
+    <method name="GetAll">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="properties" type="a{sv}" direction="out"/>
+    </method>
+    <signal name="PropertiesChanged">
+      <arg name="interface" type="s"/>
+      <arg name="changed_properties" type="a{sv}"/>
+      <arg name="invalidated_properties" type="as"/>
+    </signal>
+  </interface>
+  <interface name="org.freedesktop.DBus.Introspectable">
+    <method name="Introspect"><arg name="xml" type="s" direction="out"/></method>
+  </interface>
+  <interface name="org.freedesktop.DBus.Peer">
+    <method name="Ping"/>
+  </interface>
+</node>
+`
+
+// The org.mate.Tracker service of the daemon, for desktop widgets to follow and control the running ticket:
+//
+//	Start(s title), Stop(), Status() -> (b running, s title, x elapsed_seconds, x today_seconds)
+//	properties Running, Title, Since (RFC 3339), TodaySeconds and TargetSeconds
+//
+// PropertiesChanged is emitted when a ticket starts or stops, Since being enough to show the elapsed time
+// The method calls are handled by the loop of the daemon, between its checks
+type TrackerService struct {
+	bus   *DBusConn
+	calls chan *DBusMessage
+	last  StatusResponse
+}
+
+// Connects to the session bus and takes the org.mate.Tracker name, when daemon.dbus is set
+func startTrackerService() (*TrackerService, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("D-Bus is only supported on Linux")
+	}
+	bus, err := connectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to the session bus: %w", err)
+	}
+	if err = bus.requestName(DBUS_TRACKER_NAME); err != nil {
+		bus.close()
+		return nil, err
+	}
+	service := &TrackerService{bus: bus, calls: make(chan *DBusMessage)}
+	if service.last, err = service.getStatus(); err != nil {
+		bus.close()
+		return nil, err
+	}
+
+	go func() {
+		defer close(service.calls)
+		for {
+			message, err := bus.read()
+			if err != nil {
+				debugf("D-Bus connection closed: %v", err)
+				return
+			}
+			if message.kind == DBUS_METHOD_CALL {
+				service.calls <- message
+			}
+		}
+	}()
+	return service, nil
+}
+
+func (s *TrackerService) getStatus() (StatusResponse, error) {
+	records, err := getRecentRecords(getNow().Truncate(time.Hour * 24))
+	if err != nil {
+		return StatusResponse{}, err
+	}
+	return getStatus(records), nil
+}
+
+func getTrackerProperties(status StatusResponse) map[string]DBusVariant {
+	return map[string]DBusVariant{
+		"Running":       {"b", status.Running},
+		"Title":         {"s", status.Title},
+		"Since":         {"s", status.Since},
+		"TodaySeconds":  {"x", status.TodaySeconds},
+		"TargetSeconds": {"x", status.TargetSeconds},
+	}
+}
+
+// Emits PropertiesChanged if a ticket was started or stopped since the last call
+func (s *TrackerService) emitChanges() error {
+	status, err := s.getStatus()
+	if err != nil {
+		return err
+	}
+	if status.Running == s.last.Running && status.Title == s.last.Title && status.Since == s.last.Since {
+		return nil
+	}
+	s.last = status
+	return s.bus.emit(DBUS_TRACKER_PATH, "org.freedesktop.DBus.Properties", "PropertiesChanged", "sa{sv}as",
+		DBUS_TRACKER_INTERFACE, getTrackerProperties(status), []string{})
+}
+
+// Answers a method call
+// A failure of the call is replied to the caller, only a failure to reply is returned
+func (s *TrackerService) handle(call *DBusMessage) error {
+	if call.path != DBUS_TRACKER_PATH {
+		return s.bus.replyError(call, "org.freedesktop.DBus.Error.UnknownObject", "No object at "+call.path)
+	}
+	iface := call.iface
+	if iface == "" {
+		iface = DBUS_TRACKER_INTERFACE
+	}
+	arguments := func(signature string) bool { return call.signature == signature }
+
+	switch method := iface + "." + call.member; {
+	case method == "org.freedesktop.DBus.Peer.Ping":
+		return s.bus.reply(call, "")
+	case method == "org.freedesktop.DBus.Introspectable.Introspect":
+		return s.bus.reply(call, "s", DBUS_TRACKER_INTROSPECTION)
+	case method == "org.freedesktop.DBus.Properties.Get" && arguments("ss"):
+		status, err := s.getStatus()
+		if err != nil {
+			return s.bus.replyError(call, "org.mate.Tracker.Error.Failed", err.Error())
+		}
+		property, found := getTrackerProperties(status)[call.body[1].(string)]
+		if call.body[0].(string) != DBUS_TRACKER_INTERFACE || !found {
+			return s.bus.replyError(call, "org.freedesktop.DBus.Error.UnknownProperty",
+				fmt.Sprintf("No property %s.%s", call.body[0], call.body[1]))
+		}
+		return s.bus.reply(call, "v", property)
+	case method == "org.freedesktop.DBus.Properties.GetAll" && arguments("s"):
+		status, err := s.getStatus()
+		if err != nil {
+			return s.bus.replyError(call, "org.mate.Tracker.Error.Failed", err.Error())
+		}
+		properties := map[string]DBusVariant{}
+		if call.body[0].(string) == DBUS_TRACKER_INTERFACE {
+			properties = getTrackerProperties(status)
+		}
+		return s.bus.reply(call, "a{sv}", properties)
+	case method == "org.mate.Tracker.Status" && arguments(""):
+		status, err := s.getStatus()
+		if err != nil {
+			return s.bus.replyError(call, "org.mate.Tracker.Error.Failed", err.Error())
+		}
+		return s.bus.reply(call, "bsxx", status.Running, status.Title, status.ElapsedSeconds, status.TodaySeconds)
+	case method == "org.mate.Tracker.Start" && arguments("s"):
+		title := strings.TrimSpace(call.body[0].(string))
+		if title == "" {
+			return s.bus.replyError(call, "org.freedesktop.DBus.Error.InvalidArgs", "Expected a title")
+		}
+		if err := s.start(title); err != nil {
+			return s.bus.replyError(call, "org.mate.Tracker.Error.Failed", err.Error())
+		}
+		if err := s.bus.reply(call, ""); err != nil {
+			return err
+		}
+		return s.emitChanges()
+	case method == "org.mate.Tracker.Stop" && arguments(""):
+		if err := s.stop(); err != nil {
+			return s.bus.replyError(call, "org.mate.Tracker.Error.Failed", err.Error())
+		}
+		if err := s.bus.reply(call, ""); err != nil {
+			return err
+		}
+		return s.emitChanges()
+	}
+	return s.bus.replyError(call, "org.freedesktop.DBus.Error.UnknownMethod",
+		fmt.Sprintf("No method %s.%s with the signature \"%s\"", iface, call.member, call.signature))
+}
+
+// Starts a ticket, as the start request of mate serve
+func (s *TrackerService) start(title string) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	return writeTicket(expandTitle(title, records))
+}
+
+// Stops the running ticket, as the stop request of mate serve
+func (s *TrackerService) stop() error {
+	records, err := getRecentRecords(getNow().Truncate(time.Hour * 24))
+	if err != nil {
+		return err
+	}
+	if !getStatus(records).Running {
+		return errNotWorking
+	}
+	return writeTicketAt(getRunningEnd(records[len(records)-1].timestamp), STOP_TOKEN)
+}
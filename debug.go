@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The token of a Telegram bot in the paths of the Bot API, /bot<id>:<secret>/<method>
+var BOT_TOKEN_PATTERN = regexp.MustCompile(`/bot[0-9]+:[^/]+`)
+
+// Set by --verbose
+var verboseOption = false
+
+// Tells if mate logs what it does on stderr, as set by --verbose or MATE_DEBUG=1
+func isDebugEnabled() bool {
+	return verboseOption || os.Getenv("MATE_DEBUG") == "1"
+}
+
+// Logs a message on stderr in verbose mode
+func debugf(format string, args ...interface{}) {
+	if isDebugEnabled() {
+		fmt.Fprintf(os.Stderr, "[mate %s] %s\n", time.Now().Format("15:04:05.000"), fmt.Sprintf(format, args...))
+	}
+}
+
+// Removes the parts of a URL that may hold credentials: the query, the user info and the token of a Telegram bot
+func redactURL(address string) string {
+	if parsed, err := url.Parse(address); err == nil {
+		parsed.RawQuery, parsed.User = "", nil
+		address = parsed.String()
+	}
+	return BOT_TOKEN_PATTERN.ReplaceAllString(address, "/bot<token>")
+}
+
+// Redacts the URL of the error of an HTTP call, which holds the whole URL of the request
+func redactURLError(err error) error {
+	var urlError *url.Error
+	if errors.As(err, &urlError) {
+		urlError.URL = redactURL(urlError.URL)
+	}
+	return err
+}
+
+// Replaces the credentials of the headers of a request (Authorization, and the *token* or *key* ones) in a text,
+// e.g. the body of an error response echoing the request
+func redactHeaders(text string, headers map[string]string) string {
+	for name, value := range headers {
+		lower := strings.ToLower(name)
+		if lower == "authorization" {
+			fields := strings.Fields(value)
+			if len(fields) == 0 {
+				continue
+			}
+			value = fields[len(fields)-1]
+		} else if !strings.Contains(lower, "token") && !strings.Contains(lower, "key") {
+			continue
+		}
+		if value != "" {
+			text = strings.ReplaceAll(text, value, "<"+name+">")
+		}
+	}
+	return text
+}
+
+// Logs an HTTP call to an integration, without the parts of its URL that may hold credentials
+func debugHTTPCall(method string, address string, status int, start time.Time, err error) {
+	if !isDebugEnabled() {
+		return
+	}
+	address = redactURL(address)
+	if err != nil {
+		var urlError *url.Error
+		if errors.As(err, &urlError) {
+			// Without the URL of the error, already logged
+			err = urlError.Err
+		}
+		debugf("%s %s: %v (%v)", method, address, err, time.Since(start))
+		return
+	}
+	debugf("%s %s: %d (%v)", method, address, status, time.Since(start))
+}
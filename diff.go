@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Largest move of an entry still shown as a change of its timestamp, rather than as an entry removed and another added
+const DIFF_MAX_SHIFT = 15 * time.Minute
+
+// A difference between two databases: an entry only in the first one, only in the second one, or changed
+type RecordChange struct {
+	from *Record
+	to   *Record
+}
+
+// Reads a database file, decrypted if needed
+func readDatabaseAt(path string) ([]Record, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read %s: %w", path, err)
+	}
+	records, err := parseDatabase(content)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// Compares two lists of entries: the identical entries are left out, the entries of the same timestamp
+// are changes of title, and the entries of the same title moved by up to DIFF_MAX_SHIFT are changes of timestamp
+func diffRecords(from []Record, to []Record) (changes []RecordChange) {
+	removed, added := subtractRecords(from, to), subtractRecords(to, from)
+	pairedRemoved, pairedAdded := make(map[int]bool), make(map[int]bool)
+	pair := func(matches func(r Record, a Record) bool) {
+		for i := range removed {
+			for j := range added {
+				if !pairedRemoved[i] && !pairedAdded[j] && matches(removed[i], added[j]) {
+					changes = append(changes, RecordChange{&removed[i], &added[j]})
+					pairedRemoved[i], pairedAdded[j] = true, true
+				}
+			}
+		}
+	}
+	pair(func(r Record, a Record) bool { return r.timestamp.Equal(a.timestamp) })
+	pair(func(r Record, a Record) bool {
+		shift := a.timestamp.Sub(r.timestamp)
+		return r.title == a.title && shift <= DIFF_MAX_SHIFT && -shift <= DIFF_MAX_SHIFT
+	})
+
+	for i := range removed {
+		if !pairedRemoved[i] {
+			changes = append(changes, RecordChange{&removed[i], nil})
+		}
+	}
+	for j := range added {
+		if !pairedAdded[j] {
+			changes = append(changes, RecordChange{nil, &added[j]})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].timestamp().Before(changes[j].timestamp()) })
+	return
+}
+
+func (c RecordChange) timestamp() time.Time {
+	if c.from != nil {
+		return c.from.timestamp
+	}
+	return c.to.timestamp
+}
+
+// Prints the differences between the database and another file (or between two files), to debug a sync or check a backup:
+// "-" for an entry only in the first one, "+" for an entry only in the second one, and "~" for a changed entry
+func showDatabaseDiff(paths []string) error {
+	var from, to []Record
+	var err error
+	names := []string{getDbPath(), paths[0]}
+	if len(paths) == 2 {
+		names = paths
+		if from, err = readDatabaseAt(paths[0]); err != nil {
+			return err
+		}
+	} else if from, err = getRecords(); err != nil {
+		return err
+	}
+	if to, err = readDatabaseAt(names[1]); err != nil {
+		return err
+	}
+
+	changes := diffRecords(from, to)
+	if len(changes) == 0 {
+		fmt.Printf("%s and %s hold the same %d entries\n", names[0], names[1], len(from))
+		return nil
+	}
+	fmt.Printf("--- %s\n+++ %s\n", names[0], names[1])
+	only := [2]int{}
+	for _, c := range changes {
+		switch {
+		case c.to == nil:
+			only[0]++
+			fmt.Printf("- %s %s\n", c.from.timestamp.Format(TIME_FORMAT), getEntryName(*c.from))
+		case c.from == nil:
+			only[1]++
+			fmt.Printf("+ %s %s\n", c.to.timestamp.Format(TIME_FORMAT), getEntryName(*c.to))
+		case c.from.timestamp.Equal(c.to.timestamp):
+			fmt.Printf("~ %s %s -> %s\n", c.from.timestamp.Format(TIME_FORMAT), getEntryName(*c.from), getEntryName(*c.to))
+		default:
+			fmt.Printf("~ %s %s -> %s\n", c.from.timestamp.Format(TIME_FORMAT), getEntryName(*c.from), c.to.timestamp.Format(TIME_FORMAT))
+		}
+	}
+	fmt.Printf("%d entries only in %s, %d only in %s, %d changed\n",
+		only[0], names[0], only[1], names[1], len(changes)-only[0]-only[1])
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+const DISCORD_API_URL = "https://discord.com/api/v10"
+
+// How often the channels are polled for messages
+const DISCORD_POLL_INTERVAL = time.Second * 5
+
+// The longest message Discord accepts
+const DISCORD_MAX_MESSAGE = 2000
+
+// A message of a channel
+type DiscordMessage struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	Author  struct {
+		Bot bool `json:"bot"`
+	} `json:"author"`
+}
+
+// The Discord bot of discord.token, answering the channels listed in discord.channels
+// It polls the channels, so that no gateway connection is needed, and needs the Message Content intent
+type DiscordBot struct {
+	// The last message read in each channel
+	last map[string]string
+}
+
+// Calls the REST API of Discord as the bot
+func callDiscord(method string, path string, body interface{}, out interface{}) error {
+	headers := map[string]string{
+		"Authorization": "Bot " + getConfig("discord.token", ""),
+		"User-Agent":    "DiscordBot (https://github.com/eguerlain/mate, 1)",
+	}
+	return callJSONAPI(method, DISCORD_API_URL+path, headers, body, out)
+}
+
+// Orders the snowflake IDs, decimal numbers sent as strings
+func isDiscordIDBefore(a string, b string) bool {
+	return len(a) < len(b) || len(a) == len(b) && a < b
+}
+
+func connectDiscordBot() (*DiscordBot, error) {
+	if _, err := getRequiredConfig("discord.token", "the token of the bot, from the Discord developer portal"); err != nil {
+		return nil, err
+	}
+	channels, err := getRequiredConfig("discord.channels", "the IDs of the channels allowed to control mate, comma separated")
+	if err != nil {
+		return nil, err
+	}
+
+	var me struct {
+		Username string `json:"username"`
+	}
+	if err = callDiscord("GET", "/users/@me", nil, &me); err != nil {
+		return nil, fmt.Errorf("Cannot reach the Discord bot: %w", err)
+	}
+	// The messages sent before the bot started are skipped
+	bot := &DiscordBot{last: make(map[string]string)}
+	for _, channel := range splitConfigList(channels) {
+		var messages []DiscordMessage
+		if err = callDiscord("GET", "/channels/"+url.PathEscape(channel)+"/messages?limit=1", nil, &messages); err != nil {
+			return nil, fmt.Errorf("Cannot read the channel %s: %w", channel, err)
+		}
+		bot.last[channel] = "0"
+		if len(messages) != 0 {
+			bot.last[channel] = messages[0].ID
+		}
+	}
+	fmt.Printf("Discord bot %s running, press Ctrl+C to stop\n", me.Username)
+	return bot, nil
+}
+
+func (bot *DiscordBot) poll() (messages []BotMessage, err error) {
+	time.Sleep(DISCORD_POLL_INTERVAL)
+	for _, channel := range bot.chats() {
+		var channelMessages []DiscordMessage
+		query := url.Values{"after": {bot.last[channel]}, "limit": {"50"}}
+		if err = callDiscord("GET", "/channels/"+url.PathEscape(channel)+"/messages?"+query.Encode(), nil, &channelMessages); err != nil {
+			return nil, err
+		}
+		sort.Slice(channelMessages, func(i, j int) bool { return isDiscordIDBefore(channelMessages[i].ID, channelMessages[j].ID) })
+		for _, message := range channelMessages {
+			bot.last[channel] = message.ID
+			if !message.Author.Bot && isBotCommand(message.Content) {
+				messages = append(messages, BotMessage{channel, message.Content})
+			}
+		}
+	}
+	return
+}
+
+func (bot *DiscordBot) send(channel string, text string) error {
+	if runes := []rune(text); len(runes) > DISCORD_MAX_MESSAGE {
+		text = string(runes[:DISCORD_MAX_MESSAGE-1]) + "…"
+	}
+	return callDiscord("POST", "/channels/"+url.PathEscape(channel)+"/messages", map[string]string{"content": text}, nil)
+}
+
+func (bot *DiscordBot) chats() []string {
+	return splitConfigList(getConfig("discord.channels", ""))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const QUARANTINE_NAME = ".mate.quarantine.csv"
+
+// A line of the database that is not a valid entry, e.g. after a typo in a hand edit
+type MalformedLine struct {
+	number int
+	line   string
+	reason string
+}
+
+// Malformed lines of the database read last, reported once the command is done
+var malformedLines []MalformedLine
+
+func getQuarantinePath() string {
+	return getHomeFilePath(QUARANTINE_NAME)
+}
+
+// Parses the lines of a database (header included), leaving out the malformed ones instead of failing
+func parseRecordsLeniently(content []byte) (records []Record, malformed []MalformedLine) {
+	for index, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if index == 0 || strings.TrimSpace(line) == "" {
+			continue
+		}
+		record, err := parseRecordLine(line)
+		if err != nil {
+			malformed = append(malformed, MalformedLine{index + 1, line, err.Error()})
+			continue
+		}
+		records = append(records, record)
+	}
+	return
+}
+
+// Parses a line of the database, "timestamp,title"
+func parseRecordLine(line string) (Record, error) {
+	fields, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return Record{}, err
+	}
+	if len(fields) != 2 {
+		return Record{}, fmt.Errorf("expected 2 fields, found %d", len(fields))
+	}
+	timestamp, err := time.Parse(TIME_FORMAT, fields[0])
+	if err != nil {
+		return Record{}, fmt.Errorf("invalid timestamp \"%s\" (expected YYYY/MM/DD HH:MM:SS)", fields[0])
+	}
+	return Record{timestamp, fields[1]}, nil
+}
+
+// Prints the malformed lines of the database on stderr, if any
+func reportMalformedLines() {
+	if len(malformedLines) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%d malformed lines skipped in %s:\n", len(malformedLines), getDbPath())
+	for _, m := range malformedLines {
+		fmt.Fprintf(os.Stderr, "  line %d: %s (%s)\n", m.number, m.line, m.reason)
+	}
+	fmt.Fprintln(os.Stderr, "Fix them by hand, or move them out of the database with:\n$ mate doctor --fix-lines")
+}
+
+// Moves the malformed lines to the quarantine file, encrypted like the database
+// This is done by writeRecords, so that rewriting the database does not lose them
+func quarantineLines(lines []MalformedLine) error {
+	content, err := files.ReadFile(getQuarantinePath())
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Cannot read the quarantine: %w", err)
+	}
+	if isEncryptedDatabase(content) {
+		if content, err = decryptDatabase(content); err != nil {
+			return err
+		}
+	}
+	var quarantined bytes.Buffer
+	quarantined.Write(content)
+	for _, m := range lines {
+		fmt.Fprintf(&quarantined, "%s\n", m.line)
+	}
+
+	data := quarantined.Bytes()
+	if isEncryptionEnabled() {
+		if data, err = encryptDatabase(data); err != nil {
+			return err
+		}
+	}
+	if err = files.WriteFile(getQuarantinePath(), data, 0644); err != nil {
+		return fmt.Errorf("Cannot write the quarantine: %w", err)
+	}
+	return nil
+}
+
+// Checks the lines of the database, and with fixLines moves the malformed ones to the quarantine file
+func runDoctor(fixLines bool) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	malformed := malformedLines
+	if len(malformed) == 0 {
+		fmt.Printf("%d entries, no malformed line\n", len(records))
+		return nil
+	}
+	// Reported here rather than once the command is done
+	malformedLines = nil
+	for _, m := range malformed {
+		fmt.Printf("line %d: %s (%s)\n", m.number, m.line, m.reason)
+	}
+	if !fixLines {
+		fmt.Println("Run mate doctor --fix-lines to move them out of the database")
+		return nil
+	}
+
+	// Written back without the malformed lines, which are quarantined
+	return writeRecords(records)
+}
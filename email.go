@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// The port of SMTP over TLS, the other ports upgrading the connection with STARTTLS when the server offers it
+const SMTPS_PORT = "465"
+
+// Builds a message holding a plain text and an HTML alternative
+func buildEmail(from string, to []string, subject string, text string, htmlBody string) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, alternative := range []struct{ contentType, content string }{
+		// The last alternative is the preferred one
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", htmlBody},
+	} {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {alternative.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(part)
+		if _, err = encoder.Write([]byte(alternative.content)); err != nil {
+			return nil, err
+		}
+		if err = encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := "mate"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+	var message bytes.Buffer
+	headers := [][2]string{
+		{"From", from},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", hex.EncodeToString(id), domain)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&message, "%s: %s\r\n", header[0], header[1])
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+// Sends an email through the SMTP server of email.smtp (host:port), authenticated by email.username and
+// email.password if set, from email.from
+func sendEmail(to []string, subject string, text string, htmlBody string) error {
+	server, err := getRequiredConfig("email.smtp", "the SMTP server, e.g. smtp.example.com:587")
+	if err != nil {
+		return err
+	}
+	from, err := getRequiredConfig("email.from", "the sender of the emails")
+	if err != nil {
+		return err
+	}
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		return newConfigError(fmt.Sprintf("email.smtp: invalid server \"%s\" (expected host:port)", server))
+	}
+	message, err := buildEmail(from, to, subject, text, htmlBody)
+	if err != nil {
+		return err
+	}
+	// The envelope takes the bare addresses, the headers keeping the names
+	if address, err := mail.ParseAddress(from); err == nil {
+		from = address.Address
+	}
+	to = append([]string{}, to...)
+	for i, recipient := range to {
+		if address, err := mail.ParseAddress(recipient); err == nil {
+			to[i] = address.Address
+		}
+	}
+	var auth smtp.Auth
+	if username := getConfig("email.username", ""); username != "" {
+		auth = smtp.PlainAuth("", username, getConfig("email.password", ""), host)
+	}
+	debugf("SMTP %s: sending \"%s\" to %d recipients", server, subject, len(to))
+	if port != SMTPS_PORT {
+		return smtp.SendMail(server, auth, from, to, message)
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: API_TIMEOUT}, "tcp", server, &tls.Config{ServerName: host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if auth != nil {
+		if err = client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err = client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(message); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
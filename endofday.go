@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"time"
+)
+
+const DEFAULT_END_OF_DAY = "18:00"
+
+// Returns the end of the working day of the given timestamp's day, as set by auto_stop or end_of_day
+// If the timestamp is already past that time, the end of the calendar day is used instead
+func getEndOfDay(timestamp time.Time) time.Time {
+	midnight := timestamp.Truncate(time.Hour * 24)
+	endOfDay := midnight.Add(getConfigClock("auto_stop", getConfig("end_of_day", DEFAULT_END_OF_DAY)))
+	if !endOfDay.After(timestamp) {
+		endOfDay = midnight.AddDate(0, 0, 1)
+	}
+	return endOfDay
+}
+
+// Detects a ticket left running since a previous day and offers to stop it at the end of that day
+func checkOvernightTicket() error {
+	records, err := getRecentRecords(getNow())
+	if err != nil || len(records) == 0 {
+		return err
+	}
+
+	last := records[len(records)-1]
+	today := getNow().Truncate(time.Hour * 24)
+	if last.title == STOP_TOKEN || !last.timestamp.Before(today) {
+		return nil
+	}
+
+	endOfDay := getEndOfDay(last.timestamp)
+	fmt.Printf("%s has been running since %s (%v)\n", last.title, last.timestamp.Format(TIME_FORMAT), getNow().Sub(last.timestamp))
+	answer, ok := askUser(bufio.NewReader(os.Stdin), fmt.Sprintf("Stop it at %s? [Y/n]: ", endOfDay.Format(TIME_FORMAT)))
+	if !ok || !contains([]string{"", "y", "Y"}, answer) {
+		return nil
+	}
+
+	if err = writeTicketAt(endOfDay, STOP_TOKEN); err != nil {
+		return err
+	}
+	fmt.Printf("STOPPED %s at %s\n", last.title, endOfDay.Format(TIME_FORMAT))
+	return nil
+}
+
+// Returns when a ticket started at the given time and still running is considered stopped:
+// now, or the auto_stop time of its day if it is set and already past
+func getRunningEnd(start time.Time) time.Time {
+	now := getNow()
+	if getConfig("auto_stop", "") == "" {
+		return now
+	}
+	if endOfDay := getEndOfDay(start); endOfDay.Before(now) {
+		return endOfDay
+	}
+	return now
+}
+
+// Tells if the last ticket is still running in the database but past auto_stop
+func isAutoStopped(records []Record) bool {
+	if len(records) == 0 {
+		return false
+	}
+	last := records[len(records)-1]
+	return last.title != STOP_TOKEN && getRunningEnd(last.timestamp).Before(getNow())
+}
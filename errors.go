@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Exit codes of mate, for scripts to branch on (see "mate help")
+const (
+	EXIT_OK              = 0
+	EXIT_USAGE           = 1 // Wrong command, option or argument, or a failure of no other kind
+	EXIT_NO_DATA         = 2 // Nothing to act on, e.g. no ticket running to stop
+	EXIT_DATABASE        = 3 // The database cannot be read or written
+	EXIT_ALREADY_RUNNING = 4 // A ticket is running, e.g. when restarting the last one
+	EXIT_CONFIG          = 5 // The config is invalid, or misses a required value
+	EXIT_LOCKED          = 6 // The change touches entries locked by mate lock
+)
+
+// Descriptions of the exit codes, in the help
+var EXIT_CODES = []struct {
+	code        int
+	description string
+}{
+	{EXIT_OK, "success"},
+	{EXIT_USAGE, "wrong usage, or any other error"},
+	{EXIT_NO_DATA, "nothing to act on (e.g. no ticket running to stop)"},
+	{EXIT_DATABASE, "the database cannot be read or written"},
+	{EXIT_ALREADY_RUNNING, "a ticket is already running"},
+	{EXIT_CONFIG, "invalid or incomplete config"},
+	{EXIT_LOCKED, "the entries are locked (see mate lock)"},
+}
+
+// A wrong usage of a command, reported with a pointer to its help
+type UsageError struct {
+	command string
+	message string
+}
+
+func (e *UsageError) Error() string {
+	return e.message
+}
+
+// Returns an error for a wrong usage of a command
+func newUsageError(command string, message string) error {
+	return &UsageError{command, message}
+}
+
+// An error mate exits with a specific code for
+type ExitError struct {
+	code int
+	err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.err
+}
+
+// Tags an error with the code mate exits with when a command fails with it
+func withExitCode(code int, err error) error {
+	return &ExitError{code, err}
+}
+
+// Returns the code mate exits with for an error, EXIT_USAGE if it has none
+func getExitCode(err error) int {
+	var exitError *ExitError
+	if errors.As(err, &exitError) {
+		return exitError.code
+	}
+	return EXIT_USAGE
+}
+
+// Reports the error a command failed with, and exits with its code
+// This is the only place mate exits on errors, the rest of the code returning them
+func exitWithError(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	var usageError *UsageError
+	if errors.As(err, &usageError) {
+		fmt.Fprintf(os.Stderr, "See mate %s --help\n", usageError.command)
+	}
+	os.Exit(getExitCode(err))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// A change of the running ticket, as written to the database
+type TicketEvent struct {
+	name     string // start, stop or switch
+	title    string // Started ticket, or stopped ticket for stop
+	start    time.Time
+	end      time.Time // End of the stopped ticket, zero for start
+	previous Record    // Ticket running before a switch
+}
+
+// Returns the event of writing an entry after the given records, if any
+// Writing a STOP when no ticket is running is not an event
+func getTicketEvent(records []Record, timestamp time.Time, title string) (TicketEvent, bool) {
+	running := len(records) != 0 && records[len(records)-1].title != STOP_TOKEN && !isAutoStopped(records)
+	var last Record
+	if running {
+		last = records[len(records)-1]
+	}
+
+	switch {
+	case title == STOP_TOKEN && running:
+		return TicketEvent{name: "stop", title: last.title, start: last.timestamp, end: timestamp}, true
+	case title == STOP_TOKEN:
+		return TicketEvent{}, false
+	case running:
+		return TicketEvent{name: "switch", title: title, start: timestamp, previous: last}, true
+	}
+	return TicketEvent{name: "start", title: title, start: timestamp}, true
+}
+
+// Returns the environment given to the hooks
+func getHookEnv(event TicketEvent) []string {
+	env := append(os.Environ(),
+		"MATE_EVENT="+event.name,
+		"MATE_TITLE="+event.title,
+		"MATE_START="+fromDbTime(event.start).Format(time.RFC3339),
+	)
+	if !event.end.IsZero() {
+		env = append(env,
+			"MATE_END="+fromDbTime(event.end).Format(time.RFC3339),
+			fmt.Sprintf("MATE_DURATION=%d", int64(event.end.Sub(event.start).Seconds())),
+		)
+	}
+	if event.previous.title != "" {
+		env = append(env,
+			"MATE_PREVIOUS_TITLE="+event.previous.title,
+			"MATE_PREVIOUS_START="+fromDbTime(event.previous.timestamp).Format(time.RFC3339),
+		)
+	}
+	return env
+}
+
+// Returns a command run through the shell
+func getShellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// Runs the command of hooks.on_<event> (through the shell), with the details of the event in MATE_* variables
+// A failing hook is reported, but does not fail the command
+func runHook(event TicketEvent) {
+	command := getConfig("hooks.on_"+event.name, "")
+	if command == "" {
+		return
+	}
+
+	cmd := getShellCommand(command)
+	cmd.Env = getHookEnv(event)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Printf("The on_%s hook failed: %v\n", event.name, err)
+	}
+}
+
+// Reacts to a change of the running ticket
+// The hooks, webhooks and Slack status are the ones of the owner: the tickets of the users of mate serve are kept out of them
+func fireTicketEvent(event TicketEvent) {
+	if serverUser != "" {
+		debugf("The %s of %s is not sent to the hooks of the owner", event.name, serverUser)
+		return
+	}
+	runHook(event)
+	postWebhooks(getTicketEventPayload(event))
+	updateSlackStatus(event)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// Serves a webhook counting the payloads posted to it
+func useWebhookServer(t *testing.T) *int32 {
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&posts, 1)
+	}))
+	t.Cleanup(server.Close)
+	config["webhooks.urls"] = server.URL
+	return &posts
+}
+
+func TestFireTicketEventOnlyForTheOwner(t *testing.T) {
+	tests := []struct {
+		name      string
+		user      string
+		wantFired bool
+	}{
+		{"owner", "", true},
+		{"user of mate serve", "alice", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, "")
+			posts := useWebhookServer(t)
+			marker := filepath.Join(t.TempDir(), "hook")
+			config["hooks.on_start"] = "echo \"$MATE_TITLE\" > " + marker
+			config["users.alice"] = "alice-token"
+
+			if err := runAsUser(test.user, func() error { return writeTicketAt(testNow, "A") }); err != nil {
+				t.Fatal(err)
+			}
+			_, err := os.Stat(marker)
+			if hooked := err == nil; hooked != test.wantFired {
+				t.Errorf("got the hook run %v, want %v", hooked, test.wantFired)
+			}
+			if posted := atomic.LoadInt32(posts) != 0; posted != test.wantFired {
+				t.Errorf("got the webhook posted %v, want %v", posted, test.wantFired)
+			}
+		})
+	}
+}
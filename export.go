@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var EXPORT_FORMATS = []string{"csv", "tsv", "xlsx", "json", "ics", "timeclock", "obsidian"}
+
+// Hashtags of a title, such as #review in "PROJ-12 #review"
+var TAG_PATTERN = regexp.MustCompile(`(^|\s)#([A-Za-z][\w-]*)`)
+
+// Returns the tags of a ticket, from the hashtags of its title
+func getTags(title string) (tags []string) {
+	for _, match := range TAG_PATTERN.FindAllStringSubmatch(title, -1) {
+		tags = append(tags, match[2])
+	}
+	return
+}
+
+// Returns the tags of a ticket for the reports: its hashtags, and those given by the rules to the entries saved before them
+func getTicketTags(title string) []string {
+	tags := getTags(title)
+	for _, tag := range matchRules(title).tags {
+		if !contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Parses the --since and --until dates into [start, end[, both dates being included
+// Empty dates mean no bound, i.e. a zero time
+func parseDateRange(since string, until string) (start time.Time, end time.Time, err error) {
+	if since != "" {
+		if start, err = parseDate(since); err != nil {
+			return
+		}
+	}
+	if until != "" {
+		var day time.Time
+		if day, err = parseDate(until); err != nil {
+			return
+		}
+		end = day.AddDate(0, 0, 1)
+	}
+	return
+}
+
+// Returns the intervals within [start, end[, zero times meaning no bound
+func getIntervalsBetween(records []Record, start time.Time, end time.Time) []Interval {
+	intervals := computeIntervals(records)
+
+	var outIntervals []Interval
+	for _, in := range intervals {
+		if !start.IsZero() && !in.end.After(start) || !end.IsZero() && !in.start.Before(end) {
+			continue
+		}
+		if !start.IsZero() && in.start.Before(start) {
+			in.start = start
+		}
+		if !end.IsZero() && in.end.After(end) {
+			in.end = end
+		}
+		outIntervals = append(outIntervals, in)
+	}
+	return outIntervals
+}
+
+// Builds the rows of the intervals export, header included
+// The notes of an interval are those taken during it, joined by " | "
+func buildIntervalRows(intervals []Interval, ticketClients map[string]string, notes []Note) (rows [][]string) {
+	rows = append(rows, []string{"start", "end", "duration", "title", "project", "client", "tags", "notes"})
+	for _, in := range intervals {
+		var texts []string
+		for _, n := range getNotesBetween(notes, in.start, in.end) {
+			texts = append(texts, n.text)
+		}
+		rows = append(rows, []string{
+			in.start.Format(TIME_FORMAT),
+			in.end.Format(TIME_FORMAT),
+			fmt.Sprintf("%.2f", in.end.Sub(in.start).Hours()),
+			in.title,
+			getTicketProject(in.title),
+			getClient(in.title, ticketClients),
+			strings.Join(getTicketTags(in.title), " "),
+			strings.Join(texts, " | "),
+		})
+	}
+	return
+}
+
+// Exports the intervals between the given dates to stdout
+// The obsidian format writes to the daily notes of dir instead, the ones of today by default
+func exportEntries(format string, since string, until string, dir string) error {
+	if !contains(EXPORT_FORMATS, format) {
+		return fmt.Errorf("Invalid format \"%s\" (expected %s)", format, strings.Join(EXPORT_FORMATS, ", "))
+	}
+	if format == "obsidian" && since == "" && until == "" {
+		since = "today"
+	}
+	start, end, err := parseDateRange(since, until)
+	if err != nil {
+		return err
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	ticketClients, err := readTable(getClientsPath())
+	if err != nil {
+		return err
+	}
+	notes, err := readNotes()
+	if err != nil {
+		return err
+	}
+	intervals := getIntervalsBetween(records, start, end)
+
+	switch format {
+	case "csv", "tsv":
+		w := csv.NewWriter(os.Stdout)
+		if format == "tsv" {
+			w.Comma = '\t'
+		}
+		err = w.WriteAll(buildIntervalRows(intervals, ticketClients, notes))
+	case "xlsx":
+		if isTerminal(os.Stdout) {
+			return errors.New("Redirect the output to a file. Run:\n$ mate export --format xlsx > timesheet.xlsx")
+		}
+		sheets := []XLSXSheet{buildEntriesSheet(intervals, ticketClients, notes), buildSummarySheet(intervals)}
+		err = writeXLSX(os.Stdout, sheets)
+	case "json":
+		err = exportJSON(os.Stdout, records, start, end)
+	case "ics":
+		err = writeICS(os.Stdout, intervals)
+	case "timeclock":
+		err = writeTimeclock(os.Stdout, intervals, ticketClients, notes)
+	case "obsidian":
+		return writeDailyNotes(dir, intervals)
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot write the export: %w", err)
+	}
+	return nil
+}
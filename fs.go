@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// The file access of the database store, so that it can run on something else than the disk
+type FileSystem interface {
+	ReadFile(path string) ([]byte, error)
+	// Opens the file to read it as a stream
+	Open(path string) (io.ReadCloser, error)
+	// Reads length bytes from offset, fewer at the end of the file
+	ReadAt(path string, offset int64, length int64) ([]byte, error)
+	Size(path string) (int64, error)
+	WriteFile(path string, data []byte, perm os.FileMode) error
+	// Creates the file if it does not exist
+	AppendFile(path string, data []byte, perm os.FileMode) error
+	Rename(from string, to string) error
+	Remove(path string) error
+}
+
+// The files of the disk
+type OSFileSystem struct{}
+
+func (OSFileSystem) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (OSFileSystem) Open(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+func (OSFileSystem) ReadAt(path string, offset int64, length int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data := make([]byte, length)
+	n, err := f.ReadAt(data, offset)
+	if err == io.EOF {
+		err = nil
+	}
+	return data[:n], err
+}
+
+func (OSFileSystem) Size(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (OSFileSystem) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(path, data, perm)
+}
+
+func (OSFileSystem) AppendFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (OSFileSystem) Rename(from string, to string) error {
+	return os.Rename(from, to)
+}
+
+func (OSFileSystem) Remove(path string) error {
+	return os.Remove(path)
+}
+
+// Files kept in memory, e.g. to exercise the store without touching the disk
+// Missing files are reported like on the disk, so that os.IsNotExist applies
+type MemoryFileSystem struct {
+	lock  sync.Mutex
+	files map[string][]byte
+}
+
+func newMemoryFileSystem() *MemoryFileSystem {
+	return &MemoryFileSystem{files: make(map[string][]byte)}
+}
+
+func (m *MemoryFileSystem) ReadFile(path string) ([]byte, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	data, found := m.files[path]
+	if !found {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return append([]byte{}, data...), nil
+}
+
+func (m *MemoryFileSystem) Open(path string) (io.ReadCloser, error) {
+	data, err := m.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *MemoryFileSystem) ReadAt(path string, offset int64, length int64) ([]byte, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	data, found := m.files[path]
+	if !found {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	if offset >= int64(len(data)) {
+		return nil, nil
+	}
+	end := offset + length
+	if end > int64(len(data)) {
+		end = int64(len(data))
+	}
+	return append([]byte{}, data[offset:end]...), nil
+}
+
+func (m *MemoryFileSystem) Size(path string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	data, found := m.files[path]
+	if !found {
+		return 0, &os.PathError{Op: "stat", Path: path, Err: os.ErrNotExist}
+	}
+	return int64(len(data)), nil
+}
+
+func (m *MemoryFileSystem) WriteFile(path string, data []byte, perm os.FileMode) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.files[path] = append([]byte{}, data...)
+	return nil
+}
+
+func (m *MemoryFileSystem) AppendFile(path string, data []byte, perm os.FileMode) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.files[path] = append(m.files[path], data...)
+	return nil
+}
+
+func (m *MemoryFileSystem) Rename(from string, to string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	data, found := m.files[from]
+	if !found {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: os.ErrNotExist}
+	}
+	m.files[to] = data
+	delete(m.files, from)
+	return nil
+}
+
+func (m *MemoryFileSystem) Remove(path string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, found := m.files[path]; !found {
+		return &os.PathError{Op: "remove", Path: path, Err: os.ErrNotExist}
+	}
+	delete(m.files, path)
+	return nil
+}
+
+// The file system the database and its tables are stored on
+var files FileSystem = OSFileSystem{}
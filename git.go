@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// By default, the Jira key of the branch is the ticket (e.g. PROJ-123 for feature/PROJ-123-login)
+const DEFAULT_BRANCH_PATTERN = `([A-Z][A-Z0-9_]+-[0-9]+)`
+const DEFAULT_BRANCH_TITLE = "$1"
+
+const HOOK_MARKER = "# Installed by mate: switches the running ticket with the branch"
+
+// The post-checkout hook; its third argument is 1 for a branch checkout, 0 for a file checkout
+const POST_CHECKOUT_HOOK = `#!/bin/sh
+` + HOOK_MARKER + `
+[ "$3" = 1 ] || exit 0
+command -v mate >/dev/null 2>&1 || exit 0
+mate switch --git || true
+`
+
+// Runs a git command in the current directory and returns its trimmed output
+func runGit(args ...string) (string, error) {
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", errors.New("git " + strings.Join(args, " ") + ": " + err.Error())
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Returns the ticket of a branch: git.title (default "$1") expanded with the submatches of git.branch_pattern,
+// or the branch name if it does not match
+func getBranchTicket(branch string) (string, error) {
+	pattern, err := regexp.Compile(getConfig("git.branch_pattern", DEFAULT_BRANCH_PATTERN))
+	if err != nil {
+		return "", errors.New("git.branch_pattern: " + err.Error())
+	}
+	match := pattern.FindStringSubmatchIndex(branch)
+	if match == nil {
+		return branch, nil
+	}
+	title := pattern.ExpandString(nil, getConfig("git.title", DEFAULT_BRANCH_TITLE), branch, match)
+	return strings.TrimSpace(string(title)), nil
+}
+
+// Returns the ticket of the current branch of the repository of the current directory
+func getCurrentBranchTicket() (string, error) {
+	branch, err := runGit("symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return getBranchTicket(branch)
+}
+
+// Starts the given ticket if another one is running
+// Nothing is done when no ticket is running, or when the ticket is already running
+func switchTicket(ticket string) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 || records[len(records)-1].title == STOP_TOKEN || isAutoStopped(records) {
+		fmt.Printf("No ticket running, not switching to %s\n", ticket)
+		return nil
+	}
+	running := records[len(records)-1].title
+	if running == ticket || strings.HasPrefix(running, ticket+" ") {
+		fmt.Printf("Already working on %s\n", running)
+		return nil
+	}
+	return startTicket(expandTitle(ticket, records))
+}
+
+// Installs the post-checkout hook in the repository of the current directory
+// An existing hook is left untouched, the snippet of "mate hook print" can be added to it instead
+func installHook() error {
+	hooksPath, err := runGit("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return fmt.Errorf("Not in a git repository: %w", err)
+	}
+	path := filepath.Join(hooksPath, "post-checkout")
+
+	if content, err := os.ReadFile(path); err == nil {
+		if strings.Contains(string(content), HOOK_MARKER) {
+			fmt.Printf("The hook is already installed in %s\n", path)
+			return nil
+		}
+		return fmt.Errorf("%s already exists. Add the following lines to it:\n\n%s", path, POST_CHECKOUT_HOOK)
+	}
+
+	if err = os.MkdirAll(hooksPath, 0755); err != nil {
+		return fmt.Errorf("Cannot install the hook: %w", err)
+	}
+	if err = os.WriteFile(path, []byte(POST_CHECKOUT_HOOK), 0755); err != nil {
+		return fmt.Errorf("Cannot install the hook: %w", err)
+	}
+	fmt.Printf("Hook installed in %s\n", path)
+	return nil
+}
+
+func printHook() {
+	fmt.Print(POST_CHECKOUT_HOOK)
+}
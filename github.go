@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const DEFAULT_GITHUB_API_URL = "https://api.github.com"
+
+// GitHub references given to start, such as gh:owner/repo#123
+var GITHUB_START_PATTERN = regexp.MustCompile(`^gh:([\w.-]+/[\w.-]+)#([0-9]+)$`)
+
+// GitHub references at the beginning of a title, such as owner/repo#123 in "owner/repo#123 Fix login"
+var GITHUB_REF_PATTERN = regexp.MustCompile(`^([\w.-]+/[\w.-]+)#([0-9]+)\b`)
+
+// Remote URLs of GitHub repositories (https or ssh)
+var GITHUB_REMOTE_PATTERN = regexp.MustCompile(`github\.com[:/]([\w.-]+/[\w.-]+?)(\.git)?/?$`)
+
+// An issue or pull request of the GitHub API
+type GitHubIssue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+}
+
+func getGitHubURL() string {
+	return strings.TrimRight(getConfig("github.api_url", DEFAULT_GITHUB_API_URL), "/")
+}
+
+// Returns the headers of the GitHub API, authenticated by github.token or $GITHUB_TOKEN if set
+func getGitHubHeaders() map[string]string {
+	headers := map[string]string{"Accept": "application/vnd.github+json"}
+	token := getConfig("github.token", os.Getenv("GITHUB_TOKEN"))
+	if token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	return headers
+}
+
+// Returns the "owner/repo#123" reference of a title, if any
+func getGitHubReference(title string) (string, bool) {
+	match := GITHUB_REF_PATTERN.FindStringSubmatch(title)
+	if match == nil {
+		return "", false
+	}
+	return match[1] + "#" + match[2], true
+}
+
+// Completes a gh:owner/repo#123 title with the title of the issue or pull request,
+// e.g. "owner/repo#123 Fix login timeout"
+func expandGitHubTitle(title string) string {
+	match := GITHUB_START_PATTERN.FindStringSubmatch(title)
+	if match == nil {
+		return title
+	}
+	reference := match[1] + "#" + match[2]
+
+	// Pull requests are issues too for the GitHub API
+	var issue GitHubIssue
+	err := callJSONAPI("GET", getGitHubURL()+"/repos/"+match[1]+"/issues/"+match[2], getGitHubHeaders(), nil, &issue)
+	if err != nil {
+		fmt.Printf("Cannot fetch the title of %s: %v\n", reference, err)
+		return reference
+	}
+	return reference + " " + strings.TrimSpace(issue.Title)
+}
+
+// Returns the title of the open pull request of the current branch, as "owner/repo#123 Title"
+func getPullRequestTitle() (string, error) {
+	branch, err := runGit("symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	remote, err := runGit("remote", "get-url", "origin")
+	if err != nil {
+		return "", err
+	}
+	match := GITHUB_REMOTE_PATTERN.FindStringSubmatch(remote)
+	if match == nil {
+		return "", errors.New("the origin remote is not a GitHub repository: " + remote)
+	}
+	repository := match[1]
+	owner := strings.Split(repository, "/")[0]
+
+	var pulls []GitHubIssue
+	url := getGitHubURL() + "/repos/" + repository + "/pulls?state=open&head=" + owner + ":" + branch
+	if err = callJSONAPI("GET", url, getGitHubHeaders(), nil, &pulls); err != nil {
+		return "", err
+	}
+	if len(pulls) == 0 {
+		return "", errors.New("no open pull request for the branch " + branch)
+	}
+	return fmt.Sprintf("%s#%d %s", repository, pulls[0].Number, strings.TrimSpace(pulls[0].Title)), nil
+}
+
+// Comments the time tracked on a GitHub issue (or pull request), then closes it
+func closeGitHubIssue(reference string) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	var total time.Duration
+	sessions := 0
+	for _, in := range computeIntervals(records) {
+		if ref, found := getGitHubReference(in.title); found && ref == reference {
+			total += in.end.Sub(in.start)
+			sessions++
+		}
+	}
+
+	parts := strings.SplitN(reference, "#", 2)
+	url := getGitHubURL() + "/repos/" + parts[0] + "/issues/" + parts[1]
+	comment := map[string]string{"body": fmt.Sprintf("Time tracked: %v over %d sessions", total.Truncate(time.Minute), sessions)}
+	if err = callJSONAPI("POST", url+"/comments", getGitHubHeaders(), comment, nil); err != nil {
+		return fmt.Errorf("Cannot comment on %s: %w", reference, err)
+	}
+	if err = callJSONAPI("PATCH", url, getGitHubHeaders(), map[string]string{"state": "closed"}, nil); err != nil {
+		return fmt.Errorf("Cannot close %s: %w", reference, err)
+	}
+	fmt.Printf("%s closed (%v tracked)\n", reference, total.Truncate(time.Minute))
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const DEFAULT_GITLAB_URL = "https://gitlab.com"
+
+// GitLab references given to start: #42 for an issue, !17 for a merge request
+var GITLAB_START_PATTERN = regexp.MustCompile(`^([#!])([0-9]+)$`)
+
+// GitLab references at the beginning of a title, such as group/project!17 in "group/project!17 Add login"
+var GITLAB_REF_PATTERN = regexp.MustCompile(`^([\w.-]+(?:/[\w.-]+)+)([#!])([0-9]+)\b`)
+
+func getGitLabURL() string {
+	return strings.TrimRight(getConfig("gitlab.url", DEFAULT_GITLAB_URL), "/")
+}
+
+func getGitLabHeaders() map[string]string {
+	headers := make(map[string]string)
+	if token := getConfig("gitlab.token", ""); token != "" {
+		headers["PRIVATE-TOKEN"] = token
+	}
+	return headers
+}
+
+// Returns the path of the GitLab project: gitlab.project, or the origin remote of the current repository
+// when it is hosted on gitlab.url
+func getGitLabProject() (string, error) {
+	if project := getConfig("gitlab.project", ""); project != "" {
+		return project, nil
+	}
+
+	remote, err := runGit("remote", "get-url", "origin")
+	if err != nil {
+		return "", errors.New("set gitlab.project, or run mate in a GitLab repository")
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(getGitLabURL(), "https://"), "http://")
+	pattern := regexp.MustCompile(regexp.QuoteMeta(host) + `[:/](.+?)(\.git)?/?$`)
+	match := pattern.FindStringSubmatch(remote)
+	if match == nil {
+		return "", errors.New("the origin remote is not hosted on " + host + ": " + remote)
+	}
+	return match[1], nil
+}
+
+// Returns the API path of an issue ("#") or merge request ("!") of a project
+func getGitLabItemURL(project string, kind string, iid string) string {
+	collection := "issues"
+	if kind == "!" {
+		collection = "merge_requests"
+	}
+	return getGitLabURL() + "/api/v4/projects/" + url.PathEscape(project) + "/" + collection + "/" + iid
+}
+
+// Completes a #42 or !17 title with the title of the issue or merge request of the GitLab project,
+// e.g. "group/project!17 Add login"
+func expandGitLabTitle(title string) string {
+	match := GITLAB_START_PATTERN.FindStringSubmatch(title)
+	if match == nil {
+		return title
+	}
+	project, err := getGitLabProject()
+	if err != nil {
+		fmt.Printf("Cannot resolve %s: %v\n", title, err)
+		return title
+	}
+	reference := project + match[1] + match[2]
+
+	var item struct {
+		Title string `json:"title"`
+	}
+	if err = callJSONAPI("GET", getGitLabItemURL(project, match[1], match[2]), getGitLabHeaders(), nil, &item); err != nil {
+		fmt.Printf("Cannot fetch the title of %s: %v\n", reference, err)
+		return reference
+	}
+	return reference + " " + strings.TrimSpace(item.Title)
+}
+
+// Adds the duration of a stopped interval to the spent time of its GitLab issue or merge request
+// (as the /spend quick action does), when gitlab.spend_on_stop is set
+func spendOnGitLab(in Interval) {
+	match := GITLAB_REF_PATTERN.FindStringSubmatch(in.title)
+	if match == nil || !getConfigBool("gitlab.spend_on_stop", false) {
+		return
+	}
+	spent := in.end.Sub(in.start).Round(time.Minute)
+	if spent < time.Minute {
+		return
+	}
+
+	duration := formatMinutes(spent)
+	endpoint := getGitLabItemURL(match[1], match[2], match[3]) + "/add_spent_time?duration=" + url.QueryEscape(duration)
+	if err := callJSONAPI("POST", endpoint, getGitLabHeaders(), nil, nil); err != nil {
+		fmt.Printf("Cannot add the spent time to %s: %v\n", match[1]+match[2]+match[3], err)
+		return
+	}
+	fmt.Printf("%s spent on %s\n", duration, match[1]+match[2]+match[3])
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+const GIT_DATABASE_DIR = ".mate.git"
+const GIT_DATABASE_NAME = "mate.csv"
+const GIT_DATABASE_BRANCH = "mate"
+
+// Whether the git repository has already been pulled by this process
+var gitDatabasePulled = false
+
+// Returns the git repository holding the database, set up by "mate sync --git"
+func getGitDatabasePath() string {
+	return getHomeFilePath(GIT_DATABASE_DIR)
+}
+
+func isGitDatabaseEnabled() bool {
+	info, err := os.Stat(getGitDatabasePath() + "/.git")
+	return err == nil && info.IsDir()
+}
+
+func runGitDatabase(args ...string) (string, error) {
+	return runGit(append([]string{"-C", getGitDatabasePath()}, args...)...)
+}
+
+func hasGitDatabaseRemote() bool {
+	_, err := runGitDatabase("remote", "get-url", "origin")
+	return err == nil
+}
+
+// Reads the database of a revision of the repository, an unknown revision being an empty database
+func readGitDatabase(revision string) []Record {
+	// Not through runGit, which trims the output of an encrypted database
+	content, err := exec.Command("git", "-C", getGitDatabasePath(), "show", revision+":"+GIT_DATABASE_NAME).Output()
+	if err != nil {
+		return nil
+	}
+	records, err := parseDatabase(content)
+	if err != nil {
+		return nil
+	}
+	return records
+}
+
+func getRecordSet(records []Record) map[string]bool {
+	set := make(map[string]bool)
+	for _, r := range records {
+		set[formatRecord(r)] = true
+	}
+	return set
+}
+
+// Merges the entries of two versions of the database, given their common ancestor:
+// entries added on either side are kept, entries removed on either side are dropped
+func mergeDatabases(base []Record, ours []Record, theirs []Record) (merged []Record) {
+	baseSet, oursSet, theirsSet := getRecordSet(base), getRecordSet(ours), getRecordSet(theirs)
+	for _, r := range ours {
+		if theirsSet[formatRecord(r)] || !baseSet[formatRecord(r)] {
+			merged = append(merged, r)
+		}
+	}
+	for _, r := range theirs {
+		if !oursSet[formatRecord(r)] && !baseSet[formatRecord(r)] {
+			merged = append(merged, r)
+		}
+	}
+	sortRecords(merged)
+	return
+}
+
+// Pushes the commits of the repository missing on its remote, if any
+func pushGitDatabase() {
+	if !hasGitDatabaseRemote() {
+		return
+	}
+	if ahead, err := runGitDatabase("rev-list", "--count", "origin/"+GIT_DATABASE_BRANCH+"..HEAD"); err == nil && ahead == "0" {
+		return
+	}
+	if _, err := runGitDatabase("push", "-q", "origin", GIT_DATABASE_BRANCH); err != nil {
+		fmt.Printf("Cannot push the database: %v\n", err)
+	}
+}
+
+// Commits the database to the repository after a write
+// The remote is merged before pushing the commit, as it would be rejected otherwise
+func commitGitDatabase() error {
+	if !isGitDatabaseEnabled() {
+		return nil
+	}
+	content, err := files.ReadFile(getDbPath())
+	if err != nil {
+		return fmt.Errorf("Cannot commit the database: %w", err)
+	}
+	if err = os.WriteFile(getGitDatabasePath()+"/"+GIT_DATABASE_NAME, content, 0644); err != nil {
+		return fmt.Errorf("Cannot commit the database: %w", err)
+	}
+	if _, err = runGitDatabase("add", GIT_DATABASE_NAME); err != nil {
+		return fmt.Errorf("Cannot commit the database: %w", err)
+	}
+	// diff exits with 1 when something is staged
+	if _, err = runGitDatabase("diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+	hostname, _ := os.Hostname()
+	if _, err = runGitDatabase("commit", "-q", "-m", "Update from "+hostname); err != nil {
+		return fmt.Errorf("Cannot commit the database: %w", err)
+	}
+	if gitDatabasePulled {
+		pushGitDatabase()
+		return nil
+	}
+	return pullGitDatabase()
+}
+
+// Fetches the repository once per process, and merges the entries written on other machines
+// Nothing is done when offline, the entries being merged on the next successful fetch
+func pullGitDatabase() error {
+	if gitDatabasePulled {
+		return nil
+	}
+	gitDatabasePulled = true
+	if !isGitDatabaseEnabled() || !hasGitDatabaseRemote() {
+		return nil
+	}
+	if _, err := runGitDatabase("fetch", "-q", "origin"); err != nil {
+		return nil
+	}
+	remote := "origin/" + GIT_DATABASE_BRANCH
+	if _, err := runGitDatabase("rev-parse", "-q", "--verify", remote); err != nil {
+		// Nothing pushed yet
+		pushGitDatabase()
+		return nil
+	}
+	if _, err := runGitDatabase("merge-base", "--is-ancestor", remote, "HEAD"); err == nil {
+		pushGitDatabase()
+		return nil
+	}
+
+	var base []Record
+	if revision, err := runGitDatabase("merge-base", "HEAD", remote); err == nil {
+		base = readGitDatabase(revision)
+	}
+	ours, err := getRecords()
+	if err != nil {
+		return err
+	}
+	theirs := readGitDatabase(remote)
+	// The history is joined with the tree of the local side, then the merged database is committed on top
+	if _, err = runGitDatabase("merge", "-q", "-s", "ours", "--allow-unrelated-histories", "--no-edit", remote); err != nil {
+		return fmt.Errorf("Cannot merge the database: %w", err)
+	}
+	if err = writeRecords(mergeDatabases(base, ours, theirs)); err != nil {
+		return err
+	}
+	pushGitDatabase()
+	return nil
+}
+
+// Stores the database in a git repository, committed on every write and merged with the remote on read
+// The remote (e.g. a private repository) is set to the given URL, if any
+func setupGitDatabase(url string) error {
+	path := getGitDatabasePath()
+	if !isGitDatabaseEnabled() {
+		if _, err := runGit("init", "-q", path); err != nil {
+			return err
+		}
+		runGitDatabase("symbolic-ref", "HEAD", "refs/heads/"+GIT_DATABASE_BRANCH)
+		// Commits must not fail on a machine without a git identity
+		if _, err := runGitDatabase("config", "user.email"); err != nil {
+			hostname, _ := os.Hostname()
+			runGitDatabase("config", "user.name", "mate")
+			runGitDatabase("config", "user.email", "mate@"+hostname)
+		}
+		fmt.Printf("Git repository created in %s\n", path)
+	}
+	if url != "" {
+		command := "add"
+		if hasGitDatabaseRemote() {
+			command = "set-url"
+		}
+		if _, err := runGitDatabase("remote", command, "origin", url); err != nil {
+			return err
+		}
+	}
+
+	if err := ensureCSVExists(); err != nil {
+		return err
+	}
+	gitDatabasePulled = false
+	if err := commitGitDatabase(); err != nil {
+		return err
+	}
+	if err := pullGitDatabase(); err != nil {
+		return err
+	}
+	if remote, err := runGitDatabase("remote", "get-url", "origin"); err == nil {
+		fmt.Printf("Database synced with %s\n", remote)
+	} else {
+		fmt.Println("Database committed, run \"mate sync --git <url>\" to sync it with a remote repository")
+	}
+	return nil
+}
@@ -1,3 +1,3 @@
 module eguerlain.github.com/mate
 
-go 1.14
+go 1.16
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const HOLIDAYS_CACHE_NAME = ".mate.holidays.ics"
+const HOLIDAYS_CACHE_DURATION = time.Hour * 24 * 7
+
+// Public holidays, keyed by DATE_FORMAT, with their name
+var holidays map[string]string
+
+// Computes Easter Sunday of the given year (anonymous Gregorian algorithm)
+func getEaster(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// Returns the nth given weekday of a month (n < 0 counts from the end of the month)
+func getNthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	if n < 0 {
+		last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+		return last.AddDate(0, 0, -((int(last.Weekday())-int(weekday)+7)%7 + 7*(-n-1)))
+	}
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return first.AddDate(0, 0, (int(weekday)-int(first.Weekday())+7)%7+7*(n-1))
+}
+
+// Moves a holiday falling on a weekend to the closest weekday, as observed in the US
+func getObserved(day time.Time) time.Time {
+	switch day.Weekday() {
+	case time.Saturday:
+		return day.AddDate(0, 0, -1)
+	case time.Sunday:
+		return day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// Computes the national public holidays of a region for the given year
+var HOLIDAY_REGIONS = map[string]func(year int) map[time.Time]string{
+	"fr": func(year int) map[time.Time]string {
+		easter := getEaster(year)
+		return map[time.Time]string{
+			time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC):   "Jour de l'an",
+			easter.AddDate(0, 0, 1):                       "Lundi de Pâques",
+			time.Date(year, 5, 1, 0, 0, 0, 0, time.UTC):   "Fête du Travail",
+			time.Date(year, 5, 8, 0, 0, 0, 0, time.UTC):   "Victoire 1945",
+			easter.AddDate(0, 0, 39):                      "Ascension",
+			easter.AddDate(0, 0, 50):                      "Lundi de Pentecôte",
+			time.Date(year, 7, 14, 0, 0, 0, 0, time.UTC):  "Fête nationale",
+			time.Date(year, 8, 15, 0, 0, 0, 0, time.UTC):  "Assomption",
+			time.Date(year, 11, 1, 0, 0, 0, 0, time.UTC):  "Toussaint",
+			time.Date(year, 11, 11, 0, 0, 0, 0, time.UTC): "Armistice 1918",
+			time.Date(year, 12, 25, 0, 0, 0, 0, time.UTC): "Noël",
+		}
+	},
+	"de": func(year int) map[time.Time]string {
+		easter := getEaster(year)
+		return map[time.Time]string{
+			time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC):   "Neujahr",
+			easter.AddDate(0, 0, -2):                      "Karfreitag",
+			easter.AddDate(0, 0, 1):                       "Ostermontag",
+			time.Date(year, 5, 1, 0, 0, 0, 0, time.UTC):   "Tag der Arbeit",
+			easter.AddDate(0, 0, 39):                      "Christi Himmelfahrt",
+			easter.AddDate(0, 0, 50):                      "Pfingstmontag",
+			time.Date(year, 10, 3, 0, 0, 0, 0, time.UTC):  "Tag der Deutschen Einheit",
+			time.Date(year, 12, 25, 0, 0, 0, 0, time.UTC): "1. Weihnachtstag",
+			time.Date(year, 12, 26, 0, 0, 0, 0, time.UTC): "2. Weihnachtstag",
+		}
+	},
+	"us": func(year int) map[time.Time]string {
+		return map[time.Time]string{
+			getObserved(time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)):   "New Year's Day",
+			getNthWeekday(year, 1, time.Monday, 3):                     "Martin Luther King Jr. Day",
+			getNthWeekday(year, 2, time.Monday, 3):                     "Washington's Birthday",
+			getNthWeekday(year, 5, time.Monday, -1):                    "Memorial Day",
+			getObserved(time.Date(year, 6, 19, 0, 0, 0, 0, time.UTC)):  "Juneteenth",
+			getObserved(time.Date(year, 7, 4, 0, 0, 0, 0, time.UTC)):   "Independence Day",
+			getNthWeekday(year, 9, time.Monday, 1):                     "Labor Day",
+			getNthWeekday(year, 10, time.Monday, 2):                    "Columbus Day",
+			getObserved(time.Date(year, 11, 11, 0, 0, 0, 0, time.UTC)): "Veterans Day",
+			getNthWeekday(year, 11, time.Thursday, 4):                  "Thanksgiving Day",
+			getObserved(time.Date(year, 12, 25, 0, 0, 0, 0, time.UTC)): "Christmas Day",
+		}
+	},
+}
+
+// Opens the holiday feed: a local file, or an http(s) URL cached for a week
+func openHolidayFeed(source string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.Open(source)
+	}
+
+	cachePath := getHomeFilePath(HOLIDAYS_CACHE_NAME)
+	if info, err := os.Stat(cachePath); err == nil && time.Since(info.ModTime()) < HOLIDAYS_CACHE_DURATION {
+		return os.Open(cachePath)
+	}
+
+	client := http.Client{Timeout: time.Second * 10}
+	response, err := client.Get(source)
+	if err != nil {
+		// An outdated cache is better than no holidays at all
+		if f, cacheErr := os.Open(cachePath); cacheErr == nil {
+			return f, nil
+		}
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", source, response.Status)
+	}
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(cachePath, content, 0644); err != nil {
+		return nil, err
+	}
+	return os.Open(cachePath)
+}
+
+// Loads the public holidays of holidays.region for the years around today, and of the holidays.ics feed
+func loadHolidays() map[string]string {
+	loaded := make(map[string]string)
+
+	if getConfig("holidays.region", "") != "" {
+		compute := HOLIDAY_REGIONS[getTypedConfig("holidays.region", "holiday region", "").(string)]
+		year := getNow().Year()
+		for y := year - 5; y <= year+1; y++ {
+			for day, name := range compute(y) {
+				loaded[day.Format(DATE_FORMAT)] = name
+			}
+		}
+	}
+
+	if source := getConfig("holidays.ics", ""); source != "" {
+		f, err := openHolidayFeed(source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot read the holidays feed: %v\n", err)
+			return loaded
+		}
+		defer f.Close()
+		events, err := parseICS(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot read the holidays feed: %v\n", err)
+			return loaded
+		}
+		for _, event := range events {
+			for day := event.start.Truncate(time.Hour * 24); day.Before(event.end); day = day.AddDate(0, 0, 1) {
+				loaded[day.Format(DATE_FORMAT)] = event.summary
+			}
+		}
+	}
+
+	return loaded
+}
+
+// Returns the name of the public holiday of the given day, if any
+func getHoliday(day time.Time) (string, bool) {
+	if holidays == nil {
+		holidays = loadHolidays()
+	}
+	name, found := holidays[day.Format(DATE_FORMAT)]
+	return name, found
+}
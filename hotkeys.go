@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Actions of the hotkeys, keyed by their option in the [hotkeys] section
+var HOTKEY_ACTIONS = []string{"stop", "resume", "pick"}
+
+// Pickers tried in order for hotkeys.pick when hotkeys.picker is not set, reading the titles on stdin
+var HOTKEY_PICKERS = [][]string{
+	{"rofi", "-dmenu", "-i", "-p", "mate"},
+	{"wofi", "--dmenu", "--prompt", "mate"},
+	{"dmenu", "-i", "-p", "mate"},
+	{"zenity", "--list", "--title=mate", "--column=Ticket", "--hide-header"},
+}
+
+var X11_MODIFIERS = map[string]uint16{
+	"shift":   X11_SHIFT,
+	"ctrl":    X11_CTRL,
+	"control": X11_CTRL,
+	"alt":     X11_MOD1,
+	"super":   X11_MOD4,
+	"win":     X11_MOD4,
+}
+
+// Keysyms of the named keys, letters and digits being their ASCII code
+var X11_KEYSYMS = map[string]uint32{
+	"space":  0x20,
+	"return": 0xff0d,
+	"enter":  0xff0d,
+	"escape": 0xff1b,
+	"pause":  0xff13,
+	"insert": 0xff63,
+	"delete": 0xffff,
+	"home":   0xff50,
+	"end":    0xff57,
+}
+
+// A combination of modifiers and a key, e.g. ctrl+alt+s
+type Hotkey struct {
+	modifiers uint16
+	keysym    uint32
+}
+
+// Parses a hotkey of the config, e.g. "ctrl+alt+s", "super+shift+p" or "F9"
+// A letter, a digit or the space needs a modifier, to keep typing it
+func parseHotkey(literal string) (Hotkey, error) {
+	var hotkey Hotkey
+	parts := strings.Split(strings.ToLower(strings.ReplaceAll(literal, " ", "")), "+")
+	for _, modifier := range parts[:len(parts)-1] {
+		mask, found := X11_MODIFIERS[modifier]
+		if !found {
+			return Hotkey{}, fmt.Errorf("Invalid hotkey \"%s\" (unknown modifier \"%s\", expected ctrl, alt, shift or super)", literal, modifier)
+		}
+		hotkey.modifiers |= mask
+	}
+
+	key := parts[len(parts)-1]
+	function, err := strconv.Atoi(strings.TrimPrefix(key, "f"))
+	switch {
+	case len(key) == 1 && (key[0] >= 'a' && key[0] <= 'z' || key[0] >= '0' && key[0] <= '9'):
+		hotkey.keysym = uint32(key[0])
+	case strings.HasPrefix(key, "f") && err == nil && function >= 1 && function <= 12:
+		// XK_F1
+		hotkey.keysym = 0xffbe + uint32(function-1)
+	case X11_KEYSYMS[key] != 0:
+		hotkey.keysym = X11_KEYSYMS[key]
+	default:
+		return Hotkey{}, fmt.Errorf("Invalid hotkey \"%s\" (expected e.g. ctrl+alt+s or F9)", literal)
+	}
+	if hotkey.modifiers&^X11_SHIFT == 0 && hotkey.keysym < 0x80 {
+		return Hotkey{}, fmt.Errorf("Invalid hotkey \"%s\" (a letter, a digit or the space needs ctrl, alt or super)", literal)
+	}
+	return hotkey, nil
+}
+
+// Grabs the hotkeys of the config on the X11 display, then sends the action of each of their presses
+// The channel is closed if the connection to the display is lost
+func watchHotkeys() (<-chan string, error) {
+	hotkeys := make(map[string]Hotkey)
+	for _, action := range HOTKEY_ACTIONS {
+		if literal := getConfig("hotkeys."+action, ""); literal != "" {
+			hotkey, _ := parseHotkey(literal)
+			hotkeys[action] = hotkey
+		}
+	}
+	if len(hotkeys) == 0 {
+		return nil, nil
+	}
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" || os.Getenv("DISPLAY") == "" {
+		return nil, errors.New("global hotkeys need an X11 display ($DISPLAY), bind mate stop and mate start in the system settings instead")
+	}
+
+	x, err := connectX11()
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to the X11 display: %w", err)
+	}
+	keycodes, err := x.getKeycodes()
+	if err != nil {
+		x.close()
+		return nil, err
+	}
+	// The key presses of the hotkeys, with their modifiers as state
+	actions := make(map[X11KeyPress]string)
+	for _, action := range HOTKEY_ACTIONS {
+		hotkey, found := hotkeys[action]
+		if !found {
+			continue
+		}
+		keycode, found := keycodes[hotkey.keysym]
+		if !found {
+			fmt.Printf("Warning: no key of the keyboard for hotkeys.%s \"%s\"\n", action, getConfig("hotkeys."+action, ""))
+			continue
+		}
+		err = x.grabKey(keycode, hotkey.modifiers)
+		var x11Error X11Error
+		if errors.As(err, &x11Error) && x11Error.code == X11_BAD_ACCESS {
+			fmt.Printf("Warning: hotkeys.%s \"%s\" is already taken by another application\n", action, getConfig("hotkeys."+action, ""))
+			continue
+		}
+		if err != nil {
+			x.close()
+			return nil, err
+		}
+		actions[X11KeyPress{keycode, hotkey.modifiers}] = action
+	}
+	if len(actions) == 0 {
+		x.close()
+		return nil, errors.New("none of the hotkeys could be grabbed")
+	}
+
+	presses := make(chan string)
+	go func() {
+		defer close(presses)
+		defer x.close()
+		for {
+			packet, err := x.read()
+			if err != nil {
+				debugf("X11 connection closed: %v", err)
+				return
+			}
+			if press, ok := packet.(X11KeyPress); ok {
+				// CapsLock and NumLock do not change the hotkey
+				press.state &= X11_SHIFT | X11_CTRL | X11_MOD1 | X11_MOD4
+				if action, found := actions[press]; found {
+					presses <- action
+				}
+			}
+		}
+	}()
+	return presses, nil
+}
+
+// Runs the action of a hotkey, notifying what it did as nothing is shown in a terminal
+// The picker runs in the background, its choice being sent to picked
+func runHotkeyAction(action string, picked chan<- string) error {
+	switch action {
+	case "stop":
+		if err := stopTicket(false); err != nil {
+			return err
+		}
+		notify("Ticket stopped")
+	case "resume":
+		if err := restartLastTicket(); err != nil {
+			return err
+		}
+		records, err := getRecentRecords(getNow().Truncate(time.Hour * 24))
+		if err == nil && len(records) != 0 {
+			notify("Resumed " + records[len(records)-1].title)
+		}
+	case "pick":
+		records, err := getRecentRecords(getNow().AddDate(0, 0, -RECENT_DAYS))
+		if err != nil {
+			return err
+		}
+		var titles []string
+		for _, item := range getRecentItems(records, "") {
+			titles = append(titles, item.Arg)
+		}
+		go func() {
+			title, err := pickTitle(titles)
+			if err != nil {
+				reportDaemonError(err)
+				return
+			}
+			if title != "" {
+				picked <- title
+			}
+		}()
+	}
+	return nil
+}
+
+// Starts the ticket chosen in the picker
+func startPickedTicket(title string) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	title = expandTitle(title, records)
+	if err = startTicket(title); err != nil {
+		return err
+	}
+	notify("Started " + title)
+	return nil
+}
+
+// Lets the user choose a title in hotkeys.picker (a command reading the choices on stdin, e.g. "rofi -dmenu"),
+// or a new one, returning an empty title if the picker was cancelled
+func pickTitle(titles []string) (string, error) {
+	picker := strings.Fields(getConfig("hotkeys.picker", ""))
+	if len(picker) == 0 {
+		for _, candidate := range HOTKEY_PICKERS {
+			if _, err := exec.LookPath(candidate[0]); err == nil {
+				picker = candidate
+				break
+			}
+		}
+	}
+	if len(picker) == 0 {
+		return "", errors.New("No picker found for hotkeys.pick, install rofi or dmenu, or set hotkeys.picker")
+	}
+
+	cmd := exec.Command(picker[0], picker[1:]...)
+	cmd.Stdin = strings.NewReader(strings.Join(titles, "\n"))
+	output, err := cmd.Output()
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) {
+		// Cancelled
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("Cannot run the picker %s: %w", picker[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
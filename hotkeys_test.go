@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseHotkey(t *testing.T) {
+	tests := []struct {
+		name    string
+		literal string
+		wantErr bool
+		want    Hotkey
+	}{
+		{"letter with modifiers", "ctrl+alt+s", false, Hotkey{X11_CTRL | X11_MOD1, 's'}},
+		{"case and spaces", "Super + Shift + P", false, Hotkey{X11_MOD4 | X11_SHIFT, 'p'}},
+		{"digit", "control+1", false, Hotkey{X11_CTRL, '1'}},
+		{"function key alone", "F9", false, Hotkey{0, 0xffc6}},
+		{"first function key", "shift+f1", false, Hotkey{X11_SHIFT, 0xffbe}},
+		{"named key", "win+pause", false, Hotkey{X11_MOD4, 0xff13}},
+		{"space with a modifier", "alt+space", false, Hotkey{X11_MOD1, 0x20}},
+		{"letter alone", "s", true, Hotkey{}},
+		{"letter with shift only", "shift+s", true, Hotkey{}},
+		{"space alone", "space", true, Hotkey{}},
+		{"unknown modifier", "hyper+s", true, Hotkey{}},
+		{"unknown key", "ctrl+tab", true, Hotkey{}},
+		{"function key out of range", "F13", true, Hotkey{}},
+		{"empty", "", true, Hotkey{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseHotkey(test.literal)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
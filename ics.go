@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"time"
+)
+
+// An event of an iCalendar file
+type ICSEvent struct {
+	start   time.Time
+	end     time.Time
+	allDay  bool
+	summary string
+	uid     string
+}
+
+// Reads the logical lines of an iCalendar file, unfolding the continuation lines
+func readICSLines(r io.Reader) (lines []string, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) != 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// Parses a DTSTART/DTEND value with its parameters (e.g. "DTSTART;TZID=Europe/Paris:20240102T090000")
+// Times are converted to the wall clock time of the database
+func parseICSTime(params string, value string) (t time.Time, allDay bool, ok bool) {
+	if strings.Contains(params, "VALUE=DATE") && !strings.Contains(params, "VALUE=DATE-TIME") || len(value) == 8 {
+		day, err := time.Parse("20060102", value)
+		return day, true, err == nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		utc, err := time.Parse("20060102T150405Z", value)
+		return toDbTime(utc.Local()), false, err == nil
+	}
+
+	location := time.Local
+	for _, param := range strings.Split(params, ";") {
+		if strings.HasPrefix(param, "TZID=") {
+			if loc, err := time.LoadLocation(strings.Trim(strings.TrimPrefix(param, "TZID="), "\"")); err == nil {
+				location = loc
+			}
+		}
+	}
+	local, err := time.ParseInLocation("20060102T150405", value, location)
+	return toDbTime(local.Local()), false, err == nil
+}
+
+// Unescapes a TEXT value of an iCalendar file
+func unescapeICSText(value string) string {
+	replacer := strings.NewReplacer("\\n", "\n", "\\N", "\n", "\\,", ",", "\\;", ";", "\\\\", "\\")
+	return replacer.Replace(value)
+}
+
+// Parses the events of an iCalendar file
+// Events without a valid start are ignored; events without an end last until their start (or a day if all-day)
+func parseICS(r io.Reader) (events []ICSEvent, err error) {
+	lines, err := readICSLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var event ICSEvent
+	inEvent, validStart, hasEnd := false, false, false
+	for _, line := range lines {
+		switch line {
+		case "BEGIN:VEVENT":
+			event, inEvent, validStart, hasEnd = ICSEvent{}, true, false, false
+			continue
+		case "END:VEVENT":
+			if inEvent && validStart {
+				if !hasEnd {
+					event.end = event.start
+					if event.allDay {
+						event.end = event.start.AddDate(0, 0, 1)
+					}
+				}
+				events = append(events, event)
+			}
+			inEvent = false
+			continue
+		}
+		if !inEvent {
+			continue
+		}
+
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			continue
+		}
+		name, value := line[:colon], line[colon+1:]
+		params := ""
+		if semicolon := strings.Index(name, ";"); semicolon >= 0 {
+			name, params = name[:semicolon], name[semicolon+1:]
+		}
+
+		switch strings.ToUpper(name) {
+		case "DTSTART":
+			event.start, event.allDay, validStart = parseICSTime(params, value)
+		case "DTEND":
+			var ok bool
+			event.end, _, ok = parseICSTime(params, value)
+			hasEnd = ok
+		case "SUMMARY":
+			event.summary = unescapeICSText(value)
+		case "UID":
+			event.uid = value
+		}
+	}
+	return events, nil
+}
+
+// Escapes a TEXT value for an iCalendar file
+func escapeICSText(value string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", ";", "\\;", ",", "\\,", "\n", "\\n")
+	return replacer.Replace(value)
+}
+
+// Folds a content line at 75 octets, as required by RFC 5545, without splitting UTF-8 characters
+func foldICSLine(line string) string {
+	var folded strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > 75 {
+			folded.WriteString("\r\n ")
+			length = 1
+		}
+		folded.WriteRune(r)
+		length += size
+	}
+	folded.WriteString("\r\n")
+	return folded.String()
+}
+
+// Writes the intervals as the events of an iCalendar file
+// Times are floating (without time zone), i.e. wall clock times as in the database
+func writeICS(w io.Writer, intervals []Interval) error {
+	stamp := clock.Now().UTC().Format("20060102T150405Z")
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//mate//mate//EN", "CALSCALE:GREGORIAN"}
+	for _, in := range intervals {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:%s-%08x@mate", in.start.Format("20060102T150405"), crc32.ChecksumIEEE([]byte(in.title))),
+			"DTSTAMP:"+stamp,
+			"DTSTART:"+in.start.Format("20060102T150405"),
+			"DTEND:"+in.end.Format("20060102T150405"),
+			"SUMMARY:"+escapeICSText(in.title),
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, foldICSLine(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const DEFAULT_ICS_TITLE = "{summary}"
+
+// Converts the events of an iCalendar file to periods, titled after import.ics_title
+// ({summary} being replaced by the title of the event)
+// All-day events are ignored
+func importICS(r io.Reader) ([]Interval, error) {
+	events, err := parseICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	template := getConfig("import.ics_title", DEFAULT_ICS_TITLE)
+	var periods []Interval
+	for _, event := range events {
+		if event.allDay {
+			continue
+		}
+		title := strings.ReplaceAll(template, "{summary}", strings.TrimSpace(event.summary))
+		periods = append(periods, Interval{event.start, event.end, title})
+	}
+	sortPeriods(periods)
+	return periods, nil
+}
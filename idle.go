@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+const DEFAULT_IDLE_ACTION = "pause"
+
+var IDLE_NUMBER_PATTERN = regexp.MustCompile(`[0-9]+`)
+
+// Runs a command and returns the first number of its output
+func readCommandNumber(name string, args ...string) (int64, error) {
+	output, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return 0, err
+	}
+	match := IDLE_NUMBER_PATTERN.Find(output)
+	if match == nil {
+		return 0, errors.New("unexpected output of " + name)
+	}
+	return strconv.ParseInt(string(match), 10, 64)
+}
+
+// Returns for how long the user has not touched the keyboard or mouse
+func getIdleTime() (time.Duration, error) {
+	if runtime.GOOS == "darwin" {
+		// HIDIdleTime is in nanoseconds
+		output, err := exec.Command("ioreg", "-c", "IOHIDSystem", "-d", "4").Output()
+		if err != nil {
+			return 0, err
+		}
+		match := regexp.MustCompile(`"HIDIdleTime" = ([0-9]+)`).FindSubmatch(output)
+		if match == nil {
+			return 0, errors.New("HIDIdleTime not found in ioreg output")
+		}
+		idle, err := strconv.ParseInt(string(match[1]), 10, 64)
+		return time.Duration(idle), err
+	}
+
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		// Wayland has no generic API, GNOME exposes one over D-Bus (in milliseconds)
+		idle, err := readCommandNumber("gdbus", "call", "--session",
+			"--dest", "org.gnome.Mutter.IdleMonitor",
+			"--object-path", "/org/gnome/Mutter/IdleMonitor/Core",
+			"--method", "org.gnome.Mutter.IdleMonitor.GetIdletime")
+		if err == nil {
+			return time.Duration(idle) * time.Millisecond, nil
+		}
+	}
+
+	// X11, in milliseconds
+	idle, err := readCommandNumber("xprintidle")
+	if err != nil {
+		return 0, errors.New("cannot read the idle time (install xprintidle on X11, or use GNOME on Wayland)")
+	}
+	return time.Duration(idle) * time.Millisecond, nil
+}
+
+// Follows the idle time between two checks of the daemon
+type IdleWatcher struct {
+	disabled  bool
+	idleSince time.Time
+	paused    Record // Ticket stopped because of idleness, to resume on return
+}
+
+// Handles the idle periods longer than daemon.idle_after, as set by daemon.idle_action:
+//   - pause: the ticket is stopped when the idle period began, and resumed on return
+//   - ask: on return, the user is asked whether the idle period should be discarded
+//
+// Without a terminal to ask on, ask behaves like pause
+func (w *IdleWatcher) check() error {
+	idleAfter := getConfigDuration("daemon.idle_after", "0")
+	if w.disabled || idleAfter == 0 {
+		return nil
+	}
+	action := getTypedConfig("daemon.idle_action", "pause|ask", DEFAULT_IDLE_ACTION).(string)
+	if action == "ask" && !isTerminal(os.Stdin) {
+		action = "pause"
+	}
+
+	idle, err := getIdleTime()
+	if err != nil {
+		fmt.Printf("Idle detection disabled: %v\n", err)
+		w.disabled = true
+		return nil
+	}
+	now := getNow()
+
+	if idle >= idleAfter {
+		if w.idleSince.IsZero() {
+			w.idleSince = now.Add(-idle).Truncate(time.Second)
+			if action == "pause" {
+				return w.pause()
+			}
+		}
+		return nil
+	}
+
+	if w.idleSince.IsZero() {
+		return nil
+	}
+	idleSince, returnedAt := w.idleSince, now.Add(-idle).Truncate(time.Second)
+	w.idleSince = time.Time{}
+	if action == "pause" {
+		return w.resume(idleSince, returnedAt)
+	}
+	return askToDiscardIdle(idleSince, returnedAt)
+}
+
+// Stops the running ticket at the given time, if it was started before
+// Returns the stopped entry
+func pauseRunningTicket(at time.Time) (Record, bool, error) {
+	records, err := getRecords()
+	if err != nil {
+		return Record{}, false, err
+	}
+	if len(records) == 0 || isAutoStopped(records) {
+		return Record{}, false, nil
+	}
+	last := records[len(records)-1]
+	if last.title == STOP_TOKEN || last.timestamp.After(at) {
+		return Record{}, false, nil
+	}
+
+	if err = writeTicketAt(at, STOP_TOKEN); err != nil {
+		return Record{}, false, err
+	}
+	return last, true, nil
+}
+
+// Restarts a paused ticket at the given time
+// Nothing is done if another entry was written since the pause, or if the day changed
+func resumePausedTicket(paused Record, at time.Time) (bool, error) {
+	records, err := getRecords()
+	if err != nil || len(records) == 0 {
+		return false, err
+	}
+	last := records[len(records)-1]
+	if last.title != STOP_TOKEN || at.Before(last.timestamp) ||
+		!at.Truncate(time.Hour*24).Equal(last.timestamp.Truncate(time.Hour*24)) {
+		return false, nil
+	}
+
+	if err = writeTicketAt(at, paused.title); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Prints and notifies a message of the daemon
+func announceDaemonEvent(message string) {
+	fmt.Printf("%s %s\n", getNow().Format(CLOCK_FORMAT), message)
+	notify(message)
+}
+
+// Stops the running ticket at the beginning of the idle period
+func (w *IdleWatcher) pause() error {
+	paused, ok, err := pauseRunningTicket(w.idleSince)
+	if ok {
+		w.paused = paused
+		announceDaemonEvent(fmt.Sprintf("%s paused, idle since %s", paused.title, w.idleSince.Format(CLOCK_FORMAT)))
+	}
+	return err
+}
+
+// Resumes the ticket paused by idleness
+func (w *IdleWatcher) resume(idleSince time.Time, returnedAt time.Time) error {
+	paused := w.paused
+	w.paused = Record{}
+	if paused.title == "" {
+		return nil
+	}
+	resumed, err := resumePausedTicket(paused, returnedAt)
+	if resumed {
+		announceDaemonEvent(fmt.Sprintf("%s resumed after %v away", paused.title, returnedAt.Sub(idleSince)))
+	}
+	return err
+}
+
+// Removes a period from the running ticket, by stopping it at since and restarting it at until
+// Nothing is done if the ticket was not running during the whole period
+func removePeriodFromRunningTicket(since time.Time, until time.Time) (title string, ok bool, err error) {
+	records, err := getRecords()
+	if err != nil || len(records) == 0 || isAutoStopped(records) {
+		return "", false, err
+	}
+	last := records[len(records)-1]
+	if last.title == STOP_TOKEN || last.timestamp.After(since) || until.Before(since) {
+		return "", false, nil
+	}
+
+	if err = writeRecords(append(records, Record{since, STOP_TOKEN}, Record{until, last.title})); err != nil {
+		return "", false, err
+	}
+	return last.title, true, nil
+}
+
+// Asks whether an idle period of the running ticket should be removed from it
+func askToDiscardIdle(idleSince time.Time, returnedAt time.Time) error {
+	records, err := getRecords()
+	if err != nil || len(records) == 0 || isAutoStopped(records) {
+		return err
+	}
+	last := records[len(records)-1]
+	if last.title == STOP_TOKEN || last.timestamp.After(idleSince) {
+		return nil
+	}
+
+	notify("Welcome back! Keep the idle period?")
+	fmt.Printf("You were idle from %s to %s (%v) while working on %s\n",
+		idleSince.Format(CLOCK_FORMAT), returnedAt.Format(CLOCK_FORMAT), returnedAt.Sub(idleSince), last.title)
+	answer, ok := askUser(bufio.NewReader(os.Stdin), "Discard this period? [y/N]: ")
+	if !ok || !contains([]string{"y", "Y"}, answer) {
+		return nil
+	}
+
+	title, removed, err := removePeriodFromRunningTicket(idleSince, returnedAt)
+	if removed {
+		fmt.Printf("Idle period removed from %s\n", title)
+	}
+	return err
+}
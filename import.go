@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+var IMPORT_FORMATS = []string{"json", "ics", "csv", "watson", "timewarrior"}
+
+// A period left out of an import: one already recorded, or one overlapping an interval
+// A trimmed period is imported as its parts outside of the intervals
+type SkippedPeriod struct {
+	period     Interval
+	overlapped Interval
+	duplicate  bool
+	kept       []Interval
+}
+
+// Separates the imported records missing from the database from those already in it (same timestamp and title)
+func splitNewRecords(records []Record, imported []Record) (added []Record, present []Record) {
+	existing := make(map[string]bool)
+	for _, r := range records {
+		existing[formatRecord(r)] = true
+	}
+	for _, r := range imported {
+		if existing[formatRecord(r)] {
+			present = append(present, r)
+			continue
+		}
+		existing[formatRecord(r)] = true
+		added = append(added, r)
+	}
+	return
+}
+
+// Adds the imported records to the database, in chronological order
+// Entries already in the database (same timestamp and title) are skipped
+func mergeRecords(imported []Record) (added int, skipped int, err error) {
+	records, err := getRecords()
+	if err != nil {
+		return 0, 0, err
+	}
+	newRecords, present := splitNewRecords(records, imported)
+	if len(newRecords) != 0 {
+		records = append(records, newRecords...)
+		sortRecords(records)
+		err = writeRecords(records)
+	}
+	return len(newRecords), len(present), err
+}
+
+// Opens the file to import, "-" being stdin
+func openImportFile(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return os.Stdin, nil
+	}
+	return os.Open(path)
+}
+
+// Imports the entries of a file in the given format
+// Without a file, Watson and Timewarrior data are read from their usual place
+// The columns of a csv file are mapped to the fields of the entries by mapping (see importCSV)
+// With dryRun, the entries that would be added, skipped as duplicates or skipped as overlapping are printed instead
+func importEntries(format string, path string, mapping string, dryRun bool) error {
+	if !contains(IMPORT_FORMATS, format) {
+		return fmt.Errorf("Invalid format \"%s\" (expected %s)", format, strings.Join(IMPORT_FORMATS, ", "))
+	}
+
+	var f io.ReadCloser
+	var err error
+	if path == "" {
+		if f, err = openMigrationSource(format); err != nil {
+			return fmt.Errorf("Cannot read the %s data: %w", format, err)
+		}
+	} else if f, err = openImportFile(path); err != nil {
+		return err
+	}
+	defer f.Close()
+
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	var imported []Record
+	var periods []Interval
+	switch format {
+	case "json":
+		imported, err = importJSON(f, dryRun)
+	case "ics":
+		periods, err = importICS(f)
+	case "csv":
+		periods, err = importCSV(f, mapping)
+	case "watson":
+		periods, err = importWatson(f)
+	case "timewarrior":
+		periods, err = importTimewarrior(f)
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot import %s: %w", path, err)
+	}
+	// The periods are checked against the intervals as they are converted, the entries of a JSON export once added
+	var skipped []SkippedPeriod
+	if format != "json" {
+		imported, skipped = convertPeriods(periods, records, getOverlapPolicy() == "trim")
+	}
+	added, present := splitNewRecords(records, imported)
+	var overlaps [][2]Interval
+	if format == "json" {
+		overlaps = findOverlaps(records, added)
+	}
+	duplicates := len(present)
+	for _, s := range skipped {
+		if s.duplicate {
+			duplicates++
+		}
+	}
+	if dryRun {
+		for _, r := range added {
+			fmt.Printf("+ %s %s\n", r.timestamp.Format(TIME_FORMAT), getEntryName(r))
+		}
+		for _, r := range present {
+			fmt.Printf("= %s %s (already present)\n", r.timestamp.Format(TIME_FORMAT), getEntryName(r))
+		}
+		for _, s := range skipped {
+			if s.duplicate {
+				fmt.Printf("= %s (already present)\n", formatPeriod(s.period))
+			}
+		}
+		for _, overlap := range overlaps {
+			fmt.Printf("! %s overlaps %s (imported anyway)\n", formatPeriod(overlap[0]), formatPeriod(overlap[1]))
+		}
+		printSkippedPeriods(skipped, true)
+		fmt.Printf("Dry run: %d entries to import, %d already present, %d overlapping\n",
+			len(added), duplicates, len(overlaps)+countOverlapping(skipped))
+		return nil
+	}
+
+	if count := len(overlaps) + countOverlapping(skipped); count != 0 && getOverlapPolicy() == "reject" {
+		return fmt.Errorf("Nothing imported: %d entries overlap the recorded intervals, as shown by mate import --dry-run\n"+
+			"Fix them, or set overlaps.policy to warn (to skip them) or trim (to import their untracked time)", count)
+	}
+	printSkippedPeriods(skipped, false)
+	if _, _, err = mergeRecords(added); err != nil {
+		return err
+	}
+	fmt.Printf("%d entries imported, %d already present\n", len(added), duplicates)
+	return nil
+}
+
+// Formats a period as "title (YYYY/MM/DD HH:MM:SS - HH:MM)"
+func formatPeriod(in Interval) string {
+	return fmt.Sprintf("%s (%s - %s)", in.title, in.start.Format(TIME_FORMAT), in.end.Format(CLOCK_FORMAT))
+}
+
+// Prints the periods skipped for overlapping an interval, and the parts kept of the trimmed ones
+// The dry run flags them with "!"
+func printSkippedPeriods(skipped []SkippedPeriod, dryRun bool) {
+	for _, s := range skipped {
+		if s.duplicate {
+			continue
+		}
+		prefix := "Skipping "
+		switch {
+		case dryRun:
+			prefix = "! "
+		case len(s.kept) != 0:
+			prefix = "Trimming "
+		}
+		fmt.Printf("%s%s: overlaps %s", prefix, formatPeriod(s.period), formatPeriod(s.overlapped))
+		for i, part := range s.kept {
+			separator := ", "
+			if i == 0 {
+				separator = " (keeping "
+			}
+			fmt.Printf("%s%s - %s", separator, part.start.Format(CLOCK_FORMAT), part.end.Format(CLOCK_FORMAT))
+		}
+		if len(s.kept) != 0 {
+			fmt.Print(")")
+		}
+		fmt.Println()
+	}
+}
+
+func countOverlapping(skipped []SkippedPeriod) (count int) {
+	for _, s := range skipped {
+		if !s.duplicate {
+			count++
+		}
+	}
+	return
+}
+
+// Returns the first interval overlapping [start, end[, if any
+func findOverlap(intervals []Interval, start time.Time, end time.Time) (Interval, bool) {
+	for _, in := range intervals {
+		if in.start.Before(end) && start.Before(in.end) {
+			return in, true
+		}
+	}
+	return Interval{}, false
+}
+
+// Converts completed periods to records: an entry at their start and a STOP at their end
+// Periods overlapping the existing intervals (or each other) and periods already recorded are skipped,
+// or with trim, the overlapping periods are imported as their parts outside of the intervals
+// The STOP is omitted when an existing entry starts right at the end of the period
+func convertPeriods(periods []Interval, records []Record, trim bool) (imported []Record, skipped []SkippedPeriod) {
+	intervals := computeIntervals(records)
+	starts := make(map[time.Time]bool)
+	for _, r := range records {
+		starts[r.timestamp] = true
+	}
+	now := getNow()
+
+	for _, p := range periods {
+		if !p.start.Before(p.end) || p.end.After(now) {
+			continue
+		}
+		parts := []Interval{p}
+		if overlapped, found := findOverlap(intervals, p.start, p.end); found {
+			duplicate := overlapped.start.Equal(p.start) && overlapped.end.Equal(p.end) && overlapped.title == p.title
+			if parts = nil; trim && !duplicate {
+				parts = trimPeriod(p, intervals)
+			}
+			skipped = append(skipped, SkippedPeriod{p, overlapped, duplicate, parts})
+		}
+
+		for _, part := range parts {
+			imported = append(imported, Record{part.start, part.title})
+			if !starts[part.end] {
+				imported = append(imported, Record{part.end, STOP_TOKEN})
+			}
+			starts[part.start] = true
+			intervals = append(intervals, part)
+		}
+	}
+	return
+}
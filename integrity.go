@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const CHAIN_NAME = ".mate.chain"
+
+func isIntegrityEnabled() bool {
+	return getConfigBool("integrity.enabled", false)
+}
+
+func getChainPath() string {
+	return getHomeFilePath(CHAIN_NAME)
+}
+
+// Computes the hash of every entry, chained to the hash of the previous entry
+// With integrity.key (e.g. stored with "mate secret set integrity.key"), the hashes are HMACs that cannot be
+// recomputed without the key
+func computeChain(records []Record) (chain []string) {
+	previous := ""
+	for _, r := range records {
+		mac := hmac.New(sha256.New, []byte(getConfig("integrity.key", "")))
+		mac.Write([]byte(previous))
+		mac.Write([]byte(formatRecord(r)))
+		previous = hex.EncodeToString(mac.Sum(nil))
+		chain = append(chain, previous)
+	}
+	return
+}
+
+// Reads the hashes of the entries, or false if the database was never sealed
+func readChain() ([]string, bool, error) {
+	content, err := files.ReadFile(getChainPath())
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("Cannot read the seal of the database: %w", err)
+	}
+	return strings.Fields(string(content)), true, nil
+}
+
+func writeChain(chain []string) error {
+	content := strings.Join(chain, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	if err := files.WriteFile(getChainPath(), []byte(content), 0644); err != nil {
+		return fmt.Errorf("Cannot seal the database: %w", err)
+	}
+	return nil
+}
+
+// Compares the entries to their hashes, describing the first difference
+func verifyChain(records []Record, chain []string) (string, bool) {
+	computed := computeChain(records)
+	for i := 0; i < len(computed) && i < len(chain); i++ {
+		if computed[i] != chain[i] {
+			return fmt.Sprintf("Entry %d (%s %s) was modified, or an entry before it was removed",
+				i+1, records[i].timestamp.Format(TIME_FORMAT), getEntryName(records[i])), false
+		}
+	}
+	switch {
+	case len(records) < len(chain):
+		return fmt.Sprintf("The database was truncated: %d entries missing at its end", len(chain)-len(records)), false
+	case len(records) > len(chain):
+		return fmt.Sprintf("%d entries were added outside of mate, from %s", len(records)-len(chain),
+			records[len(chain)].timestamp.Format(TIME_FORMAT)), false
+	}
+	return "", true
+}
+
+// Seals the new version of the database, as written by mate
+// If the previous version does not match the seal, it was modified outside of mate: the seal is kept as is,
+// for mate verify to report it
+func updateChain(previous []Record, records []Record) error {
+	if !isIntegrityEnabled() {
+		return nil
+	}
+	chain, sealed, err := readChain()
+	if err != nil {
+		return err
+	}
+	if sealed {
+		if _, ok := verifyChain(previous, chain); !ok {
+			fmt.Println("Warning: the database was modified outside of mate, run mate verify")
+			return nil
+		}
+	}
+	return writeChain(computeChain(records))
+}
+
+// Reports any modification of the database made outside of mate since it was sealed
+// With reseal, the database is sealed as it is
+func verifyDatabase(reseal bool) error {
+	if !isIntegrityEnabled() {
+		return fmt.Errorf("Integrity checks are disabled, set integrity.enabled in %s", getConfigPath())
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	if reseal {
+		if err = writeChain(computeChain(records)); err != nil {
+			return err
+		}
+		fmt.Printf("Database sealed (%d entries)\n", len(records))
+		return nil
+	}
+
+	chain, sealed, err := readChain()
+	if err != nil {
+		return err
+	}
+	if !sealed {
+		return errors.New("The database is not sealed yet, run mate verify --reseal")
+	}
+	if problem, ok := verifyChain(records, chain); !ok {
+		return errors.New(problem + "\nOnce checked, run mate verify --reseal to accept the database as it is")
+	}
+	fmt.Printf("The database is intact (%d entries)\n", len(records))
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Jira issue keys anywhere in a title, such as PROJ-123 in "Review PROJ-123"
+var JIRA_KEY_PATTERN = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-[0-9]+\b`)
+
+// Returns the authentication headers of jira.url: Basic with jira.email (Jira Cloud), else Bearer (personal access token)
+func getJiraHeaders() (map[string]string, error) {
+	token, err := getRequiredConfig("jira.token", "an API token, or a personal access token")
+	if err != nil {
+		return nil, err
+	}
+	if email := getConfig("jira.email", ""); email != "" {
+		return map[string]string{"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(email+":"+token))}, nil
+	}
+	return map[string]string{"Authorization": "Bearer " + token}, nil
+}
+
+// Calls an endpoint of the Jira REST API of jira.url
+func callJiraAPI(method string, path string, body interface{}, result interface{}) error {
+	address, err := getRequiredConfig("jira.url", "e.g. https://example.atlassian.net")
+	if err != nil {
+		return err
+	}
+	headers, err := getJiraHeaders()
+	if err != nil {
+		return err
+	}
+	return callJSONAPI(method, strings.TrimRight(address, "/")+path, headers, body, result)
+}
+
+// Posts an interval as a worklog of the Jira issue of its title
+func pushToJira(in Interval) (string, error) {
+	issue := JIRA_KEY_PATTERN.FindString(in.title)
+	worklog := map[string]interface{}{
+		"started":          fromDbTime(in.start).Format("2006-01-02T15:04:05.000-0700"),
+		"timeSpentSeconds": int64(in.end.Sub(in.start).Round(time.Minute).Seconds()),
+		"comment":          in.title,
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	err := callJiraAPI("POST", "/rest/api/2/issue/"+issue+"/worklog", worklog, &created)
+	return created.ID, err
+}
+
+// Fetches the summary of a Jira issue
+func fetchJiraSummary(issue string) (string, error) {
+	var fetched struct {
+		Fields struct {
+			Summary string `json:"summary"`
+		} `json:"fields"`
+	}
+	err := callJiraAPI("GET", "/rest/api/2/issue/"+url.PathEscape(issue)+"?fields=summary", nil, &fetched)
+	return strings.TrimSpace(fetched.Fields.Summary), err
+}
+
+// Completes a title made of a Jira issue key only with the summary of the issue (e.g. "PROJ-123 Fix login timeout"),
+// when jira.url and jira.token are set
+// If Jira cannot be reached, the last title of the issue in the database is reused
+func expandJiraTitle(title string, records []Record) string {
+	if !JIRA_KEY_PATTERN.MatchString(title) || JIRA_KEY_PATTERN.FindString(title) != title ||
+		getConfig("jira.url", "") == "" || getConfig("jira.token", "") == "" {
+		return title
+	}
+
+	summary, err := fetchJiraSummary(title)
+	if err == nil && summary != "" {
+		return title + " " + summary
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if strings.HasPrefix(records[i].title, title+" ") {
+			return records[i].title
+		}
+	}
+	if err != nil {
+		fmt.Printf("Cannot fetch the summary of %s: %v\n", title, err)
+	}
+	return title
+}
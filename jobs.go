@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The directory of the copies of the database made by the backup job, in the home directory or the profile
+const BACKUPS_NAME = ".mate.backups"
+
+const DEFAULT_KEPT_BACKUPS = "30"
+
+// When a job of the daemon runs: at a time of the day, on some days of the week (every day when empty)
+type JobSchedule struct {
+	weekdays []time.Weekday
+	at       time.Duration
+}
+
+// A job the daemon runs on the schedule of jobs.<name>
+type Job struct {
+	name string
+	run  func(day time.Time) error
+}
+
+// The jobs of the daemon, run on the days and times of the [jobs] section:
+//
+//	[jobs]
+//	weekly_report = "FRI 17:00"
+//	daily_post = "MON,TUE,WED,THU,FRI 18:00"
+//	backup = "12:30"
+var JOBS = []Job{
+	{"weekly_report", runWeeklyReportJob},
+	{"daily_post", runDailyPostJob},
+	{"backup", runBackupJob},
+}
+
+// Parses the schedule of a job, e.g. "FRI 17:00", "mon,thu 09:30" or "12:30" for every day
+func parseJobSchedule(literal string) (schedule JobSchedule, err error) {
+	fields := strings.Fields(literal)
+	if len(fields) == 0 || len(fields) > 2 {
+		return schedule, errors.New("Invalid schedule \"" + literal + "\" (expected e.g. \"FRI 17:00\" or \"12:30\")")
+	}
+	if len(fields) == 2 {
+		if schedule.weekdays, err = parseWeekdays(fields[0]); err != nil {
+			return
+		}
+	}
+	schedule.at, err = parseClock(fields[len(fields)-1])
+	return
+}
+
+// Tells if the job runs on the given day
+func (schedule JobSchedule) runsOn(day time.Time) bool {
+	if len(schedule.weekdays) == 0 {
+		return true
+	}
+	for _, weekday := range schedule.weekdays {
+		if weekday == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// Runs the jobs whose time of the day is past, once per day
+// A job missed while the daemon was not running is run late the same day, not on the next days,
+// and a failed job is not retried, its error being printed for the other jobs to run
+func checkScheduledJobs() error {
+	now := getNow()
+	today := now.Truncate(time.Hour * 24)
+	for _, job := range JOBS {
+		key := "jobs." + job.name
+		if getConfig(key, "") == "" {
+			continue
+		}
+		schedule := getTypedConfig(key, "job schedule", "").(JobSchedule)
+		if !schedule.runsOn(today) || now.Before(today.Add(schedule.at)) {
+			continue
+		}
+		if marked, err := markNotified("job " + job.name + " " + today.Format(DATE_FORMAT)); !marked {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Printf("%s Running the %s job\n", now.Format(CLOCK_FORMAT), job.name)
+		if err := job.run(today); err != nil {
+			reportDaemonError(fmt.Errorf("The %s job failed: %w", job.name, err))
+		}
+	}
+	return nil
+}
+
+// Emails the report of the week to email.to, unless nothing was tracked
+func runWeeklyReportJob(day time.Time) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	report := computeWeekReport(records, getWeekStart(day))
+	if report.total == 0 {
+		fmt.Println("Nothing tracked this week, no report sent")
+		return nil
+	}
+	return sendWeekReport(report, "")
+}
+
+// Posts the summary of the day to Slack or Mattermost, unless nothing was tracked
+func runDailyPostJob(day time.Time) error {
+	records, err := getRecentRecords(day)
+	if err != nil {
+		return err
+	}
+	if formatDaySummary(records, day) == "" {
+		fmt.Println("Nothing tracked today, no summary posted")
+		return nil
+	}
+	return postSummary(day.Format(DATE_FORMAT), "")
+}
+
+// Copies the database to the backups directory (jobs.backup_dir), then removes the oldest copies
+// beyond jobs.kept_backups
+// The file is copied as it is, an encrypted database staying encrypted
+func runBackupJob(day time.Time) error {
+	content, err := files.ReadFile(getDbPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return newDatabaseError("read", err)
+	}
+	directory := getConfig("jobs.backup_dir", getHomeFilePath(BACKUPS_NAME))
+	if err = os.MkdirAll(directory, 0700); err != nil {
+		return fmt.Errorf("Cannot create the backups directory: %w", err)
+	}
+	path := filepath.Join(directory, "mate-"+day.Format("2006-01-02")+".csv")
+	if err = os.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("Cannot write the backup: %w", err)
+	}
+	fmt.Printf("Database saved to %s\n", path)
+	return rotateBackups(directory, getConfigInt("jobs.kept_backups", DEFAULT_KEPT_BACKUPS))
+}
+
+// Removes the oldest backups of the directory, keeping the given number of them (all of them with 0)
+func rotateBackups(directory string, kept int) error {
+	if kept == 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(filepath.Join(directory, "mate-*.csv"))
+	if err != nil {
+		return err
+	}
+	// The dates of the names sort in the order of the days
+	sort.Strings(backups)
+	for len(backups) > kept {
+		if err = os.Remove(backups[0]); err != nil {
+			return fmt.Errorf("Cannot remove an old backup: %w", err)
+		}
+		debugf("Removed the backup %s", backups[0])
+		backups = backups[1:]
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseJobSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		literal  string
+		wantErr  bool
+		weekdays []time.Weekday
+		at       time.Duration
+	}{
+		{"every day", "12:30", false, nil, 12*time.Hour + 30*time.Minute},
+		{"one day", "FRI 17:00", false, []time.Weekday{time.Friday}, 17 * time.Hour},
+		{"several days", "mon,thu 09:30", false, []time.Weekday{time.Monday, time.Thursday}, 9*time.Hour + 30*time.Minute},
+		{"full day names", "Monday,Friday 18:00", false, []time.Weekday{time.Monday, time.Friday}, 18 * time.Hour},
+		{"empty", "", true, nil, 0},
+		{"too many fields", "FRI 17:00 weekly", true, nil, 0},
+		{"unknown day", "FRY 17:00", true, nil, 0},
+		{"invalid time", "FRI 5pm", true, nil, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseJobSchedule(test.literal)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if !equalWeekdays(got.weekdays, test.weekdays) || got.at != test.at {
+				t.Errorf("got %v at %v, want %v at %v", got.weekdays, got.at, test.weekdays, test.at)
+			}
+		})
+	}
+}
+
+func TestJobScheduleRunsOn(t *testing.T) {
+	// 2026/10/14 is a Wednesday
+	wednesday := parseTestTime(t, "2026/10/14 00:00:00")
+	tests := []struct {
+		name     string
+		weekdays []time.Weekday
+		want     bool
+	}{
+		{"every day", nil, true},
+		{"on the day", []time.Weekday{time.Monday, time.Wednesday}, true},
+		{"on other days", []time.Weekday{time.Friday}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := (JobSchedule{weekdays: test.weekdays}).runsOn(wednesday); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
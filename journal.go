@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Prints the day as a Markdown narrative: the entries in order, each with its notes, and the breaks between them
+func showJournal(date string) error {
+	day, err := parseDate(date)
+	if err != nil {
+		return err
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	notes, err := readNotes()
+	if err != nil {
+		return err
+	}
+	intervals := clipIntervalsToDay(computeIntervals(records), day)
+	notes = getNotesBetween(notes, day, day.AddDate(0, 0, 1))
+	if len(intervals) == 0 && len(notes) == 0 {
+		fmt.Printf("Nothing to show for %s\n", day.Format(DATE_FORMAT))
+		return nil
+	}
+	fmt.Print(formatJournal(day, intervals, notes))
+	return nil
+}
+
+// Formats the journal of a day, a note going with the last interval started before it
+func formatJournal(day time.Time, intervals []Interval, notes []Note) string {
+	var journal strings.Builder
+	fmt.Fprintf(&journal, "# %s\n\n", day.Format("Monday, January 2 2006"))
+
+	n := 0
+	for n < len(notes) && (len(intervals) == 0 || notes[n].at.Before(intervals[0].start)) {
+		fmt.Fprintf(&journal, "- %s %s\n", notes[n].at.Format(CLOCK_FORMAT), notes[n].text)
+		n++
+	}
+	var total time.Duration
+	for i, in := range intervals {
+		if i != 0 {
+			if pause := in.start.Sub(intervals[i-1].end); pause >= time.Minute {
+				fmt.Fprintf(&journal, "- *%s–%s Break (%s)*\n",
+					intervals[i-1].end.Format(CLOCK_FORMAT), in.start.Format(CLOCK_FORMAT), formatMinutes(pause))
+			}
+		}
+		fmt.Fprintf(&journal, "- **%s–%s** %s (%s)\n",
+			in.start.Format(CLOCK_FORMAT), in.end.Format(CLOCK_FORMAT), in.title, formatMinutes(in.end.Sub(in.start)))
+		for n < len(notes) && (i+1 == len(intervals) || notes[n].at.Before(intervals[i+1].start)) {
+			fmt.Fprintf(&journal, "  - %s %s\n", notes[n].at.Format(CLOCK_FORMAT), notes[n].text)
+			n++
+		}
+		total += in.end.Sub(in.start)
+	}
+
+	if len(intervals) != 0 {
+		journal.WriteString("\n")
+		if deduction := computeBreakDeduction(intervals); deduction != 0 {
+			fmt.Fprintf(&journal, "Worked %s (%s of break deducted)\n", formatMinutes(total-deduction), formatMinutes(deduction))
+		} else {
+			fmt.Fprintf(&journal, "Worked %s\n", formatMinutes(total))
+		}
+	}
+	return journal.String()
+}
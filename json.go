@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const JSON_VERSION = 1
+const JSON_TIME_FORMAT = "2006-01-02T15:04:05"
+
+// The JSON export, a lossless copy of the database and of its side files:
+//
+//	{
+//	  "version": 1,
+//	  "entries": [
+//	    {"timestamp": "2024-05-02T09:00:00", "title": "PROJ-12 Fix login"},
+//	    {"timestamp": "2024-05-02T12:30:00", "stop": true}
+//	  ],
+//	  "days_off": {"2024-05-10": "vacation"},
+//	  "budgets": {"PROJ-12 Fix login": "8h0m0s"},
+//	  "clients": {"PROJ-12 Fix login": "Acme"},
+//	  "urls": {"PROJ-12 Fix login": "https://jira.example.com/browse/PROJ-12"},
+//	  "notes": [{"at": "2024-05-02T10:15:00", "note": "Found the race condition"}]
+//	}
+//
+// Timestamps are wall clock times, without time zone
+// Entries are in chronological order; a stop entry ends the previous one
+type JSONExport struct {
+	Version int               `json:"version"`
+	Entries []JSONEntry       `json:"entries"`
+	DaysOff map[string]string `json:"days_off,omitempty"`
+	Budgets map[string]string `json:"budgets,omitempty"`
+	Clients map[string]string `json:"clients,omitempty"`
+	URLs    map[string]string `json:"urls,omitempty"`
+	Notes   []JSONNote        `json:"notes,omitempty"`
+}
+
+type JSONNote struct {
+	At   string `json:"at"`
+	Note string `json:"note"`
+}
+
+type JSONEntry struct {
+	Timestamp string `json:"timestamp"`
+	Title     string `json:"title,omitempty"`
+	Stop      bool   `json:"stop,omitempty"`
+}
+
+// Exports the records within [start, end[ (zero times meaning no bound), with the side files, as JSON
+func exportJSON(w io.Writer, records []Record, start time.Time, end time.Time) error {
+	export := JSONExport{Version: JSON_VERSION, Entries: []JSONEntry{}}
+
+	for _, r := range records {
+		if !start.IsZero() && r.timestamp.Before(start) || !end.IsZero() && !r.timestamp.Before(end) {
+			continue
+		}
+		entry := JSONEntry{Timestamp: r.timestamp.Format(JSON_TIME_FORMAT), Title: r.title}
+		if r.title == STOP_TOKEN {
+			entry = JSONEntry{Timestamp: entry.Timestamp, Stop: true}
+		}
+		export.Entries = append(export.Entries, entry)
+	}
+
+	daysOff, err := readDaysOff()
+	if err != nil {
+		return err
+	}
+	export.DaysOff = make(map[string]string)
+	for date, offType := range daysOff {
+		if day, err := time.Parse(DATE_FORMAT, date); err == nil {
+			export.DaysOff[day.Format("2006-01-02")] = offType
+		}
+	}
+	if export.Budgets, err = readTable(getBudgetsPath()); err != nil {
+		return err
+	}
+	if export.Clients, err = readTable(getClientsPath()); err != nil {
+		return err
+	}
+	if export.URLs, err = readTable(getURLsPath()); err != nil {
+		return err
+	}
+	notes, err := readNotes()
+	if err != nil {
+		return err
+	}
+	for _, n := range notes {
+		if !start.IsZero() && n.at.Before(start) || !end.IsZero() && !n.at.Before(end) {
+			continue
+		}
+		export.Notes = append(export.Notes, JSONNote{n.at.Format(JSON_TIME_FORMAT), n.text})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+// Returns the records of the entries of a JSON export
+func getJSONRecords(export JSONExport) (records []Record, err error) {
+	if export.Version != JSON_VERSION {
+		return nil, fmt.Errorf("unsupported version %d (expected %d)", export.Version, JSON_VERSION)
+	}
+	for i, entry := range export.Entries {
+		timestamp, err := time.Parse(JSON_TIME_FORMAT, entry.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: invalid timestamp \"%s\"", i+1, entry.Timestamp)
+		}
+		title := entry.Title
+		if entry.Stop {
+			title = STOP_TOKEN
+		} else if title == "" {
+			return nil, fmt.Errorf("entry %d: missing title", i+1)
+		}
+		records = append(records, Record{timestamp, title})
+	}
+	return records, nil
+}
+
+// Reads a JSON export into records, and merges its side files into the current ones (unless dryRun)
+func importJSON(r io.Reader, dryRun bool) (records []Record, err error) {
+	var export JSONExport
+	if err = json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, err
+	}
+	if records, err = getJSONRecords(export); err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return records, nil
+	}
+
+	daysOff := make(map[string]string)
+	for date, offType := range export.DaysOff {
+		if day, err := time.Parse("2006-01-02", date); err == nil {
+			daysOff[day.Format(DATE_FORMAT)] = offType
+		}
+	}
+	if err = mergeTable(getOffPath(), OFF_CSV_HEADER, daysOff); err != nil {
+		return nil, err
+	}
+	if err = mergeTable(getBudgetsPath(), BUDGETS_CSV_HEADER, export.Budgets); err != nil {
+		return nil, err
+	}
+	if err = mergeTable(getClientsPath(), CLIENTS_CSV_HEADER, export.Clients); err != nil {
+		return nil, err
+	}
+	if err = mergeTable(getURLsPath(), URLS_CSV_HEADER, export.URLs); err != nil {
+		return nil, err
+	}
+	var notes []Note
+	for i, note := range export.Notes {
+		at, err := time.Parse(JSON_TIME_FORMAT, note.At)
+		if err != nil {
+			return nil, fmt.Errorf("note %d: invalid timestamp \"%s\"", i+1, note.At)
+		}
+		notes = append(notes, Note{at, note.Note})
+	}
+	if err = mergeNotes(notes); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// Adds rows to a two-column CSV, replacing the ones of the same key
+func mergeTable(path string, header string, rows map[string]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	table, err := readTable(path)
+	if err != nil {
+		return err
+	}
+	for key, value := range rows {
+		table[key] = value
+	}
+	return writeTable(path, header, table)
+}
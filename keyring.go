@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const KEYRING_SERVICE = "mate"
+
+// Lists the config keys stored in the keyring, so that the keyring is only queried for them
+const SECRETS_NAME = ".mate.secrets"
+
+// Values read from the keyring, by config key
+var secrets = map[string]string{}
+
+// PowerShell script reaching the Windows Credential Manager through the PasswordVault API
+// The key and the value are passed in MATE_SECRET_KEY and MATE_SECRET_VALUE
+const WINDOWS_VAULT_SCRIPT = `[void][Windows.Security.Credentials.PasswordVault,Windows.Security.Credentials,ContentType=WindowsRuntime]
+$vault = New-Object Windows.Security.Credentials.PasswordVault
+switch ($args[0]) {
+	'get' { $c = $vault.Retrieve('mate', $env:MATE_SECRET_KEY); $c.RetrievePassword(); Write-Output $c.Password }
+	'set' { $vault.Add((New-Object Windows.Security.Credentials.PasswordCredential('mate', $env:MATE_SECRET_KEY, $env:MATE_SECRET_VALUE))) }
+	'delete' { $vault.Remove($vault.Retrieve('mate', $env:MATE_SECRET_KEY)) }
+}`
+
+// Returns the account of a config key in the keyring, the profile being part of it
+func getKeyringAccount(key string) string {
+	if profile := getProfile(); profile != DEFAULT_PROFILE {
+		return profile + "/" + key
+	}
+	return key
+}
+
+// Returns the command driving the keyring of the OS: the macOS keychain, the Secret Service on Linux
+// (through secret-tool) or the Windows Credential Manager
+func getKeyringCommand(action string, key string, value string) (*exec.Cmd, error) {
+	account := getKeyringAccount(key)
+	switch runtime.GOOS {
+	case "darwin":
+		switch action {
+		case "get":
+			return exec.Command("security", "find-generic-password", "-s", KEYRING_SERVICE, "-a", account, "-w"), nil
+		case "set":
+			// -w given last without a value reads the value (typed twice) from stdin, not to show it in the arguments of the process
+			cmd := exec.Command("security", "add-generic-password", "-U", "-s", KEYRING_SERVICE, "-a", account, "-w")
+			cmd.Stdin = strings.NewReader(value + "\n" + value + "\n")
+			return cmd, nil
+		default:
+			return exec.Command("security", "delete-generic-password", "-s", KEYRING_SERVICE, "-a", account), nil
+		}
+	case "windows":
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", "& {"+WINDOWS_VAULT_SCRIPT+"}", action)
+		cmd.Env = append(os.Environ(), "MATE_SECRET_KEY="+account, "MATE_SECRET_VALUE="+value)
+		return cmd, nil
+	}
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil, errors.New("secret-tool not found (install libsecret-tools)")
+	}
+	switch action {
+	case "get":
+		return exec.Command("secret-tool", "lookup", "service", KEYRING_SERVICE, "account", account), nil
+	case "set":
+		cmd := exec.Command("secret-tool", "store", "--label", "mate "+account, "service", KEYRING_SERVICE, "account", account)
+		cmd.Stdin = strings.NewReader(value)
+		return cmd, nil
+	default:
+		return exec.Command("secret-tool", "clear", "service", KEYRING_SERVICE, "account", account), nil
+	}
+}
+
+func runKeyring(action string, key string, value string) (string, error) {
+	cmd, err := getKeyringCommand(action, key, value)
+	if err != nil {
+		return "", err
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", cmd.Args[0], err)
+	}
+	return strings.TrimRight(string(output), "\r\n"), nil
+}
+
+// The secrets being config values, the users of mate serve share the ones of the owner of the server
+func getSecretsPath() string {
+	return getProfilePath(getProfile()) + "/" + SECRETS_NAME
+}
+
+// Returns the config keys stored in the keyring
+func listSecretKeys() (keys []string) {
+	content, err := files.ReadFile(getSecretsPath())
+	if err != nil {
+		return nil
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if key := strings.TrimSpace(line); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return
+}
+
+func writeSecretKeys(keys []string) error {
+	sort.Strings(keys)
+	content := strings.Join(keys, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	if err := files.WriteFile(getSecretsPath(), []byte(content), 0644); err != nil {
+		return fmt.Errorf("Cannot write %s: %w", getSecretsPath(), err)
+	}
+	return nil
+}
+
+// Returns the value of a config key stored in the keyring, if any
+// A keyring that cannot be read is reported once, and the key considered unset
+func getSecret(key string) (string, bool) {
+	if value, found := secrets[key]; found {
+		return value, value != ""
+	}
+	secrets[key] = ""
+	if !contains(listSecretKeys(), key) {
+		return "", false
+	}
+	value, err := runKeyring("get", key, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot read %s from the keyring: %v\n", key, err)
+		return "", false
+	}
+	secrets[key] = value
+	return value, value != ""
+}
+
+// Stores the value of a config key in the keyring, typed on the terminal
+// The key is then read from the keyring whenever it is missing from the config file
+func setSecret(key string) error {
+	value, err := readSecret(fmt.Sprintf("Value of %s: ", key))
+	if err == nil && value == "" {
+		err = errors.New("empty value")
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot read the value: %w", err)
+	}
+	if _, err = parseTypedValue(getConfigType(key), value); err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	if _, err = runKeyring("set", key, value); err != nil {
+		return fmt.Errorf("Cannot write to the keyring: %w", err)
+	}
+
+	if keys := listSecretKeys(); !contains(keys, key) {
+		if err = writeSecretKeys(append(keys, key)); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%s stored in the keyring\n", key)
+	if _, inFile := config[key]; inFile {
+		fmt.Printf("Remove it from %s for the keyring to be used\n", getConfigPath())
+	}
+	return nil
+}
+
+func deleteSecret(key string) error {
+	keys := listSecretKeys()
+	if !contains(keys, key) {
+		return fmt.Errorf("%s is not in the keyring", key)
+	}
+	if _, err := runKeyring("delete", key, ""); err != nil {
+		return fmt.Errorf("Cannot delete from the keyring: %w", err)
+	}
+
+	var remaining []string
+	for _, k := range keys {
+		if k != key {
+			remaining = append(remaining, k)
+		}
+	}
+	if err := writeSecretKeys(remaining); err != nil {
+		return err
+	}
+	fmt.Printf("%s deleted from the keyring\n", key)
+	return nil
+}
+
+func showSecrets() {
+	keys := listSecretKeys()
+	if len(keys) == 0 {
+		fmt.Println("No secret in the keyring. Run:\n$ mate secret set jira.token")
+		return
+	}
+	for _, key := range keys {
+		fmt.Println(key)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Warns when the time worked today or this week exceeds the limits of the [limits] section:
+//
+//	[limits]
+//	day = "10h"
+//	week = "48h"
+//
+// Limits are disabled by default
+func warnAboutLimits(records []Record) {
+	dayLimit := getConfigDuration("limits.day", "0")
+	weekLimit := getConfigDuration("limits.week", "0")
+	if dayLimit == 0 && weekLimit == 0 {
+		return
+	}
+
+	today := getNow().Truncate(time.Hour * 24)
+	totals := computeTotalsPerDay(records)
+
+	if dayTotal := totals[today.Format(DATE_FORMAT)]; dayLimit != 0 && dayTotal > dayLimit {
+		fmt.Printf("Warning: %v worked today, over the %v limit\n", dayTotal, dayLimit)
+	}
+
+	var weekTotal time.Duration
+	for day := getWeekStart(today); !day.After(today); day = day.AddDate(0, 0, 1) {
+		weekTotal += totals[day.Format(DATE_FORMAT)]
+	}
+	if weekLimit != 0 && weekTotal > weekLimit {
+		fmt.Printf("Warning: %v worked this week, over the %v limit\n", weekTotal, weekLimit)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const LIVE_CHECK_INTERVAL = time.Second
+
+// Checked at least this often, as the running ticket may stop at auto_stop without any write
+const LIVE_REFRESH_INTERVAL = time.Minute
+
+// The status message sent to the WebSocket clients
+type StatusMessage struct {
+	Type string `json:"type"`
+	StatusResponse
+}
+
+// Sends the status to the WebSocket clients whenever the running ticket changes,
+// be it through the API or through the CLI writing the database
+type StatusHub struct {
+	// The user whose database is watched, empty for the owner of the server
+	user    string
+	lock    sync.Mutex
+	clients map[*WebSocket]bool
+}
+
+func newStatusHub(user string) *StatusHub {
+	return &StatusHub{user: user, clients: make(map[*WebSocket]bool)}
+}
+
+// Returns the status message and the part of it that only changes with the running ticket
+func (h *StatusHub) getMessage() (message []byte, state string, err error) {
+	var status StatusResponse
+	serverLock.Lock()
+	err = runAsUser(h.user, func() error {
+		if err := reconcileTimer(); err != nil {
+			return err
+		}
+		records, err := getRecords()
+		status = getStatus(records)
+		return err
+	})
+	serverLock.Unlock()
+	if err != nil {
+		return nil, "", err
+	}
+
+	message, _ = json.Marshal(StatusMessage{"status", status})
+	stateJSON, _ := json.Marshal([]interface{}{status.Running, status.Title, status.Since, status.TargetSeconds})
+	return message, string(stateJSON), nil
+}
+
+// Checks the database for changes, without reading it unless it was modified
+func (h *StatusHub) watch() {
+	var modified time.Time
+	var size int64
+	var state string
+	lastRefresh := time.Now()
+
+	path := getUserFilesPath(h.user) + "/" + DB_NAME
+	for range time.Tick(LIVE_CHECK_INTERVAL) {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().Equal(modified) && info.Size() == size && time.Since(lastRefresh) < LIVE_REFRESH_INTERVAL {
+			continue
+		}
+		modified, size, lastRefresh = info.ModTime(), info.Size(), time.Now()
+
+		message, newState, err := h.getMessage()
+		if err == nil && newState != state {
+			state = newState
+			h.broadcast(message)
+		}
+	}
+}
+
+func (h *StatusHub) broadcast(message []byte) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for client := range h.clients {
+		if client.writeText(message) != nil {
+			client.close()
+			delete(h.clients, client)
+		}
+	}
+}
+
+// Upgrades a request to a WebSocket, sends it the current status, and keeps it until it is closed
+func (h *StatusHub) serve(w http.ResponseWriter, r *http.Request) {
+	ws, err := acceptWebSocket(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	message, _, err := h.getMessage()
+	if err != nil || ws.writeText(message) != nil {
+		ws.close()
+		return
+	}
+
+	h.lock.Lock()
+	h.clients[ws] = true
+	h.lock.Unlock()
+
+	ws.readUntilClosed()
+
+	h.lock.Lock()
+	delete(h.clients, ws)
+	h.lock.Unlock()
+	ws.close()
+}
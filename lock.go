@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const LOCK_NAME = ".mate.lock"
+
+// Set by --force, to change locked entries anyway
+var forceOption bool
+
+func getLockPath() string {
+	return getHomeFilePath(LOCK_NAME)
+}
+
+// Returns the last locked day, or false if nothing is locked
+func getLockedUntil() (time.Time, bool, error) {
+	content, err := files.ReadFile(getLockPath())
+	if os.IsNotExist(err) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("Cannot read the lock: %w", err)
+	}
+	day, err := time.Parse(DATE_FORMAT, strings.TrimSpace(string(content)))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%s: %w", getLockPath(), err)
+	}
+	return day, true, nil
+}
+
+// Refuses the changes of entries of locked days, unless --force is given
+func checkLockedChanges(changed []Record) error {
+	lockedUntil, locked, err := getLockedUntil()
+	if err != nil || !locked || forceOption {
+		return err
+	}
+	end := lockedUntil.AddDate(0, 0, 1)
+	for _, r := range changed {
+		if r.timestamp.Before(end) {
+			return withExitCode(EXIT_LOCKED, fmt.Errorf("Entries up to %s are locked, %s %s cannot be changed (use --force to change it anyway)",
+				lockedUntil.Format(DATE_FORMAT), r.timestamp.Format(TIME_FORMAT), getEntryName(r)))
+		}
+	}
+	return nil
+}
+
+// Locks the entries up to the given day included, e.g. once submitted on a timesheet
+// Moving the lock backwards requires --force
+func lockEntries(until string) error {
+	lockedUntil, locked, err := getLockedUntil()
+	if err != nil {
+		return err
+	}
+	if until == "" {
+		if locked {
+			fmt.Printf("Entries up to %s are locked\n", lockedUntil.Format(DATE_FORMAT))
+		} else {
+			fmt.Println("No entry is locked. Run:\n$ mate lock --until 2024/05/31")
+		}
+		return nil
+	}
+
+	day, err := parseDate(until)
+	if err != nil {
+		return err
+	}
+	if locked && day.Before(lockedUntil) && !forceOption {
+		return fmt.Errorf("Entries up to %s are already locked (use --force to unlock the days after %s)",
+			lockedUntil.Format(DATE_FORMAT), day.Format(DATE_FORMAT))
+	}
+	if err = files.WriteFile(getLockPath(), []byte(day.Format(DATE_FORMAT)+"\n"), 0644); err != nil {
+		return fmt.Errorf("Cannot write the lock: %w", err)
+	}
+	fmt.Printf("Entries up to %s locked\n", day.Format(DATE_FORMAT))
+	return nil
+}
+
+// Removes the lock, which requires --force
+func unlockEntries() error {
+	_, locked, err := getLockedUntil()
+	if err != nil {
+		return err
+	}
+	if !locked {
+		fmt.Println("No entry is locked")
+		return nil
+	}
+	if !forceOption {
+		return errors.New("Unlocking allows changing submitted entries, run:\n$ mate unlock --force")
+	}
+	if err = files.Remove(getLockPath()); err != nil {
+		return fmt.Errorf("Cannot remove the lock: %w", err)
+	}
+	fmt.Println("Entries unlocked")
+	return nil
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
+	"sort"
 	"strings"
 	"time"
 )
@@ -15,61 +17,190 @@ const TIME_FORMAT = "2006/01/02 15:04:05"
 const STOP_TOKEN = "mate:STOP"
 const DB_NAME = ".mate.csv"
 const CSV_HEADER = "timestamp,title\n"
-const WORK_DAY = time.Hour*7 + time.Minute*30
 
 type Record struct {
 	timestamp time.Time
 	title     string
 }
 
+// Returns the current time, truncated to the precision of the database
+// Timestamps are stored as wall clock time, so they are compared as such
+func getNow() time.Time {
+	return toDbTime(clock.Now())
+}
+
+// Converts a time to the wall clock time of the database
+func toDbTime(t time.Time) time.Time {
+	dbTime, _ := time.Parse(TIME_FORMAT, t.Format(TIME_FORMAT))
+	return dbTime
+}
+
+// Converts a wall clock time of the database to the actual local time
+func fromDbTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+}
+
+func getHomePath() string {
+	return os.Getenv("HOME")
+}
+
+// Returns the path of a file of the home directory, or of the directory of the current profile
+// (or of the user of the request served by mate serve)
+func getHomeFilePath(name string) string {
+	var path strings.Builder
+	path.WriteString(getFilesPath())
+	path.WriteString("/")
+	path.WriteString(name)
+
+	return path.String()
+}
+
 func getDbPath() string {
 	// return "./mate.csv"
-	homePath := os.Getenv("HOME")
-	if homePath == "" {
-		log.Fatal("Cannot access home directory")
-	}
 	var dbPath strings.Builder
-	dbPath.WriteString(homePath)
+	dbPath.WriteString(getFilesPath())
 	dbPath.WriteString("/")
 	dbPath.WriteString(DB_NAME)
 
 	return dbPath.String()
 }
 
-func ensureCSVExists() {
-	f, err := os.OpenFile(getDbPath(), os.O_RDWR|os.O_CREATE, 0755)
+// Returns an error for a failure to open, read or write the database
+func newDatabaseError(action string, err error) error {
+	return withExitCode(EXIT_DATABASE, fmt.Errorf("Cannot %s the database: %w", action, err))
+}
+
+// Creates the database with its header if it is missing or empty
+func ensureCSVExists() error {
+	content, err := files.ReadFile(getDbPath())
+	if err != nil && !os.IsNotExist(err) {
+		return newDatabaseError("open", err)
+	}
+	if len(content) != 0 {
+		return nil
+	}
+	if err = files.WriteFile(getDbPath(), []byte(CSV_HEADER), 0755); err != nil {
+		return newDatabaseError("write", err)
+	}
+	return nil
+}
+
+func getRecords() ([]Record, error) {
+	if err := pullGitDatabase(); err != nil {
+		return nil, err
+	}
+	if err := ensureCSVExists(); err != nil {
+		return nil, err
+	}
+	content, records, err := readDatabaseFile()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	defer f.Close()
+	// The database is encrypted or decrypted as soon as encryption.enabled changes
+	if isEncryptionEnabled() != isEncryptedDatabase(content) {
+		if err = writeRecords(records); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
 
-	r := csv.NewReader(f)
+// Calls fn with each entry of the database, in order, reading it line by line rather than all at once
+// so that going through years of history runs in constant memory
+// An encrypted database is decrypted as a whole, as getRecords does
+func forEachRecord(fn func(Record) error) error {
+	if isEncryptionEnabled() {
+		return forEachRecordOf(getRecords, fn)
+	}
+	if err := pullGitDatabase(); err != nil {
+		return err
+	}
+	if err := ensureCSVExists(); err != nil {
+		return err
+	}
+	head, err := files.ReadAt(getDbPath(), 0, int64(len(ENCRYPTED_DB_MAGIC)))
+	if err != nil {
+		return newDatabaseError("read", err)
+	}
+	if isEncryptedDatabase(head) {
+		return forEachRecordOf(getRecords, fn)
+	}
 
-	_, err = r.Read()
+	start := time.Now()
+	f, err := files.Open(getDbPath())
 	if err != nil {
-		if err == io.EOF {
-			if _, err = f.WriteString(CSV_HEADER); err != nil {
-				log.Fatal(err)
-			}
-		} else {
-			log.Fatal(err)
+		return newDatabaseError("read", err)
+	}
+	defer f.Close()
+	malformedLines = nil
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	count := 0
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if number == 1 || strings.TrimSpace(line) == "" {
+			continue
+		}
+		// A malformed line is skipped rather than making every command fail (see mate doctor)
+		record, err := parseRecordLine(line)
+		if err != nil {
+			malformedLines = append(malformedLines, MalformedLine{number, line, err.Error()})
+			continue
 		}
+		count++
+		if err = fn(record); err != nil {
+			return err
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return newDatabaseError("read", err)
 	}
+	debugf("Streamed %s: %d entries, %d malformed lines in %v", getDbPath(), count, len(malformedLines), time.Since(start))
+	return nil
 }
 
-func getRecords() (records []Record) {
-	ensureCSVExists()
-	f, err := os.OpenFile(getDbPath(), os.O_RDONLY, 0755)
+// Calls fn with each of the records returned by read
+func forEachRecordOf(read func() ([]Record, error), fn func(Record) error) error {
+	records, err := read()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	defer f.Close()
+	for _, r := range records {
+		if err = fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// Reads the database file as is, returning its content and its records
+func readDatabaseFile() ([]byte, []Record, error) {
+	start := time.Now()
+	content, err := files.ReadFile(getDbPath())
+	if err != nil {
+		return nil, nil, newDatabaseError("read", err)
+	}
+	plain := content
+	if isEncryptedDatabase(content) {
+		if plain, err = decryptDatabase(content); err != nil {
+			return nil, nil, newDatabaseError("read", fmt.Errorf("%s: %w", getDbPath(), err))
+		}
+	}
+	// A malformed line is skipped rather than making every command fail (see mate doctor)
+	var records []Record
+	records, malformedLines = parseRecordsLeniently(plain)
+	debugf("Read %s: %d bytes, %d entries, %d malformed lines in %v",
+		getDbPath(), len(content), len(records), len(malformedLines), time.Since(start))
+	return content, records, nil
+}
+
+// Parses a database (header included)
+func parseRecords(f io.Reader) (records []Record, err error) {
 	r := csv.NewReader(f)
 
 	rawRecords, err := r.ReadAll()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	for index, rawRecord := range rawRecords {
@@ -79,7 +210,7 @@ func getRecords() (records []Record) {
 
 		timestamp, err := time.Parse(TIME_FORMAT, rawRecord[0])
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 
 		record := Record{
@@ -93,40 +224,242 @@ func getRecords() (records []Record) {
 	return
 }
 
-// Writes a new entry to the CSV
-func writeTicket(title string) {
-	ensureCSVExists()
-	now := time.Now().Format(TIME_FORMAT)
+// Formats a record as a line of the CSV
+func formatRecord(record Record) string {
+	return formatRecordFields(record.timestamp.Format(TIME_FORMAT), record.title)
+}
 
+// Formats quoted fields as a line of CSV
+func formatRecordFields(fields ...string) string {
 	var literalRecord strings.Builder
 
-	literalRecord.WriteString("\"")
-	literalRecord.WriteString(now)
-	literalRecord.WriteString("\"")
-	literalRecord.WriteString(",")
-	literalRecord.WriteString("\"")
-	literalRecord.WriteString(title)
-	literalRecord.WriteString("\"")
+	for i, field := range fields {
+		if i != 0 {
+			literalRecord.WriteString(",")
+		}
+		literalRecord.WriteString("\"")
+		literalRecord.WriteString(strings.ReplaceAll(field, "\"", "\"\""))
+		literalRecord.WriteString("\"")
+	}
 	literalRecord.WriteString("\n")
 
-	f, err := os.OpenFile(getDbPath(), os.O_WRONLY|os.O_APPEND, 0755)
+	return literalRecord.String()
+}
+
+// Writes a new entry to the CSV
+func writeTicket(title string) error {
+	if title != STOP_TOKEN {
+		title = applyRules(title)
+	}
+	return writeTicketAt(getNow(), title)
+}
+
+// Writes a new entry to the CSV with the given timestamp, and fires the resulting event
+// The timestamp should not be before the last entry (see checkEntryOverlap)
+func writeTicketAt(timestamp time.Time, title string) error {
+	records, err := getRecentRecords(timestamp)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	defer f.Close()
+	record, err := checkEntryOverlap(records, Record{timestamp, title})
+	if err != nil {
+		return err
+	}
+	timestamp = record.timestamp
+	if err = checkLockedChanges([]Record{record}); err != nil {
+		return err
+	}
+	if err = appendRecord(records, record); err != nil {
+		return err
+	}
+	// Fired once the file is closed
+	if event, isEvent := getTicketEvent(records, timestamp, title); isEvent {
+		fireTicketEvent(event)
+	}
+	return nil
+}
+
+// Appends an entry to the database holding the given records
+func appendRecord(records []Record, record Record) error {
+	// An encrypted database cannot be appended to
+	if isEncryptionEnabled() {
+		return writeRecords(append(records, record))
+	}
+
+	if err := files.AppendFile(getDbPath(), []byte(formatRecord(record)), 0755); err != nil {
+		return newDatabaseError("write", err)
+	}
+	// The cache only covers the days before today
+	if record.timestamp.Before(getNow().Truncate(time.Hour * 24)) {
+		if err := invalidateDailyTotalsCache(); err != nil {
+			return err
+		}
+	}
+	if err := auditChanges(nil, []Record{record}); err != nil {
+		return err
+	}
+	if err := updateChain(records, append(records, record)); err != nil {
+		return err
+	}
+	return commitGitDatabase()
+}
+
+// Formats the records as a database, header included
+func formatRecords(records []Record) string {
+	var content strings.Builder
+	content.WriteString(CSV_HEADER)
+	for _, r := range records {
+		content.WriteString(formatRecord(r))
+	}
+	return content.String()
+}
+
+// Rewrites the whole CSV with the given records
+// The records are written to a temporary file first, so that the database is never left half written
+func writeRecords(records []Record) error {
+	if err := ensureCSVExists(); err != nil {
+		return err
+	}
+	_, previous, err := readDatabaseFile()
+	if err != nil {
+		return err
+	}
+	if err = checkLockedChanges(subtractRecords(previous, records)); err != nil {
+		return err
+	}
+	if err = checkLockedChanges(subtractRecords(records, previous)); err != nil {
+		return err
+	}
+
+	content, err := formatDatabase(records)
+	if err != nil {
+		return err
+	}
+	quarantined := malformedLines
+	if len(quarantined) != 0 {
+		if err = quarantineLines(quarantined); err != nil {
+			return err
+		}
+	}
+	tmpPath := getDbPath() + ".tmp"
+	if err = files.WriteFile(tmpPath, content, 0755); err != nil {
+		return newDatabaseError("write", err)
+	}
+	if err = files.Rename(tmpPath, getDbPath()); err != nil {
+		return newDatabaseError("write", err)
+	}
+	if err = invalidateDailyTotalsCache(); err != nil {
+		return err
+	}
+	if len(quarantined) != 0 {
+		malformedLines = nil
+		fmt.Fprintf(os.Stderr, "%d malformed lines moved to %s\n", len(quarantined), getQuarantinePath())
+	}
+	if err = auditChanges(previous, records); err != nil {
+		return err
+	}
+	if err = updateChain(previous, records); err != nil {
+		return err
+	}
+	return commitGitDatabase()
+}
+
+// Reads a two-column CSV (after its header) into a map, from the first column to the second
+// The tables name tickets, hence are encrypted like the database
+// A missing file is the same as an empty one
+func readTable(path string) (map[string]string, error) {
+	table := make(map[string]string)
+
+	content, err := files.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return table, nil
+		}
+		return nil, fmt.Errorf("Cannot read %s: %w", path, err)
+	}
+	if isEncryptedDatabase(content) {
+		if content, err = decryptDatabase(content); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read %s: %w", path, err)
+	}
+	for index, row := range rows {
+		if index == 0 {
+			continue
+		}
+		table[row[0]] = row[1]
+	}
+	return table, nil
+}
+
+// Writes a map as a two-column CSV, sorted by key, encrypted like the database
+func writeTable(path string, header string, table map[string]string) error {
+	var keys []string
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var content strings.Builder
+	content.WriteString(header)
+	for _, key := range keys {
+		content.WriteString(formatRecordFields(key, table[key]))
+	}
+
+	data := []byte(content.String())
+	if isEncryptionEnabled() {
+		var err error
+		if data, err = encryptDatabase(data); err != nil {
+			return err
+		}
+	}
+	if err := files.WriteFile(path, data, 0755); err != nil {
+		return fmt.Errorf("Cannot write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Completes the references given to start (Jira keys, gh:owner/repo#123, GitLab #42 and !17)
+// with the title of their issue
+func expandTitle(title string, records []Record) string {
+	title = expandJiraTitle(title, records)
+	title = expandGitHubTitle(title)
+	return expandGitLabTitle(title)
+}
 
-	if _, err = f.WriteString(literalRecord.String()); err != nil {
-		log.Fatal(err)
+// Returns the title of the last ticket, running or not
+func getLastTitle(records []Record) string {
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].title != STOP_TOKEN {
+			return records[i].title
+		}
 	}
+	return ""
 }
 
-func startTicket(title string) {
-	writeTicket(title)
+func startTicket(title string) error {
+	if err := writeTicket(title); err != nil {
+		return err
+	}
 	fmt.Printf("STARTING %s\n", title)
+	return nil
 }
 
-func stopTicket() {
-	records := getRecords()
+// Stops the current ticket, now or at the end of its day if atEndOfDay is set
+// A ticket running past auto_stop is stopped at that time
+func stopTicket(atEndOfDay bool) error {
+	records, err := getRecentRecords(getWeekStart(getNow().Truncate(time.Hour * 24)))
+	if err != nil {
+		return err
+	}
+	budgets, err := readNormalizedBudgets()
+	if err != nil {
+		return err
+	}
 
 	working := false // To check if the file is not empty or that the previous entry is not already a STOP
 	var last Record
@@ -138,55 +471,72 @@ func stopTicket() {
 	}
 
 	if working {
-		writeTicket(STOP_TOKEN)
-		fmt.Printf("STOPPING %s\n", last.title)
+		stopTime := getRunningEnd(last.timestamp)
+		if atEndOfDay {
+			stopTime = getEndOfDay(last.timestamp)
+			if stopTime.After(getNow()) {
+				return fmt.Errorf("The end of the day (%s) is not reached yet", stopTime.Format(CLOCK_FORMAT))
+			}
+		}
+		if err = writeTicketAt(stopTime, STOP_TOKEN); err != nil {
+			return err
+		}
+		if stopTime.Equal(getNow()) {
+			fmt.Printf("STOPPING %s\n", last.title)
+		} else {
+			fmt.Printf("STOPPING %s at %s\n", last.title, stopTime.Format(TIME_FORMAT))
+		}
+		records = append(records, Record{stopTime, STOP_TOKEN})
+		warnAboutLimits(records)
+		// The budget covers the whole history of the ticket
+		if _, found := budgets[normalizeTitle(last.title, getAliases())]; found {
+			if records, err = getRecords(); err != nil {
+				return err
+			}
+			if err = warnAboutBudget(records, last.title); err != nil {
+				return err
+			}
+		}
+		spendOnGitLab(Interval{last.timestamp, stopTime, last.title})
 	} else {
-		fmt.Println("Not currently working on a ticket. Run:\n$ mate start [\"Ticket title\"]")
+		return errNotWorking
 	}
+	return nil
 }
 
-func yellForNoRecord() {
-	fmt.Println("No entry saved for now. Run:\n$ mate start \"Ticket title\"")
-	os.Exit(1)
-}
-
-func yellForNoPreviousTicket() {
-	fmt.Println("Can not find a previous ticket to restart. Run:")
-	fmt.Println("$ mate start \"Ticket title\"")
-	os.Exit(1)
-}
+var errNotWorking = withExitCode(EXIT_NO_DATA, errors.New("Not currently working on a ticket. Run:\n$ mate start [\"Ticket title\"]"))
+var errNoRecord = withExitCode(EXIT_NO_DATA, errors.New("No entry saved for now. Run:\n$ mate start \"Ticket title\""))
+var errNoPreviousTicket = withExitCode(EXIT_NO_DATA, errors.New("Can not find a previous ticket to restart. Run:\n$ mate start \"Ticket title\""))
 
-func yellForNotStopped(currentTicketTitle string) {
-	fmt.Printf("You are currently working on: %s\n", currentTicketTitle)
-	os.Exit(1)
+func newNotStoppedError(currentTicketTitle string) error {
+	return withExitCode(EXIT_ALREADY_RUNNING, fmt.Errorf("You are currently working on: %s", currentTicketTitle))
 }
 
-func restartLastTicket() {
-	records := getRecords()
+func restartLastTicket() error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
 	numberOfRecords := len(records)
 
 	switch {
 	case numberOfRecords == 0:
-		yellForNoRecord()
+		return errNoRecord
 	case numberOfRecords == 1:
 		if records[0].title == STOP_TOKEN {
-			yellForNoPreviousTicket()
-		} else {
-			yellForNotStopped(records[0].title)
-		}
-	case numberOfRecords > 1:
-		last := records[len(records)-1]
-		if last.title == STOP_TOKEN {
-			penultimate := records[len(records)-2]
-			if penultimate.title == STOP_TOKEN {
-				yellForNoPreviousTicket()
-			} else {
-				startTicket(penultimate.title)
-			}
-		} else {
-			yellForNotStopped(last.title)
+			return errNoPreviousTicket
 		}
+		return newNotStoppedError(records[0].title)
+	}
+	last := records[len(records)-1]
+	if last.title != STOP_TOKEN {
+		return newNotStoppedError(last.title)
 	}
+	penultimate := records[len(records)-2]
+	if penultimate.title == STOP_TOKEN {
+		return errNoPreviousTicket
+	}
+	return startTicket(penultimate.title)
 }
 
 // Computes the duration of each entry (including STOP entries, but not if last)
@@ -220,8 +570,7 @@ func computeEntriesDuration(records []Record) (tickets []struct {
 	last := records[len(records)-1]
 	if last.title != STOP_TOKEN {
 		ticket.title = last.title
-		now, _ := time.Parse(TIME_FORMAT, (time.Now().Format(TIME_FORMAT)))
-		ticket.duration = now.Sub(startTime)
+		ticket.duration = getRunningEnd(startTime).Sub(startTime)
 		tickets = append(tickets, ticket)
 	}
 	return
@@ -257,52 +606,89 @@ func groupDurations(tickets []struct {
 	return
 }
 
-// Computes the total time worked since the begining of the database
-func computeTotalTime(tickets []struct {
-	title    string
-	duration time.Duration
-}) (totalTime time.Duration) {
-	for _, t := range tickets {
-		totalTime += t.duration
+func listEntries(min time.Duration) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
 	}
-	return
-}
-
-func listEntries() {
-	tickets := computeEntriesDuration(getRecords())
+	tickets := computeEntriesDuration(normalizeRecords(records))
 
 	if len(tickets) == 0 {
 		fmt.Println("Nothing to show (yet)")
-		return
+		return nil
 	}
 
+	var folded int
+	var other time.Duration
 	for _, t := range tickets {
 		if t.title == STOP_TOKEN {
 			fmt.Printf("---\n")
+		} else if t.duration < min {
+			folded++
+			other += t.duration
 		} else {
 			fmt.Printf("%s\t%v\n", t.title, t.duration)
 		}
 	}
+	if folded != 0 {
+		fmt.Printf("%s\t%v\n", getOtherTitle(folded, min), other)
+	}
+	return nil
+}
+
+// Folds the totals under min into a single "other" one, so that false starts do not clutter the reports
+func foldTotalsUnder(totals map[string]time.Duration, min time.Duration) map[string]time.Duration {
+	folded := make(map[string]time.Duration)
+	var count int
+	var other time.Duration
+	for title, duration := range totals {
+		if duration < min {
+			count++
+			other += duration
+		} else {
+			folded[title] = duration
+		}
+	}
+	if count != 0 {
+		folded[getOtherTitle(count, min)] = other
+	}
+	return folded
+}
+
+// Returns the title under which count entries or tickets lasting less than min are folded
+func getOtherTitle(count int, min time.Duration) string {
+	return fmt.Sprintf("other (%d under %v)", count, min)
 }
 
-func showReport() {
-	tickets := groupDurations(filterStops(computeEntriesDuration(getRecords())))
+func showReport(min time.Duration) error {
+	daily, err := getDailyTotals()
+	if err != nil {
+		return err
+	}
+	tickets := foldTotalsUnder(daily.ticketTotals(), min)
 
 	if len(tickets) == 0 {
 		fmt.Println("Nothing to show (yet)")
-		return
+		return nil
 	}
 
+	budgets, err := readNormalizedBudgets()
+	if err != nil {
+		return err
+	}
 	for key, value := range tickets {
-		fmt.Printf("%s\t%v\n", key, value)
+		if progress := formatBudgetProgress(value, budgets[key]); progress != "" {
+			fmt.Printf("%s\t%v\t%s\n", key, value, progress)
+		} else {
+			fmt.Printf("%s\t%v\n", key, value)
+		}
 	}
+	return nil
 }
 
 // Return the title of the last ticket
 // A STOP_TOKEN is returned if no record in database
-func getLastTicketTitle() (status string) {
-	records := getRecords()
-
+func getLastTicketTitle(records []Record) (status string) {
 	if len(records) == 0 {
 		status = STOP_TOKEN
 	} else {
@@ -312,25 +698,104 @@ func getLastTicketTitle() (status string) {
 	return
 }
 
-func showInfo() {
-	tickets := filterStops(computeEntriesDuration(getRecords()))
-	totalTime := computeTotalTime(tickets)
-	dayDiff := WORK_DAY - totalTime
-	status := getLastTicketTitle()
+// Computes the time worked today if the running ticket was stopped at the given time
+func computeDayTotalIfStoppedAt(records []Record, stopAt time.Time) time.Duration {
+	hypothetical := append(append([]Record{}, records...), Record{stopAt, STOP_TOKEN})
+	return computeDayTotal(hypothetical, stopAt.Truncate(time.Hour*24))
+}
+
+// Computes when today's target will be reached if the current ticket keeps running
+// The mandatory break, if any, may push the time further, hence the second pass
+func computeFinishTime(records []Record, target time.Duration) time.Time {
+	finish := getNow()
+	for pass := 0; pass < 2; pass++ {
+		finish = finish.Add(target - computeDayTotalIfStoppedAt(records, finish))
+	}
+	return finish
+}
+
+// Prints the current ticket and the time left to work today, with the time at which the day will be done
+// With showBalance, the flex balance until yesterday is printed too
+// With assumeStopAt (HH:MM), the time worked today if stopping then is printed too
+func showInfo(showBalance bool, assumeStopAt string) error {
+	today := getNow().Truncate(time.Hour * 24)
+	// The current week is enough, but for the budget of the running ticket
+	records, err := getRecentRecords(getWeekStart(today))
+	if err != nil {
+		return err
+	}
+	budgets, err := readNormalizedBudgets()
+	if err != nil {
+		return err
+	}
+	if _, found := budgets[normalizeTitle(getLastTicketTitle(records), getAliases())]; found {
+		if records, err = getRecords(); err != nil {
+			return err
+		}
+	}
+	tickets := filterStops(computeEntriesDuration(records))
+	dayDiff := getDayTarget(today) - computeDayTotal(records, today)
+	status := getLastTicketTitle(records)
 
-	if status == STOP_TOKEN {
+	if status == STOP_TOKEN || isAutoStopped(records) {
 		fmt.Printf("Currently not working\n")
 	} else {
 		groupedTickets := groupDurations(tickets)
-		fmt.Printf("Working on %s (%v)\n", status, groupedTickets[status])
+		// The budget is compared with the time spent under all the aliases of the ticket
+		canonical := normalizeTitle(status, getAliases())
+		spent := groupDurations(filterStops(computeEntriesDuration(normalizeRecords(records))))[canonical]
+		if progress := formatBudgetProgress(spent, budgets[canonical]); progress != "" {
+			fmt.Printf("Working on %s (%v %s)\n", status, spent, progress)
+		} else {
+			fmt.Printf("Working on %s (%v)\n", status, groupedTickets[status])
+		}
 	}
 
+	working := status != STOP_TOKEN && !isAutoStopped(records)
 	if dayDiff > 0 {
 		fmt.Printf("Still %v to work\n", dayDiff)
+		if working {
+			fmt.Printf("Done at %s\n", computeFinishTime(records, getDayTarget(today)).Format(CLOCK_FORMAT))
+		} else {
+			// Resuming now means a ticket running from now on
+			resumed := append(append([]Record{}, records...), Record{getNow(), ""})
+			fmt.Printf("Done at %s if you resume now\n", computeFinishTime(resumed, getDayTarget(today)).Format(CLOCK_FORMAT))
+		}
 	} else {
 		fmt.Printf("You're done for today (+%v)\n", dayDiff*-1)
 	}
+
+	if assumeStopAt != "" {
+		clock, err := parseClock(assumeStopAt)
+		if err != nil {
+			return err
+		}
+		stopAt := today.Add(clock)
+		if !working {
+			fmt.Println("Not currently working, nothing to stop")
+		} else if stopAt.Before(getNow()) {
+			fmt.Printf("%s is already past\n", stopAt.Format(CLOCK_FORMAT))
+		} else {
+			total := computeDayTotalIfStoppedAt(records, stopAt)
+			fmt.Printf("Stopping at %s: %v worked today (%s)\n", stopAt.Format(CLOCK_FORMAT), total, formatBalance(total-getDayTarget(today)))
+		}
+	}
+
+	if showBalance {
+		daily, err := getDailyTotals()
+		if err != nil {
+			return err
+		}
+		balance, err := computeBalance(daily.firstDay, daily.totalsPerDay(), today)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Flex balance: %s (until yesterday)\n", formatBalance(balance))
+	}
+
+	warnAboutLimits(records)
 	// Currently [not working] / [working on #XXXX (xxmxxs)]
+	return nil
 }
 
 func contains(s []string, e string) bool {
@@ -342,89 +807,169 @@ func contains(s []string, e string) bool {
 	return false
 }
 
-func clearEntries() {
-	reader := bufio.NewReader(os.Stdin)
-	var userEntry string
-	i := 0
-	for !contains([]string{"y\n", "Y\n", "n\n", "N\n", "\n"}, userEntry) && i < 3 {
-		fmt.Print("Empty all entries in the database? [y/N]: ")
-		userEntry, _ = reader.ReadString('\n')
-		i++
+// Prints the question and returns the answer of the user, without surrounding spaces
+// The second value is false if stdin was closed
+func askUser(reader *bufio.Reader, question string) (string, bool) {
+	fmt.Print(question)
+	answer, err := reader.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return "", false
 	}
-	switch userEntry {
-	case "y\n", "Y\n":
-		err := os.Truncate(getDbPath(), int64(len(CSV_HEADER)))
-		if err != nil {
-			log.Fatal(err)
+	return strings.TrimSpace(answer), true
+}
+
+// Removes the intervals starting within [since, before[ (zero times meaning no bound) of the given ticket
+// (any ticket if empty), which become untracked time
+// Returns the remaining entries, and the removed ones: the starts of the intervals and the STOP entries
+// left redundant
+func removeIntervals(records []Record, since time.Time, before time.Time, ticket string) (kept []Record, removed []Record) {
+	for _, r := range records {
+		converted := false
+		if r.title != STOP_TOKEN && (since.IsZero() || !r.timestamp.Before(since)) &&
+			(before.IsZero() || r.timestamp.Before(before)) && (ticket == "" || r.title == ticket) {
+			// The previous interval still ends there
+			removed = append(removed, r)
+			r, converted = Record{r.timestamp, STOP_TOKEN}, true
 		}
-		fmt.Println("Database cleared")
-	default:
-		fmt.Println("Command canceled")
+		if r.title == STOP_TOKEN && (len(kept) == 0 || kept[len(kept)-1].title == STOP_TOKEN) {
+			if !converted {
+				removed = append(removed, r)
+			}
+			continue
+		}
+		kept = append(kept, r)
 	}
+	return
 }
 
-func showErrorHelp() {
-	fmt.Println("Please provide a command among:")
-	fmt.Println("  * start (s)")
-	fmt.Println("  * stop (x)")
-	fmt.Println("  * log (l)")
-	fmt.Println("  * list (ll)")
-	fmt.Println("  * info (i)")
-	fmt.Println("  * clear")
+// Moves the entries matching the filters to the trash, after confirmation unless yes is set
+func clearEntries(since string, before string, ticket string, yes bool) error {
+	start, _, err := parseDateRange(since, "")
+	if err != nil {
+		return err
+	}
+	var end time.Time
+	if before != "" {
+		if end, err = parseDate(before); err != nil {
+			return err
+		}
+	}
+
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	kept, removed := removeIntervals(records, start, end, ticket)
+	if len(removed) == 0 {
+		fmt.Println("Nothing to clear")
+		return nil
+	}
+	if !yes {
+		question := fmt.Sprintf("Delete %d entries? [y/N]: ", len(removed))
+		if since == "" && before == "" && ticket == "" {
+			question = "Empty all entries in the database? [y/N]: "
+		}
+		answer, ok := askUser(bufio.NewReader(os.Stdin), question)
+		if !ok || !contains([]string{"y", "Y"}, answer) {
+			fmt.Println("Command canceled")
+			return nil
+		}
+	}
+
+	if err = writeRecords(kept); err != nil {
+		return err
+	}
+	id, err := trashRecords(removed)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d entries deleted (undo with mate trash restore %d)\n", len(removed), id)
+	return nil
 }
 
 func main() {
-	numberOfArgs := len(os.Args)
+	commands := getCommands()
+	position := findCommandName(os.Args)
+	if position == -1 {
+		showHelp(commands)
+		if contains(os.Args, "--help") {
+			return
+		}
+		os.Exit(EXIT_USAGE)
+	}
+	name := os.Args[position]
+	if name == "help" {
+		if position+1 < len(os.Args) {
+			if command := findCommand(commands, os.Args[position+1]); command != nil {
+				showCommandHelp(command)
+				return
+			}
+		}
+		showHelp(commands)
+		return
+	}
+	command := findCommand(commands, name)
+	if command == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command \"%s\"\n", name)
+		showHelp(commands)
+		os.Exit(EXIT_USAGE)
+	}
 
-	if numberOfArgs == 1 {
-		showErrorHelp()
-		os.Exit(1)
+	in, err := parseInvocation(command, append(append([]string{}, os.Args[1:position]...), os.Args[position+1:]...))
+	if err == nil && in.flag("help") {
+		showCommandHelp(command)
+		return
 	}
+	if err == nil {
+		err = runCommand(in)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+}
 
-	if numberOfArgs > 3 {
-		fmt.Println("Too much arguments provided.")
-		fmt.Println("(Use quotes for long titles)")
-		os.Exit(1)
+// Runs a command, locally or on the remote given by --remote (or remote in the config)
+func runCommand(in *Invocation) error {
+	profileOption = in.option("profile")
+	forceOption = in.flag("force")
+	verboseOption = in.flag("verbose")
+	start := time.Now()
+	defer func() { debugf("mate %s done in %v", in.command.name, time.Since(start)) }()
+	if err := setClockFromEnv(); err != nil {
+		return err
+	}
+	if in.flag("quiet") {
+		// Errors are still reported, on stderr
+		devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		defer devNull.Close()
+		os.Stdout = devNull
+	}
+	if err := checkProfile(); err != nil {
+		return err
 	}
+	if err := loadConfig(); err != nil {
+		return err
+	}
+	debugf("Profile %s, config %s, database %s", getProfile(), getConfigPath(), getDbPath())
 
-	switch os.Args[1] {
-	case "start", "s":
-		if numberOfArgs == 3 {
-			startTicket(os.Args[2])
-		} else {
-			restartLastTicket()
-		}
-	case "stop", "x":
-		if numberOfArgs == 3 {
-			fmt.Println("The stop command does not take any parameter")
-			os.Exit(1)
-		}
-		stopTicket()
-	case "log", "l":
-		if numberOfArgs == 3 {
-			fmt.Println("The log command does not take any parameter")
-			os.Exit(1)
-		}
-		showReport()
-	case "list", "ll":
-		if numberOfArgs == 3 {
-			fmt.Println("The list command does not take any parameter")
-			os.Exit(1)
-		}
-		listEntries()
-	case "info", "i":
-		if numberOfArgs == 3 {
-			fmt.Println("The info command does not take any parameter")
-			os.Exit(1)
-		}
-		showInfo()
-	case "clear":
-		if numberOfArgs == 3 {
-			fmt.Println("The clear command does not take any parameter")
-			os.Exit(1)
-		}
-		clearEntries()
-	default:
-		showErrorHelp()
+	if remote := getCommandRemote(in); remote != "" {
+		return runRemoteCommand(remote, in)
+	}
+
+	if err := reconcileTimer(); err != nil {
+		return err
+	}
+	err := in.command.run(in)
+	reportMalformedLines()
+	if err != nil {
+		return err
+	}
+	if err = checkNotifications(); err != nil {
+		return err
 	}
+	return checkDayCompleteWebhook()
 }
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 14, 12, 0, 0, 0, time.Local)
+
+// Runs a test on a database held in memory, with the clock stopped at testNow
+// The side files (audit, notified events...) go to a temporary home directory
+func useMemoryStore(t *testing.T, database string) *MemoryFileSystem {
+	memory := newMemoryFileSystem()
+	home := useTempHome(t)
+	if database != "" {
+		memory.files[home+"/"+DB_NAME] = []byte(database)
+	}
+	previousFiles, previousClock, previousConfig := files, clock, config
+	files, clock, config = memory, FixedClock{testNow}, map[string]string{}
+	t.Cleanup(func() { files, clock, config = previousFiles, previousClock, previousConfig })
+	return memory
+}
+
+// Points the home directory to a temporary one, returning it
+func useTempHome(t *testing.T) string {
+	home := t.TempDir()
+	previous, found := os.LookupEnv("HOME")
+	os.Setenv("HOME", home)
+	t.Cleanup(func() {
+		if found {
+			os.Setenv("HOME", previous)
+		} else {
+			os.Unsetenv("HOME")
+		}
+	})
+	return home
+}
+
+func parseTestTime(t *testing.T, literal string) time.Time {
+	timestamp, err := time.Parse(TIME_FORMAT, literal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return timestamp
+}
+
+func TestComputeEntriesDuration(t *testing.T) {
+	type entry struct {
+		title    string
+		duration time.Duration
+	}
+	tests := []struct {
+		name    string
+		records [][2]string
+		want    []entry
+	}{
+		{"no entry", nil, nil},
+		{"running ticket, until now", [][2]string{{"2026/10/14 10:30:00", "A"}}, []entry{{"A", time.Hour + 30*time.Minute}}},
+		{"stopped ticket", [][2]string{{"2026/10/14 09:00:00", "A"}, {"2026/10/14 10:00:00", STOP_TOKEN}}, []entry{{"A", time.Hour}}},
+		{
+			"switch then stop",
+			[][2]string{{"2026/10/14 09:00:00", "A"}, {"2026/10/14 09:45:00", "B"}, {"2026/10/14 11:00:00", STOP_TOKEN}},
+			[]entry{{"A", 45 * time.Minute}, {"B", time.Hour + 15*time.Minute}},
+		},
+		{
+			"pause counted as a STOP entry",
+			[][2]string{{"2026/10/14 09:00:00", "A"}, {"2026/10/14 10:00:00", STOP_TOKEN}, {"2026/10/14 10:20:00", "A"}, {"2026/10/14 11:00:00", STOP_TOKEN}},
+			[]entry{{"A", time.Hour}, {STOP_TOKEN, 20 * time.Minute}, {"A", 40 * time.Minute}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, "")
+			var records []Record
+			for _, r := range test.records {
+				records = append(records, Record{parseTestTime(t, r[0]), r[1]})
+			}
+			got := computeEntriesDuration(records)
+			if len(got) != len(test.want) {
+				t.Fatalf("got %d entries %v, want %v", len(got), got, test.want)
+			}
+			for i := range got {
+				if got[i].title != test.want[i].title || got[i].duration != test.want[i].duration {
+					t.Errorf("entry %d: got %s %v, want %s %v", i, got[i].title, got[i].duration, test.want[i].title, test.want[i].duration)
+				}
+			}
+		})
+	}
+}
+
+func TestRestartLastTicket(t *testing.T) {
+	tests := []struct {
+		name     string
+		database string
+		wantErr  error
+		// The last entry once restarted, when no error is expected
+		wantLast string
+	}{
+		{"empty database", "", errNoRecord, ""},
+		{"only a STOP", CSV_HEADER + "2026/10/14 09:00:00,mate:STOP\n", errNoPreviousTicket, ""},
+		{"running ticket", CSV_HEADER + "2026/10/14 09:00:00,A\n", newNotStoppedError("A"), ""},
+		{"two STOPs", CSV_HEADER + "2026/10/14 09:00:00,A\n2026/10/14 10:00:00,mate:STOP\n2026/10/14 10:05:00,mate:STOP\n", errNoPreviousTicket, ""},
+		{"stopped ticket", CSV_HEADER + "2026/10/14 09:00:00,A\n2026/10/14 09:30:00,B\n2026/10/14 10:00:00,mate:STOP\n", nil, "B"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, test.database)
+			err := restartLastTicket()
+			if test.wantErr != nil {
+				if err == nil || err.Error() != test.wantErr.Error() {
+					t.Fatalf("got error %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			records, err := getRecords()
+			if err != nil {
+				t.Fatal(err)
+			}
+			last := records[len(records)-1]
+			if last.title != test.wantLast || !last.timestamp.Equal(getNow()) {
+				t.Errorf("got last entry %s %s, want %s now", last.timestamp.Format(TIME_FORMAT), last.title, test.wantLast)
+			}
+		})
+	}
+}
+
+func TestGetRecordsOfEmptyOrCorruptDatabase(t *testing.T) {
+	tests := []struct {
+		name          string
+		database      string
+		wantRecords   int
+		wantMalformed int
+	}{
+		{"missing file", "", 0, 0},
+		{"header only", CSV_HEADER, 0, 0},
+		{"blank lines", CSV_HEADER + "\n\n2026/10/14 09:00:00,A\n\n", 1, 0},
+		{"malformed lines", CSV_HEADER + "2026/10/14 09:00:00,A\nnot a date,B\n2026/10/14 10:00:00\n2026/10/14 11:00:00,mate:STOP\n", 2, 2},
+		{"truncated last line", CSV_HEADER + "2026/10/14 09:00:00,A\n2026/10/1", 1, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			memory := useMemoryStore(t, test.database)
+			records, err := getRecords()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != test.wantRecords || len(malformedLines) != test.wantMalformed {
+				t.Errorf("got %d entries and %d malformed lines, want %d and %d",
+					len(records), len(malformedLines), test.wantRecords, test.wantMalformed)
+			}
+			content, err := memory.ReadFile(getDbPath())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.database == "" && string(content) != CSV_HEADER {
+				t.Errorf("got database %q, want the header to be created", content)
+			}
+		})
+	}
+}
+
+func TestMemoryFileSystemReportsMissingFiles(t *testing.T) {
+	memory := newMemoryFileSystem()
+	if _, err := memory.ReadFile("missing"); !os.IsNotExist(err) {
+		t.Errorf("ReadFile: got %v, want a missing file", err)
+	}
+	if err := memory.Rename("missing", "other"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Rename: got %v, want a missing file", err)
+	}
+	if err := memory.Remove("missing"); !os.IsNotExist(err) {
+		t.Errorf("Remove: got %v, want a missing file", err)
+	}
+}
+
+func TestSideFilesInMemory(t *testing.T) {
+	memory := useMemoryStore(t, CSV_HEADER+"2026/10/14 09:00:00,A\n")
+	if _, err := markNotified("day 2026/10/14"); err != nil {
+		t.Fatal(err)
+	}
+	if err := lockEntries("2026/10/13"); err != nil {
+		t.Fatal(err)
+	}
+	if err := scheduleStop(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTicketAt(testNow, STOP_TOKEN); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{getNotifiedPath(), getLockPath(), getTimerPath(), getAuditPath()} {
+		if _, err := memory.ReadFile(path); err != nil {
+			t.Errorf("got %v, want %s in memory", err, path)
+		}
+	}
+	if entries, _ := os.ReadDir(os.Getenv("HOME")); len(entries) != 0 {
+		t.Errorf("got %d files written on the disk", len(entries))
+	}
+}
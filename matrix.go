@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How long a sync waits for an event, in milliseconds, below API_TIMEOUT
+const MATRIX_SYNC_TIMEOUT = 20000
+
+// The events of a sync, only the messages and the invites of the rooms being read
+type MatrixSync struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []struct {
+					Type    string `json:"type"`
+					Sender  string `json:"sender"`
+					Content struct {
+						MsgType string `json:"msgtype"`
+						Body    string `json:"body"`
+					} `json:"content"`
+				} `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]json.RawMessage `json:"invite"`
+	} `json:"rooms"`
+}
+
+// The Matrix bot of the account of matrix.token, answering the rooms listed in matrix.rooms
+// It joins the allowed rooms it is invited to
+type MatrixBot struct {
+	user    string
+	allowed map[string]bool
+	since   string
+}
+
+// Calls the client-server API of matrix.homeserver
+func callMatrix(method string, path string, body interface{}, out interface{}) error {
+	headers := map[string]string{"Authorization": "Bearer " + getConfig("matrix.token", "")}
+	endpoint := strings.TrimSuffix(getConfig("matrix.homeserver", ""), "/") + "/_matrix/client/v3" + path
+	return callJSONAPI(method, endpoint, headers, body, out)
+}
+
+func connectMatrixBot() (*MatrixBot, error) {
+	if _, err := getRequiredConfig("matrix.homeserver", "the URL of the homeserver, e.g. https://matrix.org"); err != nil {
+		return nil, err
+	}
+	if _, err := getRequiredConfig("matrix.token", "the access token of the account of the bot"); err != nil {
+		return nil, err
+	}
+	rooms, err := getRequiredConfig("matrix.rooms", "the IDs of the rooms allowed to control mate, e.g. !abc:matrix.org")
+	if err != nil {
+		return nil, err
+	}
+	bot := &MatrixBot{allowed: make(map[string]bool)}
+	for _, room := range splitConfigList(rooms) {
+		bot.allowed[room] = true
+	}
+
+	var me struct {
+		UserID string `json:"user_id"`
+	}
+	if err = callMatrix("GET", "/account/whoami", nil, &me); err != nil {
+		return nil, fmt.Errorf("Cannot reach the Matrix homeserver: %w", err)
+	}
+	bot.user = me.UserID
+	// The messages sent before the bot started are skipped
+	var sync MatrixSync
+	if err = bot.sync(0, `{"room":{"timeline":{"limit":1}}}`, &sync); err != nil {
+		return nil, err
+	}
+	bot.since = sync.NextBatch
+	fmt.Printf("Matrix bot %s running, press Ctrl+C to stop\n", bot.user)
+	return bot, nil
+}
+
+func (bot *MatrixBot) sync(timeout int, filter string, sync *MatrixSync) error {
+	query := url.Values{"timeout": {strconv.Itoa(timeout)}, "filter": {filter}}
+	if bot.since != "" {
+		query.Set("since", bot.since)
+	}
+	return callMatrix("GET", "/sync?"+query.Encode(), nil, sync)
+}
+
+func (bot *MatrixBot) poll() (messages []BotMessage, err error) {
+	var sync MatrixSync
+	filter := `{"room":{"timeline":{"types":["m.room.message"]}},"presence":{"types":[]},"account_data":{"types":[]}}`
+	if err = bot.sync(MATRIX_SYNC_TIMEOUT, filter, &sync); err != nil {
+		return nil, err
+	}
+	bot.since = sync.NextBatch
+
+	for room := range sync.Rooms.Invite {
+		if !bot.allowed[room] {
+			fmt.Printf("%s Ignoring the invite to %s, which is not in matrix.rooms\n", getNow().Format(CLOCK_FORMAT), room)
+			continue
+		}
+		if err = callMatrix("POST", "/rooms/"+url.PathEscape(room)+"/join", map[string]string{}, nil); err != nil {
+			return nil, err
+		}
+		fmt.Printf("%s Joined %s\n", getNow().Format(CLOCK_FORMAT), room)
+	}
+	for room, joined := range sync.Rooms.Join {
+		for _, event := range joined.Timeline.Events {
+			if event.Type != "m.room.message" || event.Sender == bot.user || !isBotCommand(event.Content.Body) {
+				continue
+			}
+			if !bot.allowed[room] {
+				fmt.Printf("%s Ignoring %s from %s, which is not in matrix.rooms\n",
+					getNow().Format(CLOCK_FORMAT), strings.Fields(event.Content.Body)[0], room)
+				continue
+			}
+			messages = append(messages, BotMessage{room, event.Content.Body})
+		}
+	}
+	return
+}
+
+// Sends a notice, the type of the messages of bots
+func (bot *MatrixBot) send(room string, text string) error {
+	transaction := strconv.FormatInt(time.Now().UnixNano(), 36)
+	path := "/rooms/" + url.PathEscape(room) + "/send/m.room.message/" + transaction
+	return callMatrix("PUT", path, map[string]string{"msgtype": "m.notice", "body": text}, nil)
+}
+
+func (bot *MatrixBot) chats() []string {
+	return splitConfigList(getConfig("matrix.rooms", ""))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// How many recent tickets the menu bar offers to start
+const MENUBAR_RECENT = 8
+
+// Cleans a text for a line of xbar, | separating the text from its parameters
+func formatMenubarText(text string) string {
+	return strings.ReplaceAll(text, "|", "¦")
+}
+
+// Returns the parameters of a menu item running mate with the given arguments, then refreshing the menu
+func formatMenubarAction(executable string, args ...string) string {
+	action := fmt.Sprintf("bash=\"%s\"", executable)
+	for i, arg := range args {
+		action += fmt.Sprintf(" param%d=\"%s\"", i+1, arg)
+	}
+	return action + " terminal=false refresh=true"
+}
+
+// Formats the status as a plugin of xbar or SwiftBar:
+// the title of the menu bar, then a menu with the running ticket, a stop action and the recent tickets to start
+func formatMenubarFeed(status StatusResponse, recent []RecentItem, executable string, dashboard string) string {
+	today := fmt.Sprintf("%s / %s today", formatMinutes(time.Duration(status.TodaySeconds)*time.Second),
+		formatMinutes(time.Duration(status.TargetSeconds)*time.Second))
+	var lines []string
+	if status.Running {
+		title := TICKET_KEY_PATTERN.FindString(status.Title)
+		if title == "" {
+			title = shortenTitle(status.Title)
+		}
+		elapsed := formatMinutes(time.Duration(status.ElapsedSeconds) * time.Second)
+		since, _ := time.Parse(time.RFC3339, status.Since)
+		lines = append(lines,
+			formatMenubarText("⏱ "+title+" "+elapsed),
+			"---",
+			formatMenubarText(status.Title),
+			fmt.Sprintf("Since %s, %s", since.Format(CLOCK_FORMAT), today),
+			"Stop | "+formatMenubarAction(executable, "stop"))
+	} else {
+		lines = append(lines, "⏸ mate", "---", "Not working", today)
+	}
+
+	label := "Start"
+	if status.Running {
+		label = "Switch to"
+	}
+	var items []string
+	for _, item := range recent {
+		// A quote cannot be passed in a parameter of xbar
+		if len(items) == MENUBAR_RECENT || strings.Contains(item.Arg, "\"") || status.Running && item.Arg == status.Title {
+			continue
+		}
+		items = append(items, "--"+formatMenubarText(shortenTitle(item.Arg))+" | "+formatMenubarAction(executable, "start", item.Arg))
+	}
+	if len(items) != 0 {
+		lines = append(append(lines, "---", label), items...)
+	}
+	return strings.Join(append(lines, "---", "Open the dashboard | href="+dashboard, "Refresh | refresh=true"), "\n") + "\n"
+}
+
+// Answers the feed of the menu bar, as plain text
+func handleMenubar(dashboard string) APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		records, err := getRecentRecords(getNow().AddDate(0, 0, -RECENT_DAYS))
+		if err != nil {
+			return err
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, err = fmt.Fprint(w, formatMenubarFeed(getStatus(records), getRecentItems(records, ""), executable, dashboard))
+		return err
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Returns the intervals of other overlapping the intervals of records, except the identical ones
+func findOverlaps(records []Record, other []Record) (overlaps [][2]Interval) {
+	intervals := computeIntervals(records)
+	for _, in := range computeIntervals(other) {
+		if overlapped, found := findOverlap(intervals, in.start, in.end); found &&
+			!(overlapped.start.Equal(in.start) && overlapped.end.Equal(in.end) && overlapped.title == in.title) {
+			overlaps = append(overlaps, [2]Interval{in, overlapped})
+		}
+	}
+	return
+}
+
+// Merges another database (e.g. a conflict file of Dropbox or Syncthing) into the current one
+// Identical entries are skipped, the others are added in chronological order, and the intervals
+// of both databases overlapping each other are reported to be fixed by hand
+// With dryRun, the entries to add are printed instead
+func mergeDatabaseFile(path string, dryRun bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	other, err := parseRecords(f)
+	if err != nil {
+		return fmt.Errorf("Cannot read %s: %w", path, err)
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+
+	overlaps := findOverlaps(records, other)
+	for _, overlap := range overlaps {
+		in, overlapped := overlap[0], overlap[1]
+		fmt.Printf("Overlap: %s (%s - %s) in %s, %s (%s - %s) in the database\n",
+			in.title, in.start.Format(TIME_FORMAT), in.end.Format(CLOCK_FORMAT), path,
+			overlapped.title, overlapped.start.Format(TIME_FORMAT), overlapped.end.Format(CLOCK_FORMAT))
+	}
+
+	if dryRun {
+		added, present := splitNewRecords(records, other)
+		for _, r := range added {
+			fmt.Printf("+ %s %s\n", r.timestamp.Format(TIME_FORMAT), getEntryName(r))
+		}
+		fmt.Printf("Dry run: %d entries to merge, %d already present, %d overlaps\n", len(added), len(present), len(overlaps))
+		return nil
+	}
+	if len(overlaps) != 0 && getOverlapPolicy() == "reject" {
+		return fmt.Errorf("Nothing merged: %d overlaps, as overlaps.policy is reject", len(overlaps))
+	}
+	added, skipped, err := mergeRecords(other)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d entries merged, %d already present, %d overlaps\n", added, skipped, len(overlaps))
+	return nil
+}
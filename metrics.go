@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Escapes a label value of the Prometheus text format
+var METRICS_LABEL_ESCAPER = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Formats the metrics in the text format of Prometheus:
+// gauges of the running ticket and of the day, and a counter of the time spent per project since the first entry
+func formatMetrics(records []Record) string {
+	status := getStatus(records)
+	running := 0
+	if status.Running {
+		running = 1
+	}
+
+	var builder strings.Builder
+	metric := func(name string, kind string, help string) {
+		fmt.Fprintf(&builder, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+	metric("mate_running", "gauge", "Whether a ticket is running.")
+	fmt.Fprintf(&builder, "mate_running %d\n", running)
+	metric("mate_current_session_seconds", "gauge", "Time spent on the running ticket, 0 when not working.")
+	fmt.Fprintf(&builder, "mate_current_session_seconds %d\n", status.ElapsedSeconds)
+	metric("mate_today_total_seconds", "gauge", "Time worked today, breaks deducted.")
+	fmt.Fprintf(&builder, "mate_today_total_seconds %d\n", status.TodaySeconds)
+	metric("mate_today_target_seconds", "gauge", "Time to work today from the schedule, 0 on a non-working day or a public holiday.")
+	fmt.Fprintf(&builder, "mate_today_target_seconds %d\n", status.TargetSeconds)
+
+	projects := make(map[string]time.Duration)
+	for _, in := range computeIntervals(records) {
+		project := getTicketProject(in.title)
+		if project == "" {
+			project = NO_PROJECT
+		}
+		projects[project] += in.end.Sub(in.start)
+	}
+	var names []string
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metric("mate_project_seconds_total", "counter", "Time spent per project, from the key of the tickets or the rules.")
+	for _, name := range names {
+		fmt.Fprintf(&builder, "mate_project_seconds_total{project=\"%s\"} %d\n",
+			METRICS_LABEL_ESCAPER.Replace(name), int64(projects[name].Seconds()))
+	}
+	return builder.String()
+}
+
+// Answers the metrics, for Prometheus to scrape with the token as bearer credentials
+func handleMetrics(w http.ResponseWriter, r *http.Request) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, err = fmt.Fprint(w, formatMetrics(records))
+	return err
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var TAG_SANITIZER = regexp.MustCompile(`[^\w-]+`)
+
+// Builds a title from a project (or first tag) and the other tags, which become hashtags
+func buildImportedTitle(name string, tags []string) string {
+	title := strings.TrimSpace(name)
+	for _, tag := range tags {
+		if tag = TAG_SANITIZER.ReplaceAllString(tag, "-"); tag != "" {
+			title += " #" + tag
+		}
+	}
+	if title == "" {
+		return "(untitled)"
+	}
+	return strings.TrimSpace(title)
+}
+
+// Returns the frames file of Watson, in $WATSON_DIR or its default directory
+func getWatsonFramesPath() string {
+	if dir := os.Getenv("WATSON_DIR"); dir != "" {
+		return dir + "/frames"
+	}
+	return getHomePath() + "/.config/watson/frames"
+}
+
+// Reads Watson frames: either its frames file ([start, stop, project, id, tags, updated] with Unix timestamps),
+// or the output of "watson log --json"
+func importWatson(r io.Reader) ([]Interval, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	var periods []Interval
+	for i, message := range raw {
+		var frame []interface{}
+		if json.Unmarshal(message, &frame) == nil {
+			if len(frame) < 3 {
+				return nil, fmt.Errorf("frame %d: expected [start, stop, project, ...]", i+1)
+			}
+			start, okStart := frame[0].(float64)
+			stop, okStop := frame[1].(float64)
+			project, okProject := frame[2].(string)
+			if !okStart || !okStop || !okProject {
+				return nil, fmt.Errorf("frame %d: expected [start, stop, project, ...]", i+1)
+			}
+			var tags []string
+			if len(frame) > 4 {
+				if rawTags, ok := frame[4].([]interface{}); ok {
+					for _, tag := range rawTags {
+						if t, ok := tag.(string); ok {
+							tags = append(tags, t)
+						}
+					}
+				}
+			}
+			periods = append(periods, Interval{
+				toDbTime(time.Unix(int64(start), 0)),
+				toDbTime(time.Unix(int64(stop), 0)),
+				buildImportedTitle(project, tags),
+			})
+			continue
+		}
+
+		var logged struct {
+			Start   string   `json:"start"`
+			Stop    string   `json:"stop"`
+			Project string   `json:"project"`
+			Tags    []string `json:"tags"`
+		}
+		if err := json.Unmarshal(message, &logged); err != nil {
+			return nil, fmt.Errorf("frame %d: %v", i+1, err)
+		}
+		start, err1 := time.Parse(time.RFC3339, logged.Start)
+		stop, err2 := time.Parse(time.RFC3339, logged.Stop)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("frame %d: invalid start or stop", i+1)
+		}
+		periods = append(periods, Interval{toDbTime(start.Local()), toDbTime(stop.Local()), buildImportedTitle(logged.Project, logged.Tags)})
+	}
+
+	sortPeriods(periods)
+	return periods, nil
+}
+
+// Reads the output of "timew export"
+// The first tag is the title, the other ones become hashtags; running intervals are ignored
+func importTimewarrior(r io.Reader) ([]Interval, error) {
+	var exported []struct {
+		Start      string   `json:"start"`
+		End        string   `json:"end"`
+		Tags       []string `json:"tags"`
+		Annotation string   `json:"annotation"`
+	}
+	if err := json.NewDecoder(r).Decode(&exported); err != nil {
+		return nil, err
+	}
+
+	var periods []Interval
+	for i, interval := range exported {
+		if interval.End == "" {
+			continue
+		}
+		start, err1 := time.Parse("20060102T150405Z", interval.Start)
+		end, err2 := time.Parse("20060102T150405Z", interval.End)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("interval %d: invalid start or end", i+1)
+		}
+
+		name, tags := interval.Annotation, interval.Tags
+		if len(tags) != 0 {
+			name, tags = tags[0], tags[1:]
+		}
+		periods = append(periods, Interval{toDbTime(start.Local()), toDbTime(end.Local()), buildImportedTitle(name, tags)})
+	}
+
+	sortPeriods(periods)
+	return periods, nil
+}
+
+// Returns the native data of Watson or Timewarrior, when no file is given
+func openMigrationSource(format string) (io.ReadCloser, error) {
+	if format == "watson" {
+		return os.Open(getWatsonFramesPath())
+	}
+
+	output, err := exec.Command("timew", "export").Output()
+	if err != nil {
+		return nil, errors.New("cannot run \"timew export\": " + err.Error())
+	}
+	return io.NopCloser(strings.NewReader(string(output))), nil
+}
+
+func sortPeriods(periods []Interval) {
+	sort.SliceStable(periods, func(i, j int) bool {
+		return periods[i].start.Before(periods[j].start)
+	})
+}
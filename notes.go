@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const NOTES_NAME = ".mate.notes.csv"
+const NOTES_HEADER = "at,note\n"
+
+// A note taken while working, belonging to the entry running at its time
+type Note struct {
+	at   time.Time
+	text string
+}
+
+func getNotesPath() string {
+	return getHomeFilePath(NOTES_NAME)
+}
+
+// Reads the notes, in chronological order
+// The notes are encrypted like the database
+func readNotes() (notes []Note, err error) {
+	content, err := files.ReadFile(getNotesPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read the notes: %w", err)
+	}
+	if isEncryptedDatabase(content) {
+		if content, err = decryptDatabase(content); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read the notes: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	for _, row := range rows[1:] {
+		at, err := time.Parse(TIME_FORMAT, row[0])
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read the notes: %w", err)
+		}
+		notes = append(notes, Note{at, row[1]})
+	}
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].at.Before(notes[j].at) })
+	return notes, nil
+}
+
+func writeNotes(notes []Note) error {
+	var content strings.Builder
+	content.WriteString(NOTES_HEADER)
+	for _, n := range notes {
+		content.WriteString(formatRecordFields(n.at.Format(TIME_FORMAT), n.text))
+	}
+
+	data := []byte(content.String())
+	if isEncryptionEnabled() {
+		var err error
+		if data, err = encryptDatabase(data); err != nil {
+			return err
+		}
+	}
+	if err := files.WriteFile(getNotesPath(), data, 0644); err != nil {
+		return fmt.Errorf("Cannot write the notes: %w", err)
+	}
+	return nil
+}
+
+// Adds notes, leaving out those already saved
+func mergeNotes(added []Note) error {
+	if len(added) == 0 {
+		return nil
+	}
+	notes, err := readNotes()
+	if err != nil {
+		return err
+	}
+	for _, n := range added {
+		if !containsNote(notes, n) {
+			notes = append(notes, n)
+		}
+	}
+	return writeNotes(notes)
+}
+
+func containsNote(notes []Note, note Note) bool {
+	for _, n := range notes {
+		if n.at.Equal(note.at) && n.text == note.text {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the notes taken within [start, end[
+func getNotesBetween(notes []Note, start time.Time, end time.Time) (between []Note) {
+	for _, n := range notes {
+		if !n.at.Before(start) && n.at.Before(end) {
+			between = append(between, n)
+		}
+	}
+	return
+}
+
+// Attaches a note to the running entry
+func addNote(text string) error {
+	records, err := getRecentRecords(getNow())
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 || records[len(records)-1].title == STOP_TOKEN || isAutoStopped(records) {
+		return errNotWorking
+	}
+	if err = mergeNotes([]Note{{getNow(), text}}); err != nil {
+		return err
+	}
+	fmt.Printf("Note added to %s\n", records[len(records)-1].title)
+	return nil
+}
+
+// Prints an entry with its notes: the last one by default, else the one of the given time (see findEntry)
+func showEntry(literal string) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	i, err := findEntry(records, literal)
+	if err != nil {
+		return err
+	}
+	entry := records[i]
+	if entry.title == STOP_TOKEN {
+		fmt.Printf("STOP at %s\n", entry.timestamp.Format(TIME_FORMAT))
+		return nil
+	}
+	end, running := getRunningEnd(entry.timestamp), true
+	if i+1 < len(records) {
+		end, running = records[i+1].timestamp, false
+	}
+	ticketClients, err := readTable(getClientsPath())
+	if err != nil {
+		return err
+	}
+	notes, err := readNotes()
+	if err != nil {
+		return err
+	}
+	address, err := getTicketURL(entry.title)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(entry.title)
+	if running {
+		fmt.Printf("Started\t%s (running, %v)\n", entry.timestamp.Format(TIME_FORMAT), end.Sub(entry.timestamp))
+	} else {
+		fmt.Printf("From\t%s to %s (%v)\n", entry.timestamp.Format(TIME_FORMAT), end.Format(TIME_FORMAT), end.Sub(entry.timestamp))
+	}
+	for _, field := range []struct{ name, value string }{
+		{"Project", getTicketProject(entry.title)},
+		{"Client", getClient(entry.title, ticketClients)},
+		{"Category", getCategory(entry.title)},
+		{"Tags", strings.Join(getTicketTags(entry.title), " ")},
+		{"URL", address},
+	} {
+		if field.value != "" {
+			fmt.Printf("%s\t%s\n", field.name, field.value)
+		}
+	}
+	// A note taken right now belongs to the running entry
+	until := end
+	if running {
+		until = end.Add(time.Second)
+	}
+	for _, n := range getNotesBetween(notes, entry.timestamp, until) {
+		fmt.Printf("%s\t%s\n", n.at.Format(CLOCK_FORMAT), n.text)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const NOTIFIED_NAME = ".mate.notified"
+const DEFAULT_LONG_TICKET = "2h"
+const MAX_NOTIFIED_KEYS = 100
+
+func getNotifiedPath() string {
+	return getHomeFilePath(NOTIFIED_NAME)
+}
+
+// Shows a desktop notification, if notifications are enabled
+// Notifications are best effort: a missing notifier is silently ignored
+func notify(message string) {
+	if !getConfigBool("notifications.enabled", false) {
+		return
+	}
+
+	// The message is given as an argument or in the environment rather than in the scripts, not to be run as code
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", "on run argv", "-e", "display notification (item 1 of argv) with title \"mate\"", "-e", "end run", "--", message)
+	case "windows":
+		script := strings.Join([]string{
+			"[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null",
+			"$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)",
+			"$xml.GetElementsByTagName('text')[0].AppendChild($xml.CreateTextNode('mate')) > $null",
+			"$xml.GetElementsByTagName('text')[1].AppendChild($xml.CreateTextNode($env:MATE_NOTIFICATION)) > $null",
+			"[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('mate').Show([Windows.UI.Notifications.ToastNotification]::new($xml))",
+		}, "; ")
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", script)
+		cmd.Env = append(os.Environ(), "MATE_NOTIFICATION="+message)
+	default:
+		cmd = exec.Command("notify-send", "mate", message)
+	}
+	cmd.Run()
+}
+
+// Reads the keys of the events already notified
+func readNotified() (keys []string, err error) {
+	content, err := files.ReadFile(getNotifiedPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Cannot read the notified events: %w", err)
+	}
+	for _, key := range strings.Split(string(content), "\n") {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Shows a notification only once per event key
+// Returns false if the event was already notified
+func notifyOnce(key string, message string) (bool, error) {
+	marked, err := markNotified(key)
+	if marked {
+		notify(message)
+	}
+	return marked, err
+}
+
+// Records that an event was notified
+// Returns false if it already was
+func markNotified(key string) (bool, error) {
+	keys, err := readNotified()
+	if err != nil || contains(keys, key) {
+		return false, err
+	}
+
+	keys = append(keys, key)
+	if len(keys) > MAX_NOTIFIED_KEYS {
+		keys = keys[len(keys)-MAX_NOTIFIED_KEYS:]
+	}
+	if err := files.WriteFile(getNotifiedPath(), []byte(strings.Join(keys, "\n")+"\n"), 0644); err != nil {
+		return false, fmt.Errorf("Cannot write the notified events: %w", err)
+	}
+	return true, nil
+}
+
+// Notifies the events reached since the last check:
+// the work day being complete, and the current ticket running for notifications.long_ticket
+func checkNotifications() error {
+	if !getConfigBool("notifications.enabled", false) {
+		return nil
+	}
+	now := getNow()
+	today := now.Truncate(time.Hour * 24)
+	records, err := getRecentRecords(today)
+	if err != nil {
+		return err
+	}
+
+	target := getDayTarget(today)
+	if getConfigBool("notifications.day_complete", true) && target != 0 && computeDayTotal(records, today) >= target {
+		if _, err = notifyOnce("day "+today.Format(DATE_FORMAT), "Work day complete, time to go home!"); err != nil {
+			return err
+		}
+	}
+
+	longTicket := getConfigDuration("notifications.long_ticket", DEFAULT_LONG_TICKET)
+	if longTicket != 0 && len(records) != 0 && !isAutoStopped(records) {
+		last := records[len(records)-1]
+		if last.title != STOP_TOKEN && now.Sub(last.timestamp) >= longTicket {
+			_, err = notifyOnce("ticket "+last.timestamp.Format(TIME_FORMAT), fmt.Sprintf("%s has been running for %v", last.title, now.Sub(last.timestamp)))
+		}
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckNotifications(t *testing.T) {
+	tests := []struct {
+		name     string
+		database string
+		want     []string
+	}{
+		{"nothing tracked", "", nil},
+		{"short day", CSV_HEADER + "2026/10/14 09:00:00,A\n2026/10/14 10:00:00,mate:STOP\n", nil},
+		{"long ticket", CSV_HEADER + "2026/10/14 09:30:00,A\n", []string{"ticket 2026/10/14 09:30:00"}},
+		{
+			"day complete",
+			CSV_HEADER + "2026/10/14 04:00:00,A\n2026/10/14 11:00:00,B\n2026/10/14 11:30:00,mate:STOP\n",
+			[]string{"day 2026/10/14"},
+		},
+		{
+			"ticket running since yesterday",
+			CSV_HEADER + "2026/10/01 09:00:00,Old\n2026/10/01 10:00:00,mate:STOP\n2026/10/13 23:00:00,A\n",
+			[]string{"day 2026/10/14", "ticket 2026/10/13 23:00:00"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, test.database)
+			config["notifications.enabled"] = "true"
+
+			if err := checkNotifications(); err != nil {
+				t.Fatal(err)
+			}
+			keys, err := readNotified()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != len(test.want) {
+				t.Fatalf("got notified %v, want %v", keys, test.want)
+			}
+			for i := range keys {
+				if keys[i] != test.want[i] {
+					t.Errorf("got notified %v, want %v", keys, test.want)
+				}
+			}
+
+			// Notified once only
+			if err := checkNotifications(); err != nil {
+				t.Fatal(err)
+			}
+			if again, _ := readNotified(); len(again) != len(keys) {
+				t.Errorf("got notified %v on the second check, want %v", again, keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Default template of the report appended to a daily note, \n standing for a new line
+const DEFAULT_OBSIDIAN_TEMPLATE = `## Time log\n\n{report}\n\nTotal: {total}`
+const DEFAULT_OBSIDIAN_FILENAME = "2006-01-02"
+
+// Markers around the report in a daily note, for an export to replace the report of the previous one
+const OBSIDIAN_REPORT_START = "<!-- mate -->"
+const OBSIDIAN_REPORT_END = "<!-- /mate -->"
+
+// Writes the report of each day to its daily note in dir (or obsidian.dir), as set in the [obsidian] section:
+//
+//	[obsidian]
+//	dir = "/home/me/notes/daily"
+//	filename = "2006-01-02"  # Go layout of the note names, .md being added
+//	template = "## Time log\n\n{report}\n\nTotal: {total}"  # {date}, {report} and {total} are replaced
+//
+// The report is appended to the note, or replaces the one of a previous export
+func writeDailyNotes(dir string, intervals []Interval) error {
+	if dir == "" {
+		dir = getConfig("obsidian.dir", "")
+	}
+	if dir == "" {
+		return newUsageError("export", "The obsidian format needs --dir, or obsidian.dir in the config")
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("No directory %s", dir)
+	}
+	if len(intervals) == 0 {
+		return withExitCode(EXIT_NO_DATA, errors.New("Nothing to export"))
+	}
+
+	template := strings.ReplaceAll(getConfig("obsidian.template", DEFAULT_OBSIDIAN_TEMPLATE), `\n`, "\n")
+	filename := getConfig("obsidian.filename", DEFAULT_OBSIDIAN_FILENAME)
+	aliases := getAliases()
+	first := intervals[0].start.Truncate(time.Hour * 24)
+	for day := first; day.Before(intervals[len(intervals)-1].end); day = day.AddDate(0, 0, 1) {
+		dayIntervals := clipIntervalsToDay(intervals, day)
+		if len(dayIntervals) == 0 {
+			continue
+		}
+		var totals []TicketTotal
+		positions := make(map[string]int)
+		var total time.Duration
+		for _, in := range dayIntervals {
+			title := normalizeTitle(in.title, aliases)
+			position, found := positions[title]
+			if !found {
+				position = len(totals)
+				positions[title] = position
+				totals = append(totals, TicketTotal{title, 0})
+			}
+			totals[position].duration += in.end.Sub(in.start)
+			total += in.end.Sub(in.start)
+		}
+		report := strings.NewReplacer(
+			"{date}", day.Format(DATE_FORMAT),
+			"{report}", formatTicketTotals(totals, "-"),
+			"{total}", formatMinutes(total-computeBreakDeduction(dayIntervals)),
+		).Replace(template)
+
+		path := filepath.Join(dir, day.Format(filename)+".md")
+		if err := writeDailyNoteReport(path, report); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote the report of %s to %s\n", day.Format(DATE_FORMAT), path)
+	}
+	return nil
+}
+
+// Appends a report to a note between the markers, replacing the one already there
+func writeDailyNoteReport(path string, report string) error {
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	note := string(content)
+	block := OBSIDIAN_REPORT_START + "\n" + report + "\n" + OBSIDIAN_REPORT_END
+
+	start, end := strings.Index(note, OBSIDIAN_REPORT_START), strings.Index(note, OBSIDIAN_REPORT_END)
+	switch {
+	case start >= 0 && end > start:
+		note = note[:start] + block + note[end+len(OBSIDIAN_REPORT_END):]
+	case note == "":
+		note = block + "\n"
+	default:
+		if !strings.HasSuffix(note, "\n") {
+			note += "\n"
+		}
+		note += "\n" + block + "\n"
+	}
+	return os.WriteFile(path, []byte(note), 0644)
+}
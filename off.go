@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const OFF_NAME = ".mate.off.csv"
+const OFF_CSV_HEADER = "date,type\n"
+
+var OFF_TYPES = []string{"vacation", "sick", "holiday"}
+
+func getOffPath() string {
+	return getHomeFilePath(OFF_NAME)
+}
+
+// Reads the days off, keyed by DATE_FORMAT, with their type
+func readDaysOff() (map[string]string, error) {
+	return readTable(getOffPath())
+}
+
+func writeDaysOff(daysOff map[string]string) error {
+	return writeTable(getOffPath(), OFF_CSV_HEADER, daysOff)
+}
+
+// Returns the time credited for the given day: its whole target if it is a day off
+func getDayCredit(day time.Time, daysOff map[string]string) time.Duration {
+	if _, off := daysOff[day.Format(DATE_FORMAT)]; off {
+		return getDayTarget(day)
+	}
+	return 0
+}
+
+// Records a day off, or removes it
+func setDayOff(date string, offType string, remove bool) error {
+	day, err := parseDate(date)
+	if err != nil {
+		return err
+	}
+	if !contains(OFF_TYPES, offType) {
+		return fmt.Errorf("Invalid type \"%s\" (expected %s)", offType, strings.Join(OFF_TYPES, ", "))
+	}
+
+	daysOff, err := readDaysOff()
+	if err != nil {
+		return err
+	}
+	key := day.Format(DATE_FORMAT)
+	if remove {
+		if _, found := daysOff[key]; !found {
+			return fmt.Errorf("%s is not a day off", key)
+		}
+		delete(daysOff, key)
+		if err = writeDaysOff(daysOff); err != nil {
+			return err
+		}
+		fmt.Printf("%s is no longer a day off\n", key)
+		return nil
+	}
+
+	daysOff[key] = offType
+	if err = writeDaysOff(daysOff); err != nil {
+		return err
+	}
+	fmt.Printf("%s marked as %s\n", key, offType)
+	return nil
+}
+
+func listDaysOff() error {
+	daysOff, err := readDaysOff()
+	if err != nil {
+		return err
+	}
+	if len(daysOff) == 0 {
+		fmt.Println("No day off recorded. Run:\n$ mate off YYYY/MM/DD [--type vacation|sick|holiday]")
+		return nil
+	}
+
+	var dates []string
+	for date := range daysOff {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	for _, date := range dates {
+		fmt.Printf("%s\t%s\n", date, daysOff[date])
+	}
+	return nil
+}
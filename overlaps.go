@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// What to do with an entry or an imported period overlapping the recorded intervals, as set in overlaps.policy:
+// reject it, warn about it (the default), or trim it to the untracked time
+const DEFAULT_OVERLAP_POLICY = "warn"
+
+func getOverlapPolicy() string {
+	return getConfig("overlaps.policy", DEFAULT_OVERLAP_POLICY)
+}
+
+// Checks an entry appended after the given records: one dated before the last entry would overlap its interval
+// (e.g. a timer firing after a switch, or a clock set back), so it is rejected, or moved to the last entry when trimmed
+func checkEntryOverlap(records []Record, record Record) (Record, error) {
+	if len(records) == 0 || !record.timestamp.Before(records[len(records)-1].timestamp) {
+		return record, nil
+	}
+	last := records[len(records)-1]
+	message := fmt.Sprintf("%s at %s is before %s at %s", getEntryName(record), record.timestamp.Format(TIME_FORMAT),
+		getEntryName(last), last.timestamp.Format(TIME_FORMAT))
+	switch getOverlapPolicy() {
+	case "reject":
+		return record, fmt.Errorf("%s: not saved, as overlaps.policy is reject", message)
+	case "trim":
+		fmt.Printf("Warning: %s, saved at %s\n", message, last.timestamp.Format(TIME_FORMAT))
+		record.timestamp = last.timestamp
+	default:
+		fmt.Printf("Warning: %s, the intervals overlap\n", message)
+	}
+	return record, nil
+}
+
+// Returns the parts of a period outside of the intervals, down to a minute
+func trimPeriod(period Interval, intervals []Interval) []Interval {
+	parts := []Interval{period}
+	for _, in := range intervals {
+		var outParts []Interval
+		for _, part := range parts {
+			if !in.start.Before(part.end) || !part.start.Before(in.end) {
+				outParts = append(outParts, part)
+				continue
+			}
+			if in.start.After(part.start) {
+				outParts = append(outParts, Interval{part.start, in.start, part.title})
+			}
+			if in.end.Before(part.end) {
+				outParts = append(outParts, Interval{in.end, part.end, part.title})
+			}
+		}
+		parts = outParts
+	}
+
+	var kept []Interval
+	for _, part := range parts {
+		if part.end.Sub(part.start) >= time.Minute {
+			kept = append(kept, part)
+		}
+	}
+	sortPeriods(kept)
+	return kept
+}
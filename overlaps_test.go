@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckEntryOverlap(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		at      string
+		wantErr bool
+		wantAt  string
+	}{
+		{"after the last entry", "reject", "2026/10/14 11:00:00", false, "2026/10/14 11:00:00"},
+		{"at the last entry", "reject", "2026/10/14 10:00:00", false, "2026/10/14 10:00:00"},
+		{"before, warned", "", "2026/10/14 09:30:00", false, "2026/10/14 09:30:00"},
+		{"before, rejected", "reject", "2026/10/14 09:30:00", true, ""},
+		{"before, trimmed", "trim", "2026/10/14 09:30:00", false, "2026/10/14 10:00:00"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, "")
+			if test.policy != "" {
+				config["overlaps.policy"] = test.policy
+			}
+			records := []Record{{parseTestTime(t, "2026/10/14 09:00:00"), "A"}, {parseTestTime(t, "2026/10/14 10:00:00"), "B"}}
+			got, err := checkEntryOverlap(records, Record{parseTestTime(t, test.at), "C"})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && !got.timestamp.Equal(parseTestTime(t, test.wantAt)) {
+				t.Errorf("got the entry at %v, want %s", got.timestamp, test.wantAt)
+			}
+		})
+	}
+
+	// The first entry cannot overlap
+	useMemoryStore(t, "")
+	config["overlaps.policy"] = "reject"
+	if _, err := checkEntryOverlap(nil, Record{parseTestTime(t, "2026/10/14 09:00:00"), "A"}); err != nil {
+		t.Errorf("got error %v for the first entry", err)
+	}
+}
+
+func TestTrimPeriod(t *testing.T) {
+	interval := func(start string, end string) Interval {
+		return Interval{parseTestTime(t, "2026/10/14 "+start+":00"), parseTestTime(t, "2026/10/14 "+end+":00"), "P"}
+	}
+	tracked := []Interval{interval("10:00", "11:00"), interval("13:00", "14:00")}
+	tests := []struct {
+		name   string
+		period Interval
+		want   []Interval
+	}{
+		{"before the intervals", interval("08:00", "09:00"), []Interval{interval("08:00", "09:00")}},
+		{"touching an interval", interval("09:00", "10:00"), []Interval{interval("09:00", "10:00")}},
+		{"overlapping the start", interval("09:00", "10:30"), []Interval{interval("09:00", "10:00")}},
+		{"overlapping the end", interval("10:30", "12:00"), []Interval{interval("11:00", "12:00")}},
+		{"within an interval", interval("10:15", "10:45"), nil},
+		{"around an interval", interval("09:00", "12:00"), []Interval{interval("09:00", "10:00"), interval("11:00", "12:00")}},
+		{
+			"across both intervals",
+			interval("09:30", "14:30"),
+			[]Interval{interval("09:30", "10:00"), interval("11:00", "13:00"), interval("14:00", "14:30")},
+		},
+		{"part under a minute dropped", Interval{tracked[0].start.Add(-30 * time.Second), tracked[0].end, "P"}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := trimPeriod(test.period, tracked)
+			if len(got) != len(test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if !got[i].start.Equal(test.want[i].start) || !got[i].end.Equal(test.want[i].end) {
+					t.Errorf("got %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
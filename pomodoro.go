@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+const DEFAULT_POMODORO_WORK = "25m"
+const DEFAULT_POMODORO_BREAK = "5m"
+const DEFAULT_POMODORO_LONG_BREAK = "15m"
+const DEFAULT_POMODORO_CYCLES = "4"
+
+// Waits for the given duration
+// Returns false if the user interrupted the wait (Ctrl+C)
+func waitOrInterrupt(duration time.Duration, interrupt chan os.Signal) bool {
+	select {
+	case <-time.After(duration):
+		return true
+	case <-interrupt:
+		fmt.Println()
+		return false
+	}
+}
+
+// Signals a pomodoro transition
+func announcePomodoro(message string) {
+	fmt.Printf("\a%s %s\n", getNow().Format(CLOCK_FORMAT), message)
+	if getConfigBool("notifications.pomodoro", true) {
+		notify(message)
+	}
+}
+
+// Runs work/break cycles on a ticket until interrupted
+// Each work segment is an entry, each break a STOP
+// A long break follows every pomodoro.cycles work segments
+func runPomodoro(title string) error {
+	work := getConfigDuration("pomodoro.work", DEFAULT_POMODORO_WORK)
+	shortBreak := getConfigDuration("pomodoro.break", DEFAULT_POMODORO_BREAK)
+	longBreak := getConfigDuration("pomodoro.long_break", DEFAULT_POMODORO_LONG_BREAK)
+	cycles := getConfigInt("pomodoro.cycles", DEFAULT_POMODORO_CYCLES)
+	if work == 0 {
+		return errors.New("pomodoro.work must be a positive duration")
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	fmt.Println("Press Ctrl+C to stop")
+	for n := 1; ; n++ {
+		if err := writeTicket(title); err != nil {
+			return err
+		}
+		announcePomodoro(fmt.Sprintf("Pomodoro #%d: working on %s for %v", n, title, work))
+		if !waitOrInterrupt(work, interrupt) {
+			if err := writeTicket(STOP_TOKEN); err != nil {
+				return err
+			}
+			fmt.Printf("STOPPING %s\n", title)
+			return nil
+		}
+
+		if err := writeTicket(STOP_TOKEN); err != nil {
+			return err
+		}
+		pause := shortBreak
+		if cycles != 0 && n%cycles == 0 {
+			pause = longBreak
+		}
+		announcePomodoro(fmt.Sprintf("Break for %v", pause))
+		if !waitOrInterrupt(pause, interrupt) {
+			fmt.Println("Pomodoro stopped during a break")
+			return nil
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Formats the grouped report of a day for a chat, empty if nothing was tracked
+func formatDaySummary(records []Record, day time.Time) string {
+	totals := computeDaySummary(records, day)
+	if len(totals) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Summary of %s %s (%s)\n%s", day.Weekday(), day.Format(DATE_FORMAT),
+		formatMinutes(computeDayTotal(records, day)), formatTicketTotals(totals, "•"))
+}
+
+// Posts the grouped report of a day to the Slack or Mattermost incoming webhook of post.webhook_url
+// The channel defaults to post.channel, or to the channel of the webhook
+func postSummary(date string, channel string) error {
+	webhook, err := getRequiredConfig("post.webhook_url", "an incoming webhook of Slack or Mattermost")
+	if err != nil {
+		return err
+	}
+	day, err := parseDate(date)
+	if err != nil {
+		return err
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	text := formatDaySummary(records, day)
+	if text == "" {
+		return fmt.Errorf("Nothing tracked on %s, nothing posted", day.Format(DATE_FORMAT))
+	}
+	message := map[string]string{"text": text}
+	if channel == "" {
+		channel = getConfig("post.channel", "")
+	}
+	if channel != "" {
+		message["channel"] = channel
+	}
+	if username := getConfig("post.username", ""); username != "" {
+		message["username"] = username
+	}
+
+	if err = callJSONAPI("POST", webhook, nil, message, nil); err != nil {
+		return fmt.Errorf("Cannot post the summary: %w", err)
+	}
+	if channel != "" {
+		fmt.Printf("Summary of %s posted to %s\n", day.Format(DATE_FORMAT), channel)
+	} else {
+		fmt.Printf("Summary of %s posted\n", day.Format(DATE_FORMAT))
+	}
+	return nil
+}
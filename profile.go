@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const DEFAULT_PROFILE = "default"
+const PROFILES_DIR_NAME = ".mate.profiles"
+const CURRENT_PROFILE_NAME = ".mate.profile"
+
+var PROFILE_NAME_PATTERN = regexp.MustCompile(`^[\w.-]+$`)
+
+// Profile given by --profile, which takes precedence over $MATE_PROFILE and "mate profile switch"
+var profileOption string
+
+func getProfilesPath() string {
+	return getHomePath() + "/" + PROFILES_DIR_NAME
+}
+
+// Returns the directory of the files of a profile: the home directory for the default profile,
+// ~/.mate.profiles/<name> for the other ones
+func getProfilePath(profile string) string {
+	if profile == DEFAULT_PROFILE {
+		return getHomePath()
+	}
+	return getProfilesPath() + "/" + profile
+}
+
+// Returns the current profile: --profile, else $MATE_PROFILE, else the one chosen by "mate profile switch"
+// The profile must have been checked (see checkProfile)
+func getProfile() string {
+	profile := profileOption
+	if profile == "" {
+		profile = os.Getenv("MATE_PROFILE")
+	}
+	if profile == "" {
+		content, err := os.ReadFile(getHomePath() + "/" + CURRENT_PROFILE_NAME)
+		if err == nil {
+			profile = strings.TrimSpace(string(content))
+		}
+	}
+	if profile == "" {
+		return DEFAULT_PROFILE
+	}
+	return profile
+}
+
+// Checks the home directory and the current profile, which the paths of all the files depend on
+func checkProfile() error {
+	if getHomePath() == "" {
+		return errors.New("Cannot access home directory")
+	}
+	profile := getProfile()
+	if profile == DEFAULT_PROFILE {
+		return nil
+	}
+	if !PROFILE_NAME_PATTERN.MatchString(profile) {
+		return fmt.Errorf("Invalid profile \"%s\" (expected letters, digits, \"-\", \"_\" or \".\")", profile)
+	}
+	if info, err := os.Stat(getProfilePath(profile)); err != nil || !info.IsDir() {
+		return fmt.Errorf("Unknown profile \"%s\". Run:\n$ mate profile create %s", profile, profile)
+	}
+	return nil
+}
+
+// Returns the default profile and the created ones, sorted by name
+func listProfiles() []string {
+	profiles := []string{DEFAULT_PROFILE}
+	entries, err := os.ReadDir(getProfilesPath())
+	if err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() && entry.Name() != DEFAULT_PROFILE {
+				profiles = append(profiles, entry.Name())
+			}
+		}
+	}
+	sort.Strings(profiles[1:])
+	return profiles
+}
+
+func showProfiles() {
+	current := getProfile()
+	for _, profile := range listProfiles() {
+		marker := " "
+		if profile == current {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, profile)
+	}
+}
+
+// Creates a profile, with its own database and config file
+func createProfile(profile string) error {
+	if !PROFILE_NAME_PATTERN.MatchString(profile) || profile == DEFAULT_PROFILE {
+		return fmt.Errorf("Invalid profile \"%s\" (expected letters, digits, \"-\", \"_\" or \".\")", profile)
+	}
+	if _, err := os.Stat(getProfilePath(profile)); err == nil {
+		return fmt.Errorf("The profile %s already exists", profile)
+	}
+	if err := os.MkdirAll(getProfilePath(profile), 0755); err != nil {
+		return fmt.Errorf("Cannot create the profile: %w", err)
+	}
+	fmt.Printf("Profile %s created (its config is %s)\n", profile, getProfilePath(profile)+"/"+CONFIG_NAME)
+	return nil
+}
+
+// Makes a profile the current one, when neither --profile nor $MATE_PROFILE is given
+func switchProfile(profile string) error {
+	profileOption = profile
+	if err := checkProfile(); err != nil {
+		return err
+	}
+
+	path := getHomePath() + "/" + CURRENT_PROFILE_NAME
+	var err error
+	if profile == DEFAULT_PROFILE {
+		err = os.Remove(path)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	} else {
+		err = os.WriteFile(path, []byte(profile+"\n"), 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot switch the profile: %w", err)
+	}
+	fmt.Printf("Switched to the %s profile\n", profile)
+	if os.Getenv("MATE_PROFILE") != "" {
+		fmt.Println("(MATE_PROFILE is set and takes precedence)")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const PROJECT_FILE_NAME = ".mate"
+
+// Returns the path of the .mate file of the current directory or of its closest parent, if any
+func findProjectFile() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	for {
+		path := filepath.Join(dir, PROJECT_FILE_NAME)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Reads the options of the .mate file of the current project, in the format of the config file:
+//
+//	prefix = "PROJ-"   # prepended to the titles given to start
+//	client = "Acme"    # client of the started tickets, unless --client is given
+func getProjectOptions() (map[string]string, error) {
+	path, found := findProjectFile()
+	if !found {
+		return map[string]string{}, nil
+	}
+	return parseConfigFile(path)
+}
+
+// Prepends the prefix of the project to a title, unless it already starts with it or with a ticket key
+// A prefix ending with "-" is joined to ticket numbers ("PROJ-" and "123" give "PROJ-123"), other titles
+// are separated from it by a space ("PROJ Fix login")
+func applyProjectPrefix(prefix string, title string) string {
+	if prefix == "" || strings.HasPrefix(title, prefix) || getProject(title) != "" {
+		return title
+	}
+	if strings.HasSuffix(prefix, "-") && title != "" && unicode.IsDigit(rune(title[0])) {
+		return prefix + title
+	}
+	return strings.TrimRight(prefix, "- ") + " " + title
+}
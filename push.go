@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DEFAULT_SYNC_DAYS = 7
+const CLOCKIFY_API_URL = "https://api.clockify.me/api/v1"
+const HARVEST_API_URL = "https://api.harvestapp.com/v2"
+
+var PUSH_SERVICES = []string{"clockify", "harvest", "jira"}
+
+// Parses the --since and --until dates of a synchronization into [start, end[
+// Without dates, the last DEFAULT_SYNC_DAYS days are synchronized
+func getSyncRange(since string, until string) (start time.Time, end time.Time, err error) {
+	if since == "" {
+		since = getNow().AddDate(0, 0, -DEFAULT_SYNC_DAYS).Format(DATE_FORMAT)
+	}
+	if start, end, err = parseDateRange(since, until); err == nil && end.IsZero() {
+		end = getNow().Truncate(time.Hour*24).AddDate(0, 0, 1)
+	}
+	return
+}
+
+// Returns the completed intervals starting within [start, end[
+func getCompletedIntervals(records []Record, start time.Time, end time.Time) (completed []Interval) {
+	intervals := computeIntervals(records)
+	if len(records) != 0 && records[len(records)-1].title != STOP_TOKEN && !isAutoStopped(records) && len(intervals) != 0 {
+		intervals = intervals[:len(intervals)-1]
+	}
+	for _, in := range intervals {
+		if !in.start.Before(start) && in.start.Before(end) {
+			completed = append(completed, in)
+		}
+	}
+	return
+}
+
+// Returns the path of the table mapping the starts of the pushed intervals to their remote ID
+func getPushedPath(service string) string {
+	return getHomeFilePath(".mate." + service + ".csv")
+}
+
+// Returns an option that must be set, the error telling how to set it
+func getRequiredConfig(key string, hint string) (string, error) {
+	value := getConfig(key, "")
+	if value == "" {
+		return "", newConfigError(fmt.Sprintf("%s is required (%s)", key, hint))
+	}
+	return value, nil
+}
+
+// Returns the value mapped to the project of a ticket in the given config section
+// (e.g. clockify.projects.PROJ), or the default of the section
+func getMappedProject(section string, title string) string {
+	if project := getTicketProject(title); project != "" {
+		if value := getConfig(section+"."+project, ""); value != "" {
+			return value
+		}
+	}
+	return getConfig(section+".default", "")
+}
+
+// Uploads an interval as a Clockify time entry, its project and tags being mapped by
+// clockify.projects.<PROJECT> and clockify.tags.<tag> to Clockify IDs
+func pushToClockify(in Interval) (string, error) {
+	token, err := getRequiredConfig("clockify.token", "see https://app.clockify.me/user/settings")
+	if err != nil {
+		return "", err
+	}
+	workspace, err := getRequiredConfig("clockify.workspace_id", "see the URL of the workspace settings")
+	if err != nil {
+		return "", err
+	}
+	headers := map[string]string{"X-Api-Key": token}
+
+	entry := map[string]interface{}{
+		"start":       fromDbTime(in.start).UTC().Format(time.RFC3339),
+		"end":         fromDbTime(in.end).UTC().Format(time.RFC3339),
+		"description": in.title,
+		"billable":    getConfigBool("clockify.billable", true),
+	}
+	if project := getMappedProject("clockify.projects", in.title); project != "" {
+		entry["projectId"] = project
+	}
+	var tagIDs []string
+	for _, tag := range getTicketTags(in.title) {
+		if id := getConfig("clockify.tags."+tag, ""); id != "" {
+			tagIDs = append(tagIDs, id)
+		}
+	}
+	if len(tagIDs) != 0 {
+		entry["tagIds"] = tagIDs
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	err = callJSONAPI("POST", CLOCKIFY_API_URL+"/workspaces/"+workspace+"/time-entries", headers, entry, &created)
+	return created.ID, err
+}
+
+// Uploads an interval as a Harvest time entry
+// The project of a ticket is mapped by harvest.projects.<PROJECT> to "project_id" or "project_id/task_id",
+// the task defaulting to harvest.task_id
+func pushToHarvest(in Interval) (string, error) {
+	token, err := getRequiredConfig("harvest.token", "see https://id.getharvest.com/developers")
+	if err != nil {
+		return "", err
+	}
+	account, err := getRequiredConfig("harvest.account_id", "see https://id.getharvest.com/developers")
+	if err != nil {
+		return "", err
+	}
+	headers := map[string]string{"Authorization": "Bearer " + token, "Harvest-Account-Id": account, "User-Agent": "mate"}
+
+	mapped := getMappedProject("harvest.projects", in.title)
+	if mapped == "" {
+		key := "harvest.projects.default"
+		if project := getTicketProject(in.title); project != "" {
+			key = "harvest.projects." + project + " or " + key
+		}
+		return "", fmt.Errorf("no Harvest project for \"%s\" (set %s)", in.title, key)
+	}
+	parts := strings.SplitN(mapped, "/", 2)
+	task := getConfig("harvest.task_id", "")
+	if len(parts) == 2 {
+		task = parts[1]
+	}
+	projectID, err1 := strconv.ParseInt(parts[0], 10, 64)
+	taskID, err2 := strconv.ParseInt(task, 10, 64)
+	if err1 != nil || err2 != nil {
+		return "", fmt.Errorf("invalid Harvest project/task \"%s\" (expected numeric IDs, the task defaulting to harvest.task_id)", mapped)
+	}
+
+	start, end := fromDbTime(in.start), fromDbTime(in.end)
+	entry := map[string]interface{}{
+		"project_id": projectID,
+		"task_id":    taskID,
+		"spent_date": start.Format("2006-01-02"),
+		"hours":      float64(end.Sub(start).Round(time.Minute)) / float64(time.Hour),
+		"notes":      in.title,
+	}
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	err = callJSONAPI("POST", HARVEST_API_URL+"/time_entries", headers, entry, &created)
+	return fmt.Sprint(created.ID), err
+}
+
+// Tells if an interval can be pushed to the service
+// Jira worklogs need an issue key in the title, and at least a minute
+func canPush(service string, in Interval) bool {
+	if service != "jira" {
+		return true
+	}
+	return JIRA_KEY_PATTERN.MatchString(in.title) && in.end.Sub(in.start).Round(time.Minute) >= time.Minute
+}
+
+// Uploads the completed intervals between since and until to a time tracking service
+// Intervals already pushed are skipped; with dryRun, the intervals to push are only listed,
+// with confirm, each of them must be confirmed
+func pushEntries(service string, since string, until string, dryRun bool, confirm bool) error {
+	if !contains(PUSH_SERVICES, service) {
+		return fmt.Errorf("Invalid service \"%s\" (expected %s)", service, strings.Join(PUSH_SERVICES, ", "))
+	}
+	start, end, err := getSyncRange(since, until)
+	if err != nil {
+		return err
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+
+	pushed, err := readTable(getPushedPath(service))
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(os.Stdin)
+	count, failed := 0, 0
+	for _, in := range getCompletedIntervals(records, start, end) {
+		key := in.start.Format(TIME_FORMAT)
+		if _, found := pushed[key]; found || !canPush(service, in) {
+			continue
+		}
+
+		description := fmt.Sprintf("%s - %s %s (%v)", key, in.end.Format(CLOCK_FORMAT), in.title, in.end.Sub(in.start))
+		if dryRun {
+			fmt.Println("Would push " + description)
+			count++
+			continue
+		}
+		if confirm {
+			answer, ok := askUser(reader, fmt.Sprintf("Push %s? [Y/n/q]: ", description))
+			if !ok || answer == "q" || answer == "Q" {
+				break
+			}
+			if !contains([]string{"", "y", "Y"}, answer) {
+				continue
+			}
+		}
+
+		var id string
+		var err error
+		switch service {
+		case "clockify":
+			id, err = pushToClockify(in)
+		case "harvest":
+			id, err = pushToHarvest(in)
+		case "jira":
+			id, err = pushToJira(in)
+		}
+		if err != nil {
+			fmt.Printf("Cannot push %s (%s): %v\n", in.title, key, err)
+			failed++
+			continue
+		}
+		pushed[key] = id
+		count++
+	}
+
+	if dryRun {
+		fmt.Printf("%d entries to push to %s\n", count, service)
+		return nil
+	}
+	if err = writeTable(getPushedPath(service), "start,"+service+"_id\n", pushed); err != nil {
+		return err
+	}
+	fmt.Printf("%d entries pushed to %s\n", count, service)
+	if failed != 0 {
+		return fmt.Errorf("%d entries could not be pushed to %s", failed, service)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// How far back the tickets of mate recent are looked for, and how many are listed
+const RECENT_DAYS = 30
+const RECENT_LIMIT = 20
+
+// A ticket of mate recent, the argument being what to pass to mate start
+type RecentItem struct {
+	Title        string `json:"title"`
+	Subtitle     string `json:"subtitle"`
+	Arg          string `json:"arg"`
+	Autocomplete string `json:"autocomplete,omitempty"`
+	UID          string `json:"uid,omitempty"`
+}
+
+// Returns the tickets worked on during the last RECENT_DAYS, the last one first, whose title contains the query
+// A query matching no title exactly comes first, to start a new ticket
+func getRecentItems(records []Record, query string) (items []RecentItem) {
+	query = strings.TrimSpace(query)
+	seen := make(map[string]bool)
+	running := len(records) != 0 && records[len(records)-1].title != STOP_TOKEN && !isAutoStopped(records)
+	for i := len(records) - 1; i >= 0 && len(items) < RECENT_LIMIT; i-- {
+		r := records[i]
+		if r.title == STOP_TOKEN || seen[r.title] {
+			continue
+		}
+		seen[r.title] = true
+		if !strings.Contains(strings.ToLower(r.title), strings.ToLower(query)) {
+			continue
+		}
+		subtitle := "Last worked on " + r.timestamp.Format("Mon 2 Jan 15:04")
+		if running && i == len(records)-1 {
+			subtitle = "Running since " + r.timestamp.Format(CLOCK_FORMAT)
+		}
+		items = append(items, RecentItem{r.title, subtitle, r.title, r.title, r.title})
+	}
+	if query != "" && !seen[query] {
+		items = append([]RecentItem{{"Start \"" + query + "\"", "New ticket", query, "", ""}}, items...)
+	}
+	return
+}
+
+// Prints the recent tickets, one per line, or for a launcher:
+//   - alfred: the JSON of a Script Filter, whose argument goes to "mate start \"$1\""
+//   - raycast: the items as a JSON array, for a script command
+func showRecentTickets(query string, format string) error {
+	records, err := getRecentRecords(getNow().AddDate(0, 0, -RECENT_DAYS))
+	if err != nil {
+		return err
+	}
+	items := getRecentItems(records, query)
+
+	encoder := json.NewEncoder(os.Stdout)
+	switch format {
+	case "alfred":
+		if items == nil {
+			items = []RecentItem{}
+		}
+		return encoder.Encode(struct {
+			Items []RecentItem `json:"items"`
+		}{items})
+	case "raycast":
+		if items == nil {
+			items = []RecentItem{}
+		}
+		for i := range items {
+			items[i].Autocomplete, items[i].UID = "", ""
+		}
+		return encoder.Encode(items)
+	}
+	if len(items) == 0 {
+		return withExitCode(EXIT_NO_DATA, fmt.Errorf("No ticket in the last %d days", RECENT_DAYS))
+	}
+	for _, item := range items {
+		fmt.Printf("%s\t%s\n", item.Arg, item.Subtitle)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// The commands run on the remote when one is set, the others running locally
+var REMOTE_COMMANDS = []string{"start", "switch", "stop", "info", "log"}
+
+// Returns the remote a command runs on, empty to run it locally
+// The remote of the config only applies to the commands available remotely, --remote failing for the others
+func getCommandRemote(in *Invocation) string {
+	if remote := in.option("remote"); remote != "" {
+		return remote
+	}
+	if contains(REMOTE_COMMANDS, in.command.name) {
+		return getConfig("remote", "")
+	}
+	return ""
+}
+
+// Calls the API of the remote mate server, authenticated by remote_token or $MATE_REMOTE_TOKEN
+func callRemote(remote string, method string, path string, body interface{}, out interface{}) error {
+	token := getConfig("remote_token", os.Getenv("MATE_REMOTE_TOKEN"))
+	if token == "" {
+		return newConfigError("remote_token is required (the serve.token of the remote)")
+	}
+	headers := map[string]string{"Authorization": "Bearer " + token}
+	if err := callJSONAPI(method, strings.TrimRight(remote, "/")+path, headers, body, out); err != nil {
+		return fmt.Errorf("Cannot reach %s: %w", remote, err)
+	}
+	return nil
+}
+
+// Runs a command against a mate server (see "mate serve") instead of the local database
+// Only the commands of the API are available: start, switch, stop, info and log (for today), without their options
+func runRemoteCommand(remote string, in *Invocation) error {
+	name := in.command.name
+	if !contains(REMOTE_COMMANDS, name) {
+		return fmt.Errorf("Only %s are available with a remote (%s)", strings.Join(REMOTE_COMMANDS, ", "), remote)
+	}
+	// The API takes a title only: the options are rejected rather than dropped
+	for _, option := range in.command.options {
+		if in.option(option.name) != "" || in.flag(option.name) {
+			return in.fail(fmt.Sprintf("The --%s option is not available with a remote", option.name))
+		}
+	}
+
+	var status StatusResponse
+	switch name {
+	case "start", "switch":
+		if len(in.args) != 1 {
+			return in.fail(fmt.Sprintf("The %s command requires a title with a remote", name))
+		}
+		if err := callRemote(remote, "POST", "/api/"+name, StartRequest{in.arg(0)}, &status); err != nil {
+			return err
+		}
+		fmt.Printf("STARTING %s\n", status.Title)
+	case "stop":
+		if err := callRemote(remote, "GET", "/api/status", nil, &status); err != nil {
+			return err
+		}
+		if !status.Running {
+			return errNotWorking
+		}
+		title := status.Title
+		if err := callRemote(remote, "POST", "/api/stop", nil, &status); err != nil {
+			return err
+		}
+		fmt.Printf("STOPPING %s\n", title)
+	case "info":
+		if err := callRemote(remote, "GET", "/api/status", nil, &status); err != nil {
+			return err
+		}
+		if status.Running {
+			fmt.Printf("Working on %s (%v)\n", status.Title, time.Duration(status.ElapsedSeconds)*time.Second)
+		} else {
+			fmt.Printf("Currently not working\n")
+		}
+		if dayDiff := time.Duration(status.TargetSeconds-status.TodaySeconds) * time.Second; dayDiff > 0 {
+			fmt.Printf("Still %v to work\n", dayDiff)
+		} else {
+			fmt.Printf("You're done for today (+%v)\n", dayDiff*-1)
+		}
+	case "log":
+		var report ReportResponse
+		if err := callRemote(remote, "GET", "/api/report", nil, &report); err != nil {
+			return err
+		}
+		if len(report.Tickets) == 0 {
+			fmt.Println("Nothing to show (yet)")
+		}
+		for _, t := range report.Tickets {
+			fmt.Printf("%s\t%v\n", t.Title, time.Duration(t.Seconds)*time.Second)
+		}
+	}
+	return nil
+}
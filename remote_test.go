@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Parses the arguments of a command of the CLI, as after "mate"
+func parseTestInvocation(t *testing.T, args ...string) *Invocation {
+	command := findCommand(getCommands(), args[0])
+	if command == nil {
+		t.Fatalf("unknown command %s", args[0])
+	}
+	in, err := parseInvocation(command, args[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return in
+}
+
+func TestGetCommandRemote(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		config string
+		want   string
+	}{
+		{"no remote", []string{"start", "A"}, "", ""},
+		{"remote command, remote in the config", []string{"start", "A"}, "https://config", "https://config"},
+		{"alias of a remote command", []string{"x"}, "https://config", "https://config"},
+		{"local command, remote in the config", []string{"secret", "list"}, "https://config", ""},
+		{"remote option", []string{"start", "A", "--remote", "https://option"}, "https://config", "https://option"},
+		{"local command, remote option", []string{"secret", "list", "--remote", "https://option"}, "", "https://option"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, "")
+			if test.config != "" {
+				config["remote"] = test.config
+			}
+			if got := getCommandRemote(parseTestInvocation(t, test.args...)); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRunRemoteCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantErr  bool
+		wantPath string
+	}{
+		{"start", []string{"start", "A"}, false, "/api/start"},
+		{"switch", []string{"switch", "A"}, false, "/api/switch"},
+		{"start without a title", []string{"start"}, true, ""},
+		{"start with an option", []string{"start", "A", "--for", "1h"}, true, ""},
+		{"start with a flag", []string{"start", "--git"}, true, ""},
+		{"local command", []string{"secret", "list"}, true, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, "")
+			config["remote_token"] = "token"
+			var paths []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				_ = json.NewEncoder(w).Encode(StatusResponse{Running: true, Title: "A"})
+			}))
+			defer server.Close()
+
+			err := runRemoteCommand(server.URL, parseTestInvocation(t, test.args...))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if got := strings.Join(paths, ","); got != test.wantPath {
+				t.Errorf("got calls to %q, want %q", got, test.wantPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Gives the entries of the given tickets the title into, returning the entries changed (as they were)
+func retitleRecords(records []Record, titles []string, into string) (retitled []Record, changed []Record) {
+	for _, r := range records {
+		if r.title != into && contains(titles, r.title) {
+			changed = append(changed, r)
+			r.title = into
+		}
+		retitled = append(retitled, r)
+	}
+	return
+}
+
+// Renames a ticket in the whole database, after showing the entries changed and asking for confirmation
+func renameTicket(old string, new string, yes bool) error {
+	return retitleTickets([]string{old}, new, yes)
+}
+
+// Consolidates the entries of several tickets under the title into, which may be one of them
+func mergeTickets(titles []string, into string, yes bool) error {
+	return retitleTickets(titles, into, yes)
+}
+
+// Gives the entries of the given tickets the title into, after showing them and asking for confirmation
+func retitleTickets(titles []string, into string, yes bool) error {
+	if into == STOP_TOKEN || into == "" || contains(titles, STOP_TOKEN) {
+		return errors.New("Only tickets can be renamed, to a non empty title")
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	retitled, changed := retitleRecords(records, titles, into)
+	if len(changed) == 0 {
+		return withExitCode(EXIT_NO_DATA, fmt.Errorf("No entry of \"%s\"", strings.Join(titles, "\", \"")))
+	}
+
+	for _, title := range titles {
+		var count int
+		var first, last Record
+		for _, r := range changed {
+			if r.title != title {
+				continue
+			}
+			if count == 0 {
+				first = r
+			}
+			count++
+			last = r
+		}
+		if count != 0 {
+			fmt.Printf("%d entries of \"%s\", from %s to %s\n", count, title,
+				first.timestamp.Format(TIME_FORMAT), last.timestamp.Format(TIME_FORMAT))
+		}
+	}
+	if !yes {
+		answer, ok := askUser(bufio.NewReader(os.Stdin), fmt.Sprintf("Rename them to \"%s\"? [y/N]: ", into))
+		if !ok || !contains([]string{"y", "Y"}, answer) {
+			fmt.Println("Command canceled")
+			return nil
+		}
+	}
+	if err = writeRecords(retitled); err != nil {
+		return err
+	}
+	fmt.Printf("%d entries renamed to \"%s\" (undo with mate undo)\n", len(changed), into)
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The time spent on the tickets of a project during a week
+type ProjectReport struct {
+	name    string
+	total   time.Duration
+	tickets []TicketTotal
+}
+
+// The weekly grouped report: the time worked per day, and per project then ticket
+type WeekReport struct {
+	monday   time.Time
+	days     [7]time.Duration
+	target   time.Duration
+	total    time.Duration
+	projects []ProjectReport
+}
+
+// Computes the report of the week starting on monday, the projects and their tickets
+// being sorted by the time spent on them
+func computeWeekReport(records []Record, monday time.Time) WeekReport {
+	report := WeekReport{monday: monday}
+	perDay := computeTicketTotalsPerDay(records)
+	tickets := make(map[string]time.Duration)
+	for i := range report.days {
+		day := monday.AddDate(0, 0, i)
+		report.target += getDayTarget(day)
+		for title, duration := range perDay[day.Format(DATE_FORMAT)] {
+			report.days[i] += duration
+			tickets[title] += duration
+		}
+		report.total += report.days[i]
+	}
+	report.projects = groupTicketsByProject(tickets)
+	return report
+}
+
+// Groups the time spent per ticket by project, the projects and their tickets being sorted by the time spent on them
+func groupTicketsByProject(tickets map[string]time.Duration) (projects []ProjectReport) {
+	positions := make(map[string]int)
+	for title, duration := range tickets {
+		project := getTicketProject(title)
+		if project == "" {
+			project = NO_PROJECT
+		}
+		position, found := positions[project]
+		if !found {
+			position = len(projects)
+			positions[project] = position
+			projects = append(projects, ProjectReport{name: project})
+		}
+		projects[position].total += duration
+		projects[position].tickets = append(projects[position].tickets, TicketTotal{title, duration})
+	}
+	sortProjectReports(projects)
+	return projects
+}
+
+// Sorts the projects and their tickets by the time spent on them
+func sortProjectReports(projects []ProjectReport) {
+	sort.Slice(projects, func(i, j int) bool {
+		a, b := projects[i], projects[j]
+		return a.total > b.total || a.total == b.total && a.name < b.name
+	})
+	for _, project := range projects {
+		sort.Slice(project.tickets, func(i, j int) bool {
+			a, b := project.tickets[i], project.tickets[j]
+			return a.duration > b.duration || a.duration == b.duration && a.title < b.title
+		})
+	}
+}
+
+func (report WeekReport) title() string {
+	return "Week of " + report.monday.Format(DATE_FORMAT)
+}
+
+// Formats the report as plain text
+func formatWeekReportText(report WeekReport) string {
+	lines := []string{fmt.Sprintf("%s: %s / %s", report.title(), formatMinutes(report.total), formatMinutes(report.target)), ""}
+	for i, duration := range report.days {
+		lines = append(lines, fmt.Sprintf("%s  %s", report.monday.AddDate(0, 0, i).Format("Mon 01/02"), formatMinutes(duration)))
+	}
+	for _, project := range report.projects {
+		lines = append(lines, "", fmt.Sprintf("%s: %s", project.name, formatMinutes(project.total)), formatTicketTotals(project.tickets, "  -"))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Formats the report as an HTML document, for the email clients showing HTML
+func formatWeekReportHTML(report WeekReport) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "<!DOCTYPE html>\n<html><body style=\"font-family: sans-serif\">\n<h2>%s</h2>\n", html.EscapeString(report.title()))
+	fmt.Fprintf(&builder, "<p>Worked <b>%s</b> of %s</p>\n<table cellpadding=\"4\">\n",
+		formatMinutes(report.total), formatMinutes(report.target))
+	for i, duration := range report.days {
+		fmt.Fprintf(&builder, "<tr><td>%s</td><td align=\"right\">%s</td></tr>\n",
+			report.monday.AddDate(0, 0, i).Format("Mon 01/02"), formatMinutes(duration))
+	}
+	builder.WriteString("</table>\n")
+	for _, project := range report.projects {
+		fmt.Fprintf(&builder, "<h3>%s: %s</h3>\n<ul>\n", html.EscapeString(project.name), formatMinutes(project.total))
+		for _, ticket := range project.tickets {
+			fmt.Fprintf(&builder, "<li>%s: %s</li>\n", html.EscapeString(ticket.title), formatMinutes(ticket.duration))
+		}
+		builder.WriteString("</ul>\n")
+	}
+	builder.WriteString("</body></html>\n")
+	return builder.String()
+}
+
+// Prints the grouped report of the week of the given day, or with email sends it to the recipients
+// (email.to by default, else a comma separated list)
+func showWeekReport(date string, email bool, to string) error {
+	day, err := parseDate(date)
+	if err != nil {
+		return err
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	report := computeWeekReport(records, getWeekStart(day))
+	if !email {
+		fmt.Print(formatWeekReportText(report))
+		return nil
+	}
+	if report.total == 0 {
+		return withExitCode(EXIT_NO_DATA, fmt.Errorf("Nothing tracked during the week of %s, nothing sent", report.monday.Format(DATE_FORMAT)))
+	}
+	return sendWeekReport(report, to)
+}
+
+// Sends the report by email, in HTML with a plain text alternative
+func sendWeekReport(report WeekReport, to string) error {
+	if to == "" {
+		to = getConfig("email.to", "")
+	}
+	recipients := splitConfigList(to)
+	if len(recipients) == 0 {
+		return newConfigError("email.to is required (the recipients of the report, comma separated), or pass --to")
+	}
+	subject := "mate: " + report.title()
+	if err := sendEmail(recipients, subject, formatWeekReportText(report), formatWeekReportHTML(report)); err != nil {
+		return fmt.Errorf("Cannot send the report: %w", err)
+	}
+	fmt.Printf("Report of the week of %s sent to %s\n", report.monday.Format(DATE_FORMAT), strings.Join(recipients, ", "))
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Sorts the records by timestamp, keeping the order of entries sharing the same timestamp
+func sortRecords(records []Record) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].timestamp.Before(records[j].timestamp)
+	})
+}
+
+// Assigns an untracked gap to a ticket
+// The STOP entries of the gap are replaced by a single entry starting with the gap
+func fillGap(records []Record, gap Gap, title string) (outRecords []Record) {
+	for _, r := range records {
+		inGap := !r.timestamp.Before(gap.start) && r.timestamp.Before(gap.end)
+		if inGap && r.title == STOP_TOKEN {
+			continue
+		}
+		outRecords = append(outRecords, r)
+	}
+	outRecords = append(outRecords, Record{gap.start, title})
+	sortRecords(outRecords)
+	return
+}
+
+// Returns the title of the ticket running right before and right after the gap
+func gapNeighbours(intervals []Interval, gap Gap) (before string, after string) {
+	for _, in := range intervals {
+		if in.end.Equal(gap.start) {
+			before = in.title
+		}
+		if in.start.Equal(gap.end) {
+			after = in.title
+		}
+	}
+	return
+}
+
+func fillGapsInteractively(date string) error {
+	day, err := parseDate(date)
+	if err != nil {
+		return err
+	}
+
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	intervals := clipIntervalsToDay(computeIntervals(records), day)
+	gaps := findGaps(intervals, MIN_GAP_DURATION)
+	if len(gaps) == 0 {
+		fmt.Printf("No untracked time on %s\n", day.Format(DATE_FORMAT))
+		return nil
+	}
+
+	var titles []string
+	for _, in := range intervals {
+		if !contains(titles, in.title) {
+			titles = append(titles, in.title)
+		}
+	}
+
+	fmt.Printf("%d untracked period(s) on %s\n", len(gaps), day.Format(DATE_FORMAT))
+	fmt.Println("Tickets of the day:")
+	for i, title := range titles {
+		fmt.Printf("  %d. %s\n", i+1, title)
+	}
+
+	// The tickets seen in the active window by the daemon (see daemon.track_windows)
+	samples, err := readWindowSamples()
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	filled := 0
+	for _, g := range gaps {
+		before, after := gapNeighbours(intervals, g)
+		fmt.Println()
+		fmt.Printf("%s - %s (%v), between \"%s\" and \"%s\"\n",
+			g.start.Format(CLOCK_FORMAT), g.end.Format(CLOCK_FORMAT), g.end.Sub(g.start), before, after)
+
+		question := "Ticket number or new title (empty for a break, q to quit): "
+		suggested, seen := suggestGapTicket(samples, g)
+		if suggested != "" {
+			fmt.Printf("Suggested: %s (in the active window for about %v)\n", suggested, seen)
+			question = "Ticket number or new title (empty for the suggestion, - for a break, q to quit): "
+		}
+		answer, ok := askUser(reader, question)
+		if !ok || answer == "q" {
+			break
+		}
+		if answer == "" && suggested != "" {
+			answer = suggested
+		}
+		if answer == "" || answer == "-" {
+			continue
+		}
+
+		title := answer
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(titles) {
+			title = titles[n-1]
+		}
+		records = fillGap(records, g, title)
+		fmt.Printf("Assigned to %s\n", title)
+		filled++
+	}
+
+	if filled == 0 {
+		fmt.Println("Nothing changed")
+		return nil
+	}
+	if err = writeRecords(records); err != nil {
+		return err
+	}
+	fmt.Printf("%d period(s) filled\n", filled)
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A rule of the [rules] section: the project, tags and category of the tickets whose title matches
+type Rule struct {
+	pattern  *regexp.Regexp
+	project  string
+	tags     []string
+	category string
+}
+
+// Compiles the rules of the [rules] section, a regular expression (quoted if need be) for each list of fields:
+//
+//	[rules]
+//	"(?i)standup|planning" = "category=meeting tags=sync"
+//	"^Customer call" = "project=SUP tags=support,call"
+//
+// The patterns are tried in their alphabetical order, the first project and category found winning
+func compileRules(options map[string]string) (rules []Rule, err error) {
+	var keys []string
+	for key := range options {
+		if strings.HasPrefix(key, "rules.") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		pattern, err := regexp.Compile(strings.TrimPrefix(key, "rules."))
+		if err != nil {
+			return nil, fmt.Errorf("%s: Invalid pattern (%v)", key, err)
+		}
+		rule := Rule{pattern: pattern}
+		for _, field := range strings.Fields(options[key]) {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 || parts[1] == "" {
+				return nil, fmt.Errorf("%s: Invalid field \"%s\" (expected project=, tags= or category=)", key, field)
+			}
+			switch parts[0] {
+			case "project":
+				rule.project = parts[1]
+			case "tags":
+				rule.tags = strings.Split(parts[1], ",")
+			case "category":
+				if err = checkCategory(parts[1]); err != nil {
+					return nil, fmt.Errorf("%s: %v", key, err)
+				}
+				rule.category = parts[1]
+			default:
+				return nil, fmt.Errorf("%s: Invalid field \"%s\" (expected project=, tags= or category=)", key, field)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Returns the rules of the config, validated by loadConfig
+func getRules() []Rule {
+	rules, _ := compileRules(config)
+	return rules
+}
+
+// Merges the rules matching a title: the project and category of the first ones giving them, and every tag
+// The category is a tag too, so that it is saved with the entry
+func matchRules(title string) (match Rule) {
+	if title == STOP_TOKEN {
+		return
+	}
+	for _, rule := range getRules() {
+		if !rule.pattern.MatchString(title) {
+			continue
+		}
+		if match.project == "" {
+			match.project = rule.project
+		}
+		if match.category == "" {
+			match.category = rule.category
+		}
+		for _, tag := range rule.tags {
+			if !contains(match.tags, tag) {
+				match.tags = append(match.tags, tag)
+			}
+		}
+	}
+	if match.category != "" && !contains(match.tags, match.category) {
+		match.tags = append(match.tags, match.category)
+	}
+	return
+}
+
+// Appends the tags given by the rules to a title, as hashtags, so that they are saved with the entry
+func applyRules(title string) string {
+	tags := getTags(title)
+	for _, tag := range matchRules(title).tags {
+		if !contains(tags, tag) {
+			title += " #" + tag
+			tags = append(tags, tag)
+		}
+	}
+	return title
+}
+
+// Returns the project of a ticket: the key prefix of its title, else the one given by the rules
+func getTicketProject(title string) string {
+	if project := getProject(title); project != "" {
+		return project
+	}
+	return matchRules(title).project
+}
+
+// Applies the rules to the entries already saved, or with dryRun only shows the titles that would change
+func categorizeEntries(dryRun bool, yes bool) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	changes := make(map[string]string)
+	counts := make(map[string]int)
+	var titles []string
+	categorized := make([]Record, len(records))
+	for i, r := range records {
+		categorized[i] = r
+		if r.title == STOP_TOKEN {
+			continue
+		}
+		if _, found := changes[r.title]; !found {
+			changes[r.title] = applyRules(r.title)
+			if changes[r.title] != r.title {
+				titles = append(titles, r.title)
+			}
+		}
+		if changes[r.title] != r.title {
+			categorized[i].title = changes[r.title]
+			counts[r.title]++
+		}
+	}
+	if len(titles) == 0 {
+		fmt.Println("Nothing to categorize")
+		return nil
+	}
+
+	sort.Strings(titles)
+	for _, title := range titles {
+		fmt.Printf("%s -> %s (%d entries)\n", title, changes[title], counts[title])
+	}
+	if dryRun {
+		return nil
+	}
+	if !yes {
+		answer, ok := askUser(bufio.NewReader(os.Stdin), fmt.Sprintf("Rename %d tickets? [y/N]: ", len(titles)))
+		if !ok || !contains([]string{"y", "Y"}, answer) {
+			fmt.Println("Command canceled")
+			return nil
+		}
+	}
+	if err = writeRecords(categorized); err != nil {
+		return err
+	}
+	fmt.Printf("%d tickets categorized (undo with mate undo)\n", len(titles))
+	return nil
+}
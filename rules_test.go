@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCompileRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]string
+		wantErr bool
+		want    int
+	}{
+		{"no rules", map[string]string{"target": "8h"}, false, 0},
+		{"rules", map[string]string{"rules.^API": "project=API tags=backend", "rules.(?i)standup": "category=meeting"}, false, 2},
+		{"invalid pattern", map[string]string{"rules.(": "tags=a"}, true, 0},
+		{"field without a value", map[string]string{"rules.^API": "tags="}, true, 0},
+		{"unknown field", map[string]string{"rules.^API": "owner=me"}, true, 0},
+		{"unknown category", map[string]string{"rules.^API": "category=nap"}, true, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := compileRules(test.options)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if len(rules) != test.want {
+				t.Errorf("got %d rules, want %d", len(rules), test.want)
+			}
+		})
+	}
+}
+
+func TestApplyRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"no match", "Lunch", "Lunch"},
+		{"tags", "API login", "API login #backend"},
+		{"tag already given", "API login #backend", "API login #backend #review"},
+		{"category as a tag", "Daily standup", "Daily standup #meeting"},
+		// The tags added are not matched again
+		{"tag of another rule", "API standup", "API standup #backend #meeting"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, "")
+			config["rules.^API"] = "tags=backend"
+			config["rules.(?i)standup"] = "category=meeting"
+			config["rules.#backend"] = "tags=review"
+			if got := applyRules(test.title); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStartTicketAppliesRulesOnce(t *testing.T) {
+	useMemoryStore(t, "")
+	config["rules.^API"] = "tags=backend"
+	config["rules.#backend"] = "tags=review"
+	if err := startTicket("API login"); err != nil {
+		t.Fatal(err)
+	}
+	records, err := getRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].title != "API login #backend" {
+		t.Errorf("got %v, want the title tagged once", records)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const DEFAULT_WORK_DAY = "7h30m"
+
+// Returns the time to work on the given day, as set in the [schedule] section:
+//
+//	[schedule]
+//	mon = "8h"
+//	fri = "6h"
+//
+// Weekdays default to schedule.default (7h30m), Saturday, Sunday and public holidays to 0
+func getDayTarget(day time.Time) time.Duration {
+	if _, holiday := getHoliday(day); holiday {
+		return 0
+	}
+	defaultTarget := getConfig("schedule.default", DEFAULT_WORK_DAY)
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		defaultTarget = "0"
+	}
+	weekday := strings.ToLower(day.Weekday().String()[:3])
+	return getConfigDuration("schedule."+weekday, defaultTarget)
+}
+
+// Returns the Monday of the week of the given day
+func getWeekStart(day time.Time) time.Time {
+	daysSinceMonday := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -daysSinceMonday)
+}
+
+// Prints the time worked each day of the week, compared to the schedule, with the running flex balance
+func showWeek(date string) error {
+	day, err := parseDate(date)
+	if err != nil {
+		return err
+	}
+
+	daily, err := getDailyTotals()
+	if err != nil {
+		return err
+	}
+	totals := daily.totalsPerDay()
+	monday := getWeekStart(day)
+	today := getNow().Truncate(time.Hour * 24)
+	balance, err := computeBalance(daily.firstDay, totals, monday)
+	if err != nil {
+		return err
+	}
+	balanceStart, _ := getBalanceStart(daily.firstDay)
+	daysOff, err := readDaysOff()
+	if err != nil {
+		return err
+	}
+	var weekTotal, weekTarget time.Duration
+
+	fmt.Printf("Week of %s\n", monday.Format(DATE_FORMAT))
+	for i := 0; i < 7; i++ {
+		current := monday.AddDate(0, 0, i)
+		total, target := totals[current.Format(DATE_FORMAT)], getDayTarget(current)
+		credit := getDayCredit(current, daysOff)
+		weekTotal += total
+		weekTarget += target
+
+		offType := ""
+		if t, off := daysOff[current.Format(DATE_FORMAT)]; off {
+			offType = "\t" + t
+		} else if name, holiday := getHoliday(current); holiday {
+			offType = "\t" + name
+		}
+		if current.After(today) {
+			fmt.Printf("%s\t-\t/ %v%s\n", current.Format("Mon 01/02"), target, offType)
+			continue
+		}
+		if !current.Before(balanceStart) {
+			balance += total + credit - target
+		}
+		fmt.Printf("%s\t%v\t/ %v\t%s\t(balance %s)%s\n", current.Format("Mon 01/02"), total, target,
+			formatBalance(total+credit-target), formatBalance(balance), offType)
+	}
+	fmt.Printf("Total\t\t%v\t/ %v\n", weekTotal, weekTarget)
+	return nil
+}
+
+// Formats a difference of time with its sign
+func formatBalance(balance time.Duration) string {
+	if balance < 0 {
+		return fmt.Sprintf("-%v", -balance)
+	}
+	return fmt.Sprintf("+%v", balance)
+}
+
+// Computes the time worked per day, keyed by DATE_FORMAT, minus the mandatory breaks
+// Intervals spanning midnight are split between their days
+func computeTotalsPerDay(records []Record) map[string]time.Duration {
+	totals := make(map[string]time.Duration)
+	for key, intervals := range splitIntervalsPerDay(computeIntervals(records)) {
+		for _, in := range intervals {
+			totals[key] += in.end.Sub(in.start)
+		}
+		totals[key] -= computeBreakDeduction(intervals)
+	}
+	return totals
+}
+
+// Groups the intervals per day, keyed by DATE_FORMAT, those spanning midnight being split between their days
+func splitIntervalsPerDay(intervals []Interval) map[string][]Interval {
+	intervalsPerDay := make(map[string][]Interval)
+	for _, in := range intervals {
+		for _, part := range splitIntervalAtMidnight(in) {
+			key := part.start.Format(DATE_FORMAT)
+			intervalsPerDay[key] = append(intervalsPerDay[key], part)
+		}
+	}
+	return intervalsPerDay
+}
+
+// Splits an interval into its parts within each day, in order
+func splitIntervalAtMidnight(in Interval) (parts []Interval) {
+	for start := in.start; start.Before(in.end); {
+		end := start.Truncate(time.Hour*24).AddDate(0, 0, 1)
+		if end.After(in.end) {
+			end = in.end
+		}
+		parts = append(parts, Interval{start, end, in.title})
+		start = end
+	}
+	return
+}
+
+// Returns the first day accounted in the flex balance: balance.since, or the day of the first entry
+// (zero if there is none)
+func getBalanceStart(firstDay time.Time) (time.Time, bool) {
+	if getConfig("balance.since", "") != "" {
+		return getTypedConfig("balance.since", "date", "").(time.Time), true
+	}
+	return firstDay, !firstDay.IsZero()
+}
+
+// Returns the initial flex balance, as set by balance.initial (e.g. "-2h30m")
+func getInitialBalance() time.Duration {
+	return getTypedConfig("balance.initial", "signed duration", "0").(time.Duration)
+}
+
+// Computes the flex balance: the time worked minus the target of each day, from the balance start until the given day (excluded)
+// Days off count as fully worked
+func computeBalance(firstDay time.Time, totals map[string]time.Duration, until time.Time) (time.Duration, error) {
+	daysOff, err := readDaysOff()
+	if err != nil {
+		return 0, err
+	}
+	balance := getInitialBalance()
+	start, found := getBalanceStart(firstDay)
+	if !found {
+		return balance, nil
+	}
+	for day := start; day.Before(until); day = day.AddDate(0, 0, 1) {
+		balance += totals[day.Format(DATE_FORMAT)] + getDayCredit(day, daysOff) - getDayTarget(day)
+	}
+	return balance, nil
+}
+
+// Prints the time worked each day of the month, with the days off
+func showMonth(date string) error {
+	day, err := parseDate(date)
+	if err != nil {
+		return err
+	}
+
+	daily, err := getDailyTotals()
+	if err != nil {
+		return err
+	}
+	totals := daily.totalsPerDay()
+	daysOff, err := readDaysOff()
+	if err != nil {
+		return err
+	}
+	first := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	today := getNow().Truncate(time.Hour * 24)
+	var monthTotal, monthTarget, monthCredit time.Duration
+	offCounts := make(map[string]int)
+
+	fmt.Println(first.Format("January 2006"))
+	for current := first; current.Month() == first.Month(); current = current.AddDate(0, 0, 1) {
+		key := current.Format(DATE_FORMAT)
+		total, target := totals[key], getDayTarget(current)
+		monthTarget += target
+		monthTotal += total
+		monthCredit += getDayCredit(current, daysOff)
+
+		if offType, off := daysOff[key]; off {
+			offCounts[offType]++
+			fmt.Printf("%s\t%v\t%s\n", current.Format("Mon 01/02"), total, offType)
+		} else if name, holiday := getHoliday(current); holiday {
+			fmt.Printf("%s\t%v\t%s\n", current.Format("Mon 01/02"), total, name)
+		} else if total != 0 || (target != 0 && !current.After(today)) {
+			fmt.Printf("%s\t%v\t/ %v\n", current.Format("Mon 01/02"), total, target)
+		}
+	}
+
+	fmt.Printf("Worked\t%v / %v\n", monthTotal, monthTarget)
+	for _, offType := range OFF_TYPES {
+		if offCounts[offType] != 0 {
+			fmt.Printf("%s\t%d day(s)\n", offType, offCounts[offType])
+		}
+	}
+	if monthCredit != 0 {
+		fmt.Printf("Credited\t%v\n", monthCredit)
+	}
+	return nil
+}
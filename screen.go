@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const SCREEN_POLL_INTERVAL = time.Second * 5
+
+// A screen lock or unlock
+type ScreenEvent struct {
+	locked bool
+	at     time.Time
+}
+
+// Sends the screen lock changes on the returned channel
+// On Linux, the ScreenSaver D-Bus signals are followed through dbus-monitor
+// On macOS, the lock state of the session is polled
+func watchScreen() (<-chan ScreenEvent, error) {
+	events := make(chan ScreenEvent)
+
+	if runtime.GOOS == "darwin" {
+		go pollMacScreenLock(events)
+		return events, nil
+	}
+
+	cmd := exec.Command("dbus-monitor", "--session",
+		"type='signal',interface='org.freedesktop.ScreenSaver',member='ActiveChanged'",
+		"type='signal',interface='org.gnome.ScreenSaver',member='ActiveChanged'")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		// The value of the signal is on the line following its header
+		scanner := bufio.NewScanner(stdout)
+		signalSeen := false
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			switch {
+			case strings.Contains(line, "member=ActiveChanged"):
+				signalSeen = true
+			case signalSeen && strings.HasPrefix(line, "boolean "):
+				events <- ScreenEvent{line == "boolean true", getNow()}
+				signalSeen = false
+			}
+		}
+		cmd.Wait()
+	}()
+	return events, nil
+}
+
+// Tells if the macOS session is locked
+func isMacScreenLocked() bool {
+	output, err := exec.Command("ioreg", "-n", "Root", "-d1").Output()
+	return err == nil && strings.Contains(string(output), "\"CGSSessionScreenIsLocked\"=Yes")
+}
+
+func pollMacScreenLock(events chan<- ScreenEvent) {
+	locked := isMacScreenLocked()
+	for range time.Tick(SCREEN_POLL_INTERVAL) {
+		if nowLocked := isMacScreenLocked(); nowLocked != locked {
+			locked = nowLocked
+			events <- ScreenEvent{locked, getNow()}
+		}
+	}
+}
+
+// Pauses the running ticket while the screen is locked, and offers to resume it on unlock
+type ScreenWatcher struct {
+	paused Record
+}
+
+func (w *ScreenWatcher) handle(event ScreenEvent) error {
+	if event.locked {
+		paused, ok, err := pauseRunningTicket(event.at)
+		if ok {
+			w.paused = paused
+			announceDaemonEvent(fmt.Sprintf("%s paused, screen locked", paused.title))
+		}
+		return err
+	}
+
+	paused := w.paused
+	w.paused = Record{}
+	if paused.title == "" {
+		return nil
+	}
+
+	if !isTerminal(os.Stdin) {
+		notify(fmt.Sprintf("Welcome back! Run \"mate start\" to resume %s", paused.title))
+		return nil
+	}
+	notify(fmt.Sprintf("Welcome back! Resume %s?", paused.title))
+	answer, ok := askUser(bufio.NewReader(os.Stdin), fmt.Sprintf("Screen unlocked, resume %s? [Y/n]: ", paused.title))
+	if !ok || !contains([]string{"", "y", "Y"}, answer) {
+		return nil
+	}
+	resumed, err := resumePausedTicket(paused, getNow())
+	if resumed {
+		fmt.Printf("STARTING %s\n", paused.title)
+	}
+	return err
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DEFAULT_LISTEN = "127.0.0.1:8080"
+
+// The web dashboard, served at the root
+//
+//go:embed web
+var webAssets embed.FS
+
+// The state of the timer returned by the API
+type StatusResponse struct {
+	Running        bool   `json:"running"`
+	Title          string `json:"title,omitempty"`
+	Since          string `json:"since,omitempty"`
+	ElapsedSeconds int64  `json:"elapsed_seconds,omitempty"`
+	TodaySeconds   int64  `json:"today_seconds"`
+	TargetSeconds  int64  `json:"target_seconds"`
+}
+
+// The time spent per ticket during a day, returned by the API
+type ReportResponse struct {
+	Date         string         `json:"date"`
+	TotalSeconds int64          `json:"total_seconds"`
+	Tickets      []ReportTicket `json:"tickets"`
+}
+
+type ReportTicket struct {
+	Title   string `json:"title"`
+	Seconds int64  `json:"seconds"`
+}
+
+// An interval of the timeline returned by the API
+type IntervalResponse struct {
+	Title string `json:"title"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// The time worked per day of a week, returned by the API
+type WeekResponse struct {
+	Start string        `json:"start"`
+	Days  []DayResponse `json:"days"`
+}
+
+type DayResponse struct {
+	Date          string `json:"date"`
+	TotalSeconds  int64  `json:"total_seconds"`
+	TargetSeconds int64  `json:"target_seconds"`
+}
+
+// The body of the start and switch requests
+type StartRequest struct {
+	Title string `json:"title"`
+}
+
+// An error returned by the API
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Serializes the accesses to the database
+var serverLock sync.Mutex
+
+func getStatus(records []Record) StatusResponse {
+	now := getNow()
+	today := now.Truncate(time.Hour * 24)
+	status := StatusResponse{
+		TodaySeconds:  int64(computeDayTotal(records, today).Seconds()),
+		TargetSeconds: int64(getDayTarget(today).Seconds()),
+	}
+	if len(records) != 0 && records[len(records)-1].title != STOP_TOKEN && !isAutoStopped(records) {
+		last := records[len(records)-1]
+		status.Running = true
+		status.Title = last.title
+		status.Since = fromDbTime(last.timestamp).Format(time.RFC3339)
+		status.ElapsedSeconds = int64(now.Sub(last.timestamp).Seconds())
+	}
+	return status
+}
+
+func getReport(records []Record, day time.Time) ReportResponse {
+	report := ReportResponse{
+		Date:         day.Format("2006-01-02"),
+		TotalSeconds: int64(computeDayTotal(records, day).Seconds()),
+	}
+	for _, t := range computeDaySummary(records, day) {
+		report.Tickets = append(report.Tickets, ReportTicket{t.title, int64(t.duration.Seconds())})
+	}
+	return report
+}
+
+func getTimeline(records []Record, day time.Time) []IntervalResponse {
+	timeline := []IntervalResponse{}
+	for _, in := range clipIntervalsToDay(computeIntervals(records), day) {
+		timeline = append(timeline, IntervalResponse{
+			in.title,
+			fromDbTime(in.start).Format(time.RFC3339),
+			fromDbTime(in.end).Format(time.RFC3339),
+		})
+	}
+	return timeline
+}
+
+func getWeek(records []Record, day time.Time) WeekResponse {
+	totals := computeTotalsPerDay(records)
+	monday := getWeekStart(day)
+	week := WeekResponse{Start: monday.Format("2006-01-02")}
+	for i := 0; i < 7; i++ {
+		current := monday.AddDate(0, 0, i)
+		week.Days = append(week.Days, DayResponse{
+			current.Format("2006-01-02"),
+			int64(totals[current.Format(DATE_FORMAT)].Seconds()),
+			int64(getDayTarget(current).Seconds()),
+		})
+	}
+	return week
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{message})
+}
+
+// A handler of the API, answering with an internal error when it fails
+type APIHandler func(w http.ResponseWriter, r *http.Request) error
+
+// Rejects the requests without the "Authorization: Bearer <token>" header of an account,
+// the handler running on the files of the user of the token, or of the user parameter for an admin
+func requireToken(accounts ServerAccounts, handler APIHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requester, found := accounts.authenticate(r)
+		if !found {
+			writeError(w, http.StatusUnauthorized, "invalid or missing token")
+			return
+		}
+		role := accounts.getRole(requester)
+		user := requester
+		if target := r.URL.Query().Get("user"); target != "" && target != requester {
+			if role != "admin" {
+				writeError(w, http.StatusForbidden, "only an admin can access the entries of another user")
+				return
+			}
+			if !contains(accounts.names(), target) {
+				writeError(w, http.StatusNotFound, "unknown user \""+target+"\"")
+				return
+			}
+			user = target
+		}
+		serverLock.Lock()
+		defer serverLock.Unlock()
+		serverRole = role
+		defer func() { serverRole = "" }()
+		err := runAsUser(user, func() error {
+			if requester == "" {
+				beginAuditOperation("mate serve: " + r.Method + " " + r.URL.Path)
+			} else {
+				beginAuditOperation("mate serve (" + requester + "): " + r.Method + " " + r.URL.Path)
+			}
+			if err := reconcileTimer(); err != nil {
+				return err
+			}
+			return handler(w, r)
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// Only lets the requests of the given method through
+func requireMethod(method string, handler APIHandler) APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != method {
+			writeError(w, http.StatusMethodNotAllowed, "expected "+method)
+			return nil
+		}
+		return handler(w, r)
+	}
+}
+
+// Answers with the status after a change of the database
+func writeStatus(w http.ResponseWriter) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, getStatus(records))
+	return nil
+}
+
+// Reads the title of a start or switch request
+func readStartRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var request StartRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || strings.TrimSpace(request.Title) == "" {
+		writeError(w, http.StatusBadRequest, "expected {\"title\": \"Ticket title\"}")
+		return "", false
+	}
+	return strings.TrimSpace(request.Title), true
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request) error {
+	return writeStatus(w)
+}
+
+func handleStart(w http.ResponseWriter, r *http.Request) error {
+	title, ok := readStartRequest(w, r)
+	if !ok {
+		return nil
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	if err = writeTicket(expandTitle(title, records)); err != nil {
+		return err
+	}
+	return writeStatus(w)
+}
+
+func handleSwitch(w http.ResponseWriter, r *http.Request) error {
+	title, ok := readStartRequest(w, r)
+	if !ok {
+		return nil
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	if !getStatus(records).Running {
+		writeError(w, http.StatusConflict, "no ticket running")
+		return nil
+	}
+	if err = writeTicket(expandTitle(title, records)); err != nil {
+		return err
+	}
+	return writeStatus(w)
+}
+
+func handleStop(w http.ResponseWriter, r *http.Request) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	if !getStatus(records).Running {
+		writeError(w, http.StatusConflict, "no ticket running")
+		return nil
+	}
+	if err = writeTicketAt(getRunningEnd(records[len(records)-1].timestamp), STOP_TOKEN); err != nil {
+		return err
+	}
+	return writeStatus(w)
+}
+
+// Reads the date parameter of a request, today by default
+func readDateParameter(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	day, err := parseDate(r.URL.Query().Get("date"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return time.Time{}, false
+	}
+	return day, true
+}
+
+// Answers with a view of the database for the date parameter of the request
+func handleDayView(view func(records []Record, day time.Time) interface{}) APIHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		day, ok := readDateParameter(w, r)
+		if !ok {
+			return nil
+		}
+		records, err := getRecords()
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusOK, view(records, day))
+		return nil
+	}
+}
+
+var handleReport = handleDayView(func(records []Record, day time.Time) interface{} { return getReport(records, day) })
+var handleTimeline = handleDayView(func(records []Record, day time.Time) interface{} { return getTimeline(records, day) })
+var handleWeek = handleDayView(func(records []Record, day time.Time) interface{} { return getWeek(records, day) })
+
+// Returns the database, or replaces it by the one sent (as done by mate sync)
+// The database is versioned by its ETag, a PUT with If-Match (or If-None-Match: *) failing with 412 when
+// the database changed since it was read
+func handleRecords(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case "GET":
+		records, err := getRecords()
+		if err != nil {
+			return err
+		}
+		content := formatRecords(records)
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("ETag", getRecordsETag(content))
+		io.WriteString(w, content)
+	case "PUT":
+		current, err := getRecords()
+		if err != nil {
+			return err
+		}
+		match := r.Header.Get("If-Match")
+		if match != "" && match != getRecordsETag(formatRecords(current)) || r.Header.Get("If-None-Match") == "*" && len(current) != 0 {
+			writeError(w, http.StatusPreconditionFailed, "the database changed since it was read")
+			return nil
+		}
+		records, err := parseRecords(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid database: "+err.Error())
+			return nil
+		}
+		sortRecords(records)
+		if err = writeRecords(records); err != nil {
+			return err
+		}
+		w.Header().Set("ETag", getRecordsETag(formatRecords(records)))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "expected GET or PUT")
+	}
+	return nil
+}
+
+// Returns the ETag of the database as CSV
+func getRecordsETag(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return "\"" + hex.EncodeToString(hash[:16]) + "\""
+}
+
+// Answers with the JSON export of the entries between the since and until parameters, as for mate team report
+func handleExport(w http.ResponseWriter, r *http.Request) error {
+	start, end, err := parseDateRange(r.URL.Query().Get("since"), r.URL.Query().Get("until"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return exportJSON(w, records, start, end)
+}
+
+// Serves the REST API, authenticated by serve.token or by the token of a user (see ServerAccounts),
+// an admin passing user=<name> to read or write the entries of a user:
+//
+//	GET  /api/status                        running ticket and time worked today
+//	POST /api/start   {"title": "..."}      starts a ticket
+//	POST /api/switch  {"title": "..."}      starts a ticket if another one is running
+//	POST /api/stop                          stops the running ticket
+//	GET  /api/report?date=YYYY-MM-DD        time spent per ticket on a day (today by default)
+//	GET  /api/timeline?date=YYYY-MM-DD      intervals of a day
+//	GET  /api/week?date=YYYY-MM-DD          time worked per day of the week
+//	GET  /api/records                       the database, as CSV
+//	PUT  /api/records                       replaces the database, unless it changed since the ETag given as If-Match
+//	GET  /api/export?since=...&until=...    the JSON export of the entries, the dates being included
+//	GET  /api/events?token=...              WebSocket sending the status whenever the running ticket changes
+//	GET  /api/menubar?token=...             with --menubar-feed, the status as a plugin of xbar or SwiftBar
+//	GET  /metrics                           gauges of the day and time per project, for Prometheus
+//
+// For the users of the [users] of the config:
+//
+//	GET  /api/team/report?since=...         the time spent per project and per user, over the current week by default
+//	GET  /api/team/users                    for reporters and admins, the users and their status
+//
+// The web dashboard is served at the root, and asks for the token
+func serve(listen string, menubarFeed bool) error {
+	token, err := getRequiredConfig("serve.token", "the token clients send as \"Authorization: Bearer <token>\"")
+	if err != nil {
+		return err
+	}
+	accounts, err := loadServerAccounts(token)
+	if err != nil {
+		return err
+	}
+	if listen == "" {
+		listen = getConfig("serve.listen", DEFAULT_LISTEN)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/status", requireToken(accounts, requireMethod("GET", handleStatus)))
+	mux.HandleFunc("/api/start", requireToken(accounts, requireMethod("POST", handleStart)))
+	mux.HandleFunc("/api/switch", requireToken(accounts, requireMethod("POST", handleSwitch)))
+	mux.HandleFunc("/api/stop", requireToken(accounts, requireMethod("POST", handleStop)))
+	mux.HandleFunc("/api/report", requireToken(accounts, requireMethod("GET", handleReport)))
+	mux.HandleFunc("/api/timeline", requireToken(accounts, requireMethod("GET", handleTimeline)))
+	mux.HandleFunc("/api/week", requireToken(accounts, requireMethod("GET", handleWeek)))
+	mux.HandleFunc("/api/records", requireToken(accounts, handleRecords))
+	mux.HandleFunc("/api/export", requireToken(accounts, requireMethod("GET", handleExport)))
+	mux.HandleFunc("/metrics", requireToken(accounts, requireMethod("GET", handleMetrics)))
+	mux.HandleFunc("/api/team/report", requireToken(accounts, requireMethod("GET", handleTeamReport(accounts))))
+	mux.HandleFunc("/api/team/users", requireToken(accounts, requireRole("reporter", requireMethod("GET", handleTeamUsers(accounts)))))
+
+	// The WebSockets of each user get the status of the database of the user
+	hubs := map[string]*StatusHub{"": newStatusHub("")}
+	for _, name := range accounts.names() {
+		hubs[name] = newStatusHub(name)
+	}
+	for _, hub := range hubs {
+		go hub.watch()
+	}
+	mux.HandleFunc("/api/events", func(w http.ResponseWriter, r *http.Request) {
+		user, found := accounts.authenticate(r)
+		if !found {
+			writeError(w, http.StatusUnauthorized, "invalid or missing token")
+			return
+		}
+		hubs[user].serve(w, r)
+	})
+	assets, _ := fs.Sub(webAssets, "web")
+	mux.Handle("/", http.FileServer(http.FS(assets)))
+
+	if len(accounts.users) != 0 {
+		fmt.Printf("mate serving on %s for %d users, press Ctrl+C to stop\n", listen, len(accounts.users))
+	} else {
+		fmt.Printf("mate serving on %s, press Ctrl+C to stop\n", listen)
+	}
+	if menubarFeed {
+		address := "http://" + listen
+		if strings.HasPrefix(listen, ":") {
+			address = "http://127.0.0.1" + listen
+		}
+		mux.HandleFunc("/api/menubar", requireToken(accounts, requireMethod("GET", handleMenubar(address+"/"))))
+		fmt.Println("For a menu bar timer, save as mate.1m.sh in the plugins of xbar or SwiftBar (and make it executable):")
+		fmt.Printf("  #!/bin/sh\n  curl -fsS \"%s/api/menubar?token=<serve.token>\"\n", address)
+	}
+	return http.ListenAndServe(listen, mux)
+}
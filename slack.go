@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const SLACK_API_URL = "https://slack.com/api"
+const DEFAULT_SLACK_EMOJI = ":computer:"
+const DEFAULT_SLACK_STATUS = "Working on {title}"
+
+// Sets the Slack status of the user of slack.token (a user token with the users.profile:write scope)
+// An empty text clears the status
+func setSlackStatus(text string, emoji string) error {
+	var response struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	profile := map[string]interface{}{
+		"profile": map[string]interface{}{
+			"status_text":       text,
+			"status_emoji":      emoji,
+			"status_expiration": 0,
+		},
+	}
+	headers := map[string]string{"Authorization": "Bearer " + getConfig("slack.token", "")}
+	if err := callJSONAPI("POST", SLACK_API_URL+"/users.profile.set", headers, profile, &response); err != nil {
+		return err
+	}
+	if !response.OK {
+		return fmt.Errorf("users.profile.set: %s", response.Error)
+	}
+	return nil
+}
+
+// Updates the Slack status with the running ticket, as set by slack.status (default "Working on {title}")
+// and slack.emoji, and clears it when the ticket is stopped
+func updateSlackStatus(event TicketEvent) {
+	if getConfig("slack.token", "") == "" {
+		return
+	}
+
+	var err error
+	if event.name == "stop" {
+		err = setSlackStatus("", "")
+	} else {
+		text := strings.ReplaceAll(getConfig("slack.status", DEFAULT_SLACK_STATUS), "{title}", event.title)
+		// Slack truncates the status at 100 characters
+		if runes := []rune(text); len(runes) > 100 {
+			text = string(runes[:99]) + "…"
+		}
+		err = setSlackStatus(text, getConfig("slack.emoji", DEFAULT_SLACK_EMOJI))
+	}
+	if err != nil {
+		fmt.Printf("Cannot update the Slack status: %v\n", err)
+	}
+}
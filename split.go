@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Splits an entry in two at the given time (HH:MM on the day of the entry, or YYYY/MM/DD HH:MM),
+// the second part going to another ticket
+// The entry is found as by mate delete: the last one by default
+func splitEntry(literal string, at string, secondTitle string) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	i, err := findEntry(records, literal)
+	if err != nil {
+		return err
+	}
+	entry := records[i]
+	if entry.title == STOP_TOKEN {
+		return errors.New("A STOP cannot be split, only the entry of a ticket")
+	}
+
+	var splitAt time.Time
+	if clock, err := parseClock(at); err == nil {
+		splitAt = entry.timestamp.Truncate(time.Hour * 24).Add(clock)
+	} else if t, err := time.Parse("2006/01/02 15:04", at); err == nil {
+		splitAt = t
+	} else {
+		return fmt.Errorf("Invalid time \"%s\" (expected HH:MM or \"YYYY/MM/DD HH:MM\")", at)
+	}
+	end := getRunningEnd(entry.timestamp)
+	if i+1 < len(records) {
+		end = records[i+1].timestamp
+	}
+	if !splitAt.After(entry.timestamp) || !splitAt.Before(end) {
+		return fmt.Errorf("%s is not within %s (%s - %s)", splitAt.Format(TIME_FORMAT), entry.title,
+			entry.timestamp.Format(TIME_FORMAT), end.Format(TIME_FORMAT))
+	}
+
+	split := append(append(append([]Record{}, records[:i+1]...), Record{splitAt, secondTitle}), records[i+1:]...)
+	if err = writeRecords(split); err != nil {
+		return err
+	}
+	fmt.Printf("%s\t%s - %s\n", entry.title, entry.timestamp.Format(TIME_FORMAT), splitAt.Format(TIME_FORMAT))
+	fmt.Printf("%s\t%s - %s\n", secondTitle, splitAt.Format(TIME_FORMAT), end.Format(TIME_FORMAT))
+	return nil
+}
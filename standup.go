@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Returns the last day before the given one with tracked time
+func getPreviousWorkedDay(records []Record, day time.Time) (time.Time, bool) {
+	intervals := computeIntervals(records)
+	for i := len(intervals) - 1; i >= 0; i-- {
+		if intervals[i].start.Before(day) {
+			return intervals[i].start.Truncate(time.Hour * 24), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Prints the tickets of the previous worked day and of today, ready to paste in a standup thread
+// The previous worked day is "Yesterday", or its weekday after a weekend or days off
+func showStandup(markdown bool) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	today := getNow().Truncate(time.Hour * 24)
+
+	type section struct {
+		name   string
+		totals []TicketTotal
+	}
+	var sections []section
+	if previous, found := getPreviousWorkedDay(records, today); found {
+		name := "Yesterday"
+		if !previous.Equal(today.AddDate(0, 0, -1)) {
+			name = previous.Weekday().String()
+			if today.Sub(previous) >= time.Hour*24*7 {
+				name += " " + previous.Format(DATE_FORMAT)
+			}
+		}
+		sections = append(sections, section{name, computeDaySummary(records, previous)})
+	}
+	sections = append(sections, section{"Today (so far)", computeDaySummary(records, today)})
+
+	for i, s := range sections {
+		if i != 0 {
+			fmt.Println()
+		}
+		if markdown {
+			fmt.Printf("**%s:**\n", s.name)
+		} else {
+			fmt.Printf("%s:\n", s.name)
+		}
+		if len(s.totals) == 0 {
+			fmt.Println("- Nothing tracked yet")
+			continue
+		}
+		fmt.Println(formatTicketTotals(s.totals, "-"))
+	}
+	return nil
+}
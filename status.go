@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const STATUS_CACHE_NAME = ".mate.status.json"
+const DEFAULT_STATUS_CACHE = "5s"
+
+// Longest title shown in a status bar, longer ones being cut with an ellipsis
+const STATUS_TITLE_WIDTH = 30
+
+// A status line as last printed, reused by the next calls of the same format for status.cache
+// (e.g. at every redraw of tmux) as long as the database keeps its size
+type StatusCache struct {
+	Format string `json:"format"`
+	At     string `json:"at"`
+	Size   int64  `json:"size"`
+	Output string `json:"output"`
+}
+
+func getStatusCachePath() string {
+	return getHomeFilePath(STATUS_CACHE_NAME)
+}
+
+// Cuts a title to STATUS_TITLE_WIDTH characters
+func shortenTitle(title string) string {
+	if runes := []rune(title); len(runes) > STATUS_TITLE_WIDTH {
+		return string(runes[:STATUS_TITLE_WIDTH-1]) + "…"
+	}
+	return title
+}
+
+// The status as read by Waybar (with "return-type": "json") and i3blocks (with format=json)
+// The class is working, idle or overtime (working past the target of the day), for the CSS of the bar
+type WaybarStatus struct {
+	Text    string `json:"text"`
+	Tooltip string `json:"tooltip"`
+	Class   string `json:"class"`
+}
+
+// Formats the status for a status bar or a prompt:
+//   - plain: "PROJ-123 1h12m (5h30m / 8h today)"
+//   - tmux: the same, colored with tmux #[...] styles
+//   - waybar: a WaybarStatus
+//   - prompt: "⏱ PROJ-123 1h12m", the key of the ticket standing for its title, and nothing when not working
+func formatStatus(format string, status StatusResponse) string {
+	today := fmt.Sprintf("%s / %s today", formatMinutes(time.Duration(status.TodaySeconds)*time.Second),
+		formatMinutes(time.Duration(status.TargetSeconds)*time.Second))
+	current := "Not working"
+	if status.Running {
+		current = shortenTitle(status.Title) + " " + formatMinutes(time.Duration(status.ElapsedSeconds)*time.Second)
+	}
+
+	switch format {
+	case "prompt":
+		if !status.Running {
+			return ""
+		}
+		title := TICKET_KEY_PATTERN.FindString(status.Title)
+		if title == "" {
+			title = shortenTitle(status.Title)
+		}
+		return "⏱ " + title + " " + formatMinutes(time.Duration(status.ElapsedSeconds)*time.Second)
+	case "tmux":
+		// # starts a style in tmux, and is escaped by doubling it
+		current = strings.ReplaceAll(current, "#", "##")
+		color := "yellow"
+		if status.Running {
+			color = "green"
+		}
+		return fmt.Sprintf("#[fg=%s]%s#[default] %s", color, current, today)
+	case "waybar":
+		waybar := WaybarStatus{current, today, "idle"}
+		if status.Running {
+			since, _ := time.Parse(time.RFC3339, status.Since)
+			waybar.Tooltip = fmt.Sprintf("%s\nSince %s\n%s", status.Title, since.Format(CLOCK_FORMAT), today)
+			waybar.Class = "working"
+			if status.TargetSeconds != 0 && status.TodaySeconds >= status.TargetSeconds {
+				waybar.Class = "overtime"
+			}
+		}
+		content, _ := json.Marshal(waybar)
+		return string(content)
+	}
+	return fmt.Sprintf("%s (%s)", current, today)
+}
+
+// Prints the running ticket and the time worked today on a single line, for a status bar
+// The line is cached for status.cache, which keeps a status bar redrawn every second from reading the database,
+// and a prompt printed at every command within a few milliseconds
+func showStatus(format string) error {
+	// The cache is not encrypted, the titles of an encrypted database are not written to it
+	useCache := !isEncryptionEnabled() && getConfigDuration("status.cache", DEFAULT_STATUS_CACHE) != 0
+	var size int64
+	if useCache {
+		if err := ensureCSVExists(); err != nil {
+			return err
+		}
+		var err error
+		if size, err = files.Size(getDbPath()); err != nil {
+			return newDatabaseError("read", err)
+		}
+		if output, found := readStatusCache(format, size); found {
+			if output != "" {
+				fmt.Println(output)
+			}
+			return nil
+		}
+	}
+
+	records, err := getRecentRecords(getNow().Truncate(time.Hour * 24))
+	if err != nil {
+		return err
+	}
+	output := formatStatus(format, getStatus(records))
+	if output != "" {
+		fmt.Println(output)
+	}
+	if useCache {
+		return writeStatusCache(StatusCache{format, getNow().Format(time.RFC3339Nano), size, output})
+	}
+	return nil
+}
+
+// Returns the cached status line of a format, if it is recent and the database kept its size
+func readStatusCache(format string, size int64) (string, bool) {
+	content, err := files.ReadFile(getStatusCachePath())
+	if err != nil {
+		return "", false
+	}
+	var cache StatusCache
+	if json.Unmarshal(content, &cache) != nil || cache.Format != format || cache.Size != size {
+		return "", false
+	}
+	at, err := time.Parse(time.RFC3339Nano, cache.At)
+	if age := getNow().Sub(at); err != nil || age < 0 || age >= getConfigDuration("status.cache", DEFAULT_STATUS_CACHE) {
+		return "", false
+	}
+	debugf("Status read from %s", getStatusCachePath())
+	return cache.Output, true
+}
+
+func writeStatusCache(cache StatusCache) error {
+	content, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("Cannot write the status cache: %w", err)
+	}
+	if err = files.WriteFile(getStatusCachePath(), content, 0644); err != nil {
+		return fmt.Errorf("Cannot write the status cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The time spent on a ticket
+type TicketTotal struct {
+	title    string
+	duration time.Duration
+}
+
+// Computes the time spent per ticket during a day, in the order the tickets were first worked on
+func computeDaySummary(records []Record, day time.Time) (totals []TicketTotal) {
+	positions := make(map[string]int)
+	for _, in := range clipIntervalsToDay(computeIntervals(records), day) {
+		position, found := positions[in.title]
+		if !found {
+			position = len(totals)
+			positions[in.title] = position
+			totals = append(totals, TicketTotal{in.title, 0})
+		}
+		totals[position].duration += in.end.Sub(in.start)
+	}
+	return
+}
+
+// Formats a duration to the minute, e.g. "1h30m", "2h" or "45m"
+func formatMinutes(duration time.Duration) string {
+	duration = duration.Truncate(time.Minute)
+	if duration == 0 {
+		return "0m"
+	}
+	literal := strings.TrimSuffix(duration.String(), "0s")
+	if strings.HasSuffix(literal, "h0m") {
+		literal = strings.TrimSuffix(literal, "0m")
+	}
+	return literal
+}
+
+// Formats the tickets of a summary, one per line with the given bullet
+func formatTicketTotals(totals []TicketTotal, bullet string) string {
+	var lines []string
+	for _, t := range totals {
+		lines = append(lines, fmt.Sprintf("%s %s: %s", bullet, t.title, formatMinutes(t.duration)))
+	}
+	return strings.Join(lines, "\n")
+}
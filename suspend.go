@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"time"
+)
+
+const DEFAULT_SUSPEND_ACTION = "subtract"
+
+// Time the wall clock may run ahead of the monotonic clock between two checks of the daemon before assuming a suspend
+const SUSPEND_TOLERANCE = time.Minute
+
+// Detects system suspends from the wall clock running ahead of the monotonic clock between two checks of the daemon
+// The monotonic clock stops during a suspend, while both clocks run when a check blocks the daemon (e.g. on a prompt)
+type SuspendWatcher struct {
+	// getNow() at the last check, without monotonic clock reading
+	lastCheck time.Time
+	// time.Now() at the last check, with its monotonic clock reading
+	lastMonotonic time.Time
+}
+
+// Handles a suspend that happened since the last check, as set by daemon.suspend_action:
+//   - subtract: the suspended period is removed from the running ticket
+//   - ask: the user is asked whether to remove it (subtract without a terminal)
+//   - keep: the suspended period is kept
+func (w *SuspendWatcher) check() error {
+	now, monotonic := getNow().Round(0), time.Now()
+	lastCheck, lastMonotonic := w.lastCheck, w.lastMonotonic
+	w.lastCheck, w.lastMonotonic = now, monotonic
+	if lastCheck.IsZero() {
+		return nil
+	}
+	suspended := now.Sub(lastCheck) - monotonic.Sub(lastMonotonic)
+	if suspended < SUSPEND_TOLERANCE {
+		return nil
+	}
+
+	action := getTypedConfig("daemon.suspend_action", "subtract|ask|keep", DEFAULT_SUSPEND_ACTION).(string)
+	if action == "keep" {
+		return nil
+	}
+
+	since, until := toDbTime(now.Add(-suspended)), toDbTime(now)
+	records, err := getRecords()
+	if err != nil || len(records) == 0 || records[len(records)-1].title == STOP_TOKEN {
+		return err
+	}
+	running := records[len(records)-1].title
+
+	if action == "ask" && isTerminal(os.Stdin) {
+		notify("Welcome back! Keep the suspended period?")
+		fmt.Printf("The system was suspended from %s to %s (%v) while working on %s\n",
+			since.Format(CLOCK_FORMAT), until.Format(CLOCK_FORMAT), until.Sub(since), running)
+		answer, ok := askUser(bufio.NewReader(os.Stdin), "Remove this period? [Y/n]: ")
+		if !ok || !contains([]string{"", "y", "Y"}, answer) {
+			return nil
+		}
+	}
+
+	title, removed, err := removePeriodFromRunningTicket(since, until)
+	if removed {
+		announceDaemonEvent(fmt.Sprintf("Suspended for %v, removed from %s", until.Sub(since), title))
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuspendWatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		database string
+		action   string
+		// How far the wall clock runs ahead of the monotonic clock between the two checks
+		suspended time.Duration
+		want      string
+	}{
+		{"no suspend", CSV_HEADER + "2026/10/14 09:00:00,A\n", "", 0, CSV_HEADER + "2026/10/14 09:00:00,A\n"},
+		{"within the tolerance", CSV_HEADER + "2026/10/14 09:00:00,A\n", "", 30 * time.Second, CSV_HEADER + "2026/10/14 09:00:00,A\n"},
+		{
+			"suspended while working",
+			CSV_HEADER + "2026/10/14 09:00:00,A\n",
+			"",
+			2 * time.Hour,
+			CSV_HEADER + "2026/10/14 09:00:00,A\n2026/10/14 12:00:00,mate:STOP\n2026/10/14 14:00:00,A\n",
+		},
+		{"suspended while working, kept", CSV_HEADER + "2026/10/14 09:00:00,A\n", "keep", 2 * time.Hour, CSV_HEADER + "2026/10/14 09:00:00,A\n"},
+		{
+			"suspended while not working",
+			CSV_HEADER + "2026/10/14 09:00:00,A\n2026/10/14 10:00:00,mate:STOP\n",
+			"",
+			2 * time.Hour,
+			CSV_HEADER + "2026/10/14 09:00:00,A\n2026/10/14 10:00:00,mate:STOP\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, test.database)
+			if test.action != "" {
+				config["daemon.suspend_action"] = test.action
+			}
+			var watcher SuspendWatcher
+			if err := watcher.check(); err != nil {
+				t.Fatal(err)
+			}
+			// The monotonic clock barely moves during the test, the wall clock jumping ahead
+			clock = FixedClock{testNow.Add(test.suspended)}
+			if err := watcher.check(); err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := getRecords()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := formatRecords(records), formatTestDatabase(t, test.want); got != want {
+				t.Errorf("got database %q, want %q", got, want)
+			}
+		})
+	}
+}
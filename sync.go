@@ -0,0 +1,425 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var SYNC_TYPES = []string{"mate", "webdav", "s3"}
+
+// Times a sync is retried when the remote database changes between its download and its upload
+const SYNC_ATTEMPTS = 3
+
+// The database as uploaded by the last sync, in the home directory or the profile
+const SYNC_BASE_NAME = ".mate.sync"
+
+var errRemoteChanged = errors.New("the remote database changed since it was downloaded")
+
+// A remote copy of the database
+type SyncTarget interface {
+	// Returns the remote database
+	download() (SyncDownload, error)
+	// Replaces the remote database, unless it changed since the given download (errRemoteChanged)
+	upload(content []byte, since SyncDownload) error
+}
+
+// A download of the remote database, with its version (ETag) for the upload not to overwrite the changes made since
+// A remote that gives no ETag is overwritten regardless
+type SyncDownload struct {
+	content []byte
+	etag    string
+	found   bool
+}
+
+// The database of a "mate serve" instance, authenticated by sync.token
+type MateSyncTarget struct {
+	url   string
+	token string
+}
+
+// A file of a WebDAV server, authenticated by sync.user and sync.password
+type WebDAVSyncTarget struct {
+	url      string
+	user     string
+	password string
+}
+
+// An object of an S3 bucket (or of an S3 compatible storage, at sync.endpoint)
+type S3SyncTarget struct {
+	bucket    string
+	key       string
+	region    string
+	endpoint  string
+	accessKey string
+	secretKey string
+}
+
+// Turns the status of a download into its result, 404 meaning that there is no remote database yet
+func checkDownload(status int, header http.Header, content []byte, err error) (SyncDownload, error) {
+	switch {
+	case err != nil:
+		return SyncDownload{}, err
+	case status == http.StatusNotFound:
+		return SyncDownload{}, nil
+	case status != http.StatusOK:
+		return SyncDownload{}, fmt.Errorf("download failed: %d %s", status, strings.TrimSpace(string(content)))
+	}
+	return SyncDownload{content, header.Get("ETag"), true}, nil
+}
+
+// Adds the headers making an upload fail if the remote database changed since the download
+func setUploadConditions(headers map[string]string, since SyncDownload) map[string]string {
+	if !since.found {
+		headers["If-None-Match"] = "*"
+	} else if since.etag != "" {
+		headers["If-Match"] = since.etag
+	}
+	return headers
+}
+
+func checkUpload(status int, header http.Header, content []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	if status == http.StatusPreconditionFailed {
+		return errRemoteChanged
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("upload failed: %d %s", status, strings.TrimSpace(string(content)))
+	}
+	return nil
+}
+
+func (t MateSyncTarget) download() (SyncDownload, error) {
+	return checkDownload(callRawAPI("GET", t.url+"/api/records", map[string]string{"Authorization": "Bearer " + t.token}, nil))
+}
+
+func (t MateSyncTarget) upload(content []byte, since SyncDownload) error {
+	headers := setUploadConditions(map[string]string{"Authorization": "Bearer " + t.token, "Content-Type": "text/csv"}, since)
+	return checkUpload(callRawAPI("PUT", t.url+"/api/records", headers, content))
+}
+
+func (t WebDAVSyncTarget) getHeaders() map[string]string {
+	headers := map[string]string{"Content-Type": "text/csv"}
+	if t.user != "" {
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(t.user+":"+t.password))
+	}
+	return headers
+}
+
+func (t WebDAVSyncTarget) download() (SyncDownload, error) {
+	return checkDownload(callRawAPI("GET", t.url, t.getHeaders(), nil))
+}
+
+func (t WebDAVSyncTarget) upload(content []byte, since SyncDownload) error {
+	return checkUpload(callRawAPI("PUT", t.url, setUploadConditions(t.getHeaders(), since), content))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Signs an S3 request with AWS Signature Version 4
+// Returns its URL and headers
+func (t S3SyncTarget) sign(method string, body []byte) (string, map[string]string) {
+	var host, path string
+	if t.endpoint != "" {
+		endpoint, _ := url.Parse(t.endpoint)
+		host, path = endpoint.Host, "/"+t.bucket+"/"+t.key
+	} else {
+		host, path = t.bucket+".s3."+t.region+".amazonaws.com", "/"+t.key
+	}
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		segments = append(segments, url.PathEscape(segment))
+	}
+	canonicalURI := strings.Join(segments, "/")
+
+	now := time.Now().UTC()
+	amzDate, day := now.Format("20060102T150405Z"), now.Format("20060102")
+	payloadHash := sha256.Sum256(body)
+	headers := map[string]string{
+		"Host":                 host,
+		"X-Amz-Date":           amzDate,
+		"X-Amz-Content-Sha256": hex.EncodeToString(payloadHash[:]),
+	}
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		method,
+		canonicalURI,
+		"",
+		"host:" + host + "\nx-amz-content-sha256:" + headers["X-Amz-Content-Sha256"] + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		headers["X-Amz-Content-Sha256"],
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := day + "/" + t.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+t.secretKey), day)
+	key = hmacSHA256(key, t.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	headers["Authorization"] = "AWS4-HMAC-SHA256 Credential=" + t.accessKey + "/" + scope +
+		", SignedHeaders=" + signedHeaders + ", Signature=" + signature
+
+	scheme := "https://"
+	if strings.HasPrefix(t.endpoint, "http://") {
+		scheme = "http://"
+	}
+	return scheme + host + canonicalURI, headers
+}
+
+func (t S3SyncTarget) download() (SyncDownload, error) {
+	address, headers := t.sign("GET", nil)
+	return checkDownload(callRawAPI("GET", address, headers, nil))
+}
+
+// The conditions are left out of the signature, S3 accepting unsigned If-Match and If-None-Match headers
+func (t S3SyncTarget) upload(content []byte, since SyncDownload) error {
+	address, headers := t.sign("PUT", content)
+	headers["Content-Type"] = "text/csv"
+	return checkUpload(callRawAPI("PUT", address, setUploadConditions(headers, since), content))
+}
+
+// Returns the target of sync.url, of type sync.type:
+//   - mate (default): a "mate serve" URL, authenticated by sync.token (or remote_token)
+//   - webdav: the URL of the file, authenticated by sync.user and sync.password
+//   - s3: s3://bucket/key, with sync.region, sync.access_key, sync.secret_key and optionally sync.endpoint
+func getSyncTarget() (SyncTarget, error) {
+	address, err := getRequiredConfig("sync.url", "the URL of the remote database")
+	if err != nil {
+		return nil, err
+	}
+	address = strings.TrimRight(address, "/")
+	switch syncType := getConfig("sync.type", SYNC_TYPES[0]); syncType {
+	case "mate":
+		token := getConfig("sync.token", getConfig("remote_token", ""))
+		if token == "" {
+			return nil, newConfigError("sync.token is required (the serve.token of the server)")
+		}
+		return MateSyncTarget{address, token}, nil
+	case "webdav":
+		return WebDAVSyncTarget{address, getConfig("sync.user", ""), getConfig("sync.password", "")}, nil
+	case "s3":
+		parsed, err := url.Parse(address)
+		if err != nil || parsed.Scheme != "s3" || parsed.Host == "" || len(parsed.Path) < 2 {
+			return nil, newConfigError("sync.url: expected s3://bucket/key")
+		}
+		accessKey, err := getRequiredConfig("sync.access_key", "the access key ID of the bucket")
+		if err != nil {
+			return nil, err
+		}
+		secretKey, err := getRequiredConfig("sync.secret_key", "the secret access key of the bucket")
+		if err != nil {
+			return nil, err
+		}
+		return S3SyncTarget{
+			bucket:    parsed.Host,
+			key:       strings.TrimPrefix(parsed.Path, "/"),
+			region:    getConfig("sync.region", "us-east-1"),
+			endpoint:  strings.TrimRight(getConfig("sync.endpoint", ""), "/"),
+			accessKey: accessKey,
+			secretKey: secretKey,
+		}, nil
+	default:
+		return nil, newConfigError(fmt.Sprintf("sync.type: unknown type \"%s\" (expected %s)", syncType, strings.Join(SYNC_TYPES, ", ")))
+	}
+}
+
+// Returns the path of the database as uploaded by the last sync, the base of the next merge
+// It is named after sync.url, for the base of another remote not to be used
+func getSyncBasePath(address string) string {
+	hash := sha256.Sum256([]byte(address))
+	return getHomeFilePath(SYNC_BASE_NAME + "." + hex.EncodeToString(hash[:4]) + ".csv")
+}
+
+// Reads the database as of the last sync, empty before the first one
+func readSyncBase(path string) ([]Record, error) {
+	content, err := files.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read the last synced database: %w", err)
+	}
+	return parseDatabase(content)
+}
+
+// Keeps the uploaded database, encrypted like the local one
+func writeSyncBase(path string, records []Record) error {
+	content, err := formatDatabase(records)
+	if err != nil {
+		return err
+	}
+	if err = files.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("Cannot write the last synced database: %w", err)
+	}
+	return nil
+}
+
+// Merges the remote database into the local one, then uploads the result
+// The sync is run again when another machine uploaded its database in the meantime
+func syncDatabase() error {
+	target, err := getSyncTarget()
+	if err != nil {
+		return err
+	}
+	return syncWithTarget(target, getSyncBasePath(getConfig("sync.url", "")))
+}
+
+func syncWithTarget(target SyncTarget, basePath string) error {
+	for attempt := 1; ; attempt++ {
+		err := mergeWithTarget(target, basePath)
+		if !errors.Is(err, errRemoteChanged) || attempt == SYNC_ATTEMPTS {
+			return err
+		}
+		debugf("The remote database changed during the sync, syncing again")
+	}
+}
+
+// Merges the remote database into the local one given the database of the last sync, then uploads the result:
+//   - the completed intervals added to the remote since the last sync are added; those overlapping local intervals
+//     or ending in the future (the clocks of the machines differing) are conflicts, reported and left out, in which
+//     case the remote is not overwritten
+//   - the completed intervals removed from the remote since the last sync are removed, unless changed locally, and
+//     those removed locally (by delete, rename, split, compact...) are not brought back
+//
+// Before the first sync, every completed interval of the remote is added
+func mergeWithTarget(target SyncTarget, basePath string) error {
+	download, err := target.download()
+	if err != nil {
+		return fmt.Errorf("Cannot download the remote database: %w", err)
+	}
+	base, err := readSyncBase(basePath)
+	if err != nil {
+		return err
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	added, removed, conflicts := 0, 0, 0
+	if download.found {
+		remote, err := parseDatabase(download.content)
+		if err != nil {
+			return fmt.Errorf("Invalid remote database: %w", err)
+		}
+		end := getNow().AddDate(1, 0, 0)
+		remoteIntervals, baseIntervals := getCompletedIntervals(remote, time.Time{}, end), getCompletedIntervals(base, time.Time{}, end)
+		var removedRecords []Record
+		for _, in := range getRemovedIntervals(baseIntervals, remoteIntervals, getCompletedIntervals(records, time.Time{}, end)) {
+			removedRecords = append(removedRecords, Record{in.start, in.title}, Record{in.end, STOP_TOKEN})
+		}
+		kept := subtractRecords(records, removedRecords)
+		removed = len(records) - len(kept)
+		records = kept
+
+		periods := subtractIntervals(remoteIntervals, baseIntervals)
+		imported, skipped := convertPeriods(periods, records, false)
+		printSkippedPeriods(skipped, false)
+		conflicts = countConflicts(periods, records, imported) + countFuturePeriods(periods)
+		newRecords, _ := splitNewRecords(records, imported)
+		added = len(newRecords)
+		if added != 0 || removed != 0 {
+			records = append(records, newRecords...)
+			sortRecords(records)
+			if err = writeRecords(records); err != nil {
+				return err
+			}
+		}
+	}
+
+	if conflicts != 0 {
+		return fmt.Errorf("%d entries merged, %d conflicts: the remote database was not updated\n"+
+			"Fix the conflicting entries, then run mate sync again", added, conflicts)
+	}
+	// Files are encrypted like the local database, a mate server encrypting its own
+	content := []byte(formatRecords(records))
+	if _, isServer := target.(MateSyncTarget); !isServer {
+		content, err = formatDatabase(records)
+	}
+	if err == nil {
+		err = target.upload(content, download)
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot upload the database: %w", err)
+	}
+	if err = writeSyncBase(basePath, records); err != nil {
+		return err
+	}
+	if removed != 0 {
+		fmt.Printf("%d entries merged, %d removed, database uploaded\n", added, removed)
+	} else {
+		fmt.Printf("%d entries merged, database uploaded\n", added)
+	}
+	return nil
+}
+
+// Returns the intervals of the last sync removed from the remote since, and left unchanged locally
+// The intervals still running are left out, as a machine uploads its database without the ticket running on another
+func getRemovedIntervals(base []Interval, remote []Interval, local []Interval) []Interval {
+	removed := subtractIntervals(base, remote)
+	return subtractIntervals(removed, subtractIntervals(removed, local))
+}
+
+// Counts the periods ending after now, left out of the import: the remote is not overwritten for them not to be lost
+func countFuturePeriods(periods []Interval) (count int) {
+	now := getNow()
+	for _, p := range periods {
+		if p.end.After(now) {
+			count++
+		}
+	}
+	return
+}
+
+// Returns the intervals missing from others (same start, end and title)
+func subtractIntervals(intervals []Interval, others []Interval) (missing []Interval) {
+	existing := make(map[string]bool)
+	for _, in := range others {
+		existing[formatInterval(in)] = true
+	}
+	for _, in := range intervals {
+		if !existing[formatInterval(in)] {
+			missing = append(missing, in)
+		}
+	}
+	return
+}
+
+func formatInterval(in Interval) string {
+	return formatRecordFields(in.start.Format(TIME_FORMAT), in.end.Format(TIME_FORMAT), in.title)
+}
+
+// Counts the periods left out of the import because they overlap the local intervals
+func countConflicts(periods []Interval, records []Record, imported []Record) (conflicts int) {
+	local := computeIntervals(records)
+	importedStarts := make(map[time.Time]bool)
+	for _, r := range imported {
+		importedStarts[r.timestamp] = true
+	}
+	for _, p := range periods {
+		if importedStarts[p.start] || !p.start.Before(p.end) {
+			continue
+		}
+		if overlapped, found := findOverlap(local, p.start, p.end); found &&
+			!(overlapped.start.Equal(p.start) && overlapped.end.Equal(p.end) && overlapped.title == p.title) {
+			conflicts++
+		}
+	}
+	return
+}
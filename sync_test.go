@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// A remote database held in memory, versioned by a counter
+type memorySyncTarget struct {
+	content []byte
+	found   bool
+	version int
+	// Uploads the given database right before the next upload, as another machine would
+	concurrent string
+}
+
+func (t *memorySyncTarget) download() (SyncDownload, error) {
+	return SyncDownload{t.content, strconv.Itoa(t.version), t.found}, nil
+}
+
+func (t *memorySyncTarget) upload(content []byte, since SyncDownload) error {
+	if t.concurrent != "" {
+		t.content, t.found, t.concurrent = []byte(t.concurrent), true, ""
+		t.version++
+	}
+	if since.found != t.found || since.etag != strconv.Itoa(t.version) {
+		return errRemoteChanged
+	}
+	t.content, t.found = content, true
+	t.version++
+	return nil
+}
+
+// Returns a database as formatted by mate, for databases to be compared whatever their quoting
+func formatTestDatabase(t *testing.T, database string) string {
+	records, err := parseDatabase([]byte(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortRecords(records)
+	return formatRecords(records)
+}
+
+func TestSyncWithTarget(t *testing.T) {
+	const a = "2026/10/14 08:00:00,A\n2026/10/14 09:00:00,mate:STOP\n"
+	const b = "2026/10/14 09:00:00,B\n2026/10/14 10:00:00,mate:STOP\n"
+	const c = "2026/10/14 10:30:00,C\n2026/10/14 11:00:00,mate:STOP\n"
+	const d = "2026/10/13 10:00:00,D\n2026/10/13 11:00:00,mate:STOP\n"
+	const running = "2026/10/14 11:00:00,R\n"
+	tests := []struct {
+		name       string
+		local      string
+		base       string
+		remote     string
+		concurrent string
+		wantErr    bool
+		want       string
+	}{
+		{"first sync, no remote yet", a, "", "", "", false, a},
+		{"first sync, remote entries added", a, "", b, "", false, a + b},
+		{"removed locally since the last sync", b, a + b, a + b, "", false, b},
+		{"removed remotely since the last sync", a + b, a + b, b, "", false, b},
+		{"added on both sides", a + c, a, a + b, "", false, a + b + c},
+		{"renamed locally", strings.Replace(a, ",A", ",A2", 1), a, a, "", false, strings.Replace(a, ",A", ",A2", 1)},
+		{"uploaded by another machine during the sync", a, a, a, a + d, false, a + d},
+		{"removed remotely but changed locally", strings.Replace(a, ",A", ",A2", 1) + b, a + b, b, "", false, strings.Replace(a, ",A", ",A2", 1) + b},
+		{"running here, left out of the upload of another machine", a + running, a + running, a, "", false, a + running},
+		{"conflict", a, "", "2026/10/14 08:30:00,X\n2026/10/14 09:30:00,mate:STOP\n", "", true, ""},
+		{"remote interval in the future", a, "", a + "2026/10/14 13:00:00,E\n2026/10/14 14:00:00,mate:STOP\n", "", true, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, CSV_HEADER+test.local)
+			basePath := getSyncBasePath("https://example.com")
+			if test.base != "" {
+				records, _ := parseDatabase([]byte(CSV_HEADER + test.base))
+				if err := writeSyncBase(basePath, records); err != nil {
+					t.Fatal(err)
+				}
+			}
+			target := &memorySyncTarget{found: test.remote != ""}
+			if test.remote != "" {
+				target.content = []byte(CSV_HEADER + test.remote)
+			}
+			if test.concurrent != "" {
+				target.concurrent = CSV_HEADER + test.concurrent
+			}
+			remoteBefore := string(target.content)
+
+			err := syncWithTarget(target, basePath)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got no error, want conflicts")
+				}
+				if string(target.content) != remoteBefore {
+					t.Errorf("got the remote updated to %q, want it unchanged", target.content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := formatTestDatabase(t, CSV_HEADER+test.want)
+			records, err := getRecords()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := formatRecords(records); got != want {
+				t.Errorf("got local database %q, want %q", got, want)
+			}
+			if got := formatTestDatabase(t, string(target.content)); got != want {
+				t.Errorf("got remote database %q, want %q", got, want)
+			}
+			base, err := readSyncBase(basePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := formatRecords(base); got != want {
+				t.Errorf("got last synced database %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestHandleRecordsConditionalPut(t *testing.T) {
+	const database = CSV_HEADER + "2026/10/14 08:00:00,A\n2026/10/14 09:00:00,mate:STOP\n"
+	tests := []struct {
+		name       string
+		database   string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{"unconditional", database, "", "", http.StatusNoContent},
+		{"current version", database, "If-Match", getRecordsETag(formatTestDatabase(t, database)), http.StatusNoContent},
+		{"stale version", database, "If-Match", getRecordsETag(CSV_HEADER), http.StatusPreconditionFailed},
+		{"created over entries", database, "If-None-Match", "*", http.StatusPreconditionFailed},
+		{"created over no entries", "", "If-None-Match", "*", http.StatusNoContent},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, test.database)
+			uploaded := CSV_HEADER + "2026/10/14 10:00:00,B\n2026/10/14 11:00:00,mate:STOP\n"
+			request := httptest.NewRequest("PUT", "/api/records", strings.NewReader(uploaded))
+			if test.header != "" {
+				request.Header.Set(test.header, test.value)
+			}
+			response := httptest.NewRecorder()
+			if err := handleRecords(response, request); err != nil {
+				t.Fatal(err)
+			}
+			if response.Code != test.wantStatus {
+				t.Fatalf("got status %d, want %d", response.Code, test.wantStatus)
+			}
+			records, err := getRecords()
+			if err != nil {
+				t.Fatal(err)
+			}
+			replaced := formatRecords(records) == formatTestDatabase(t, uploaded)
+			if replaced != (test.wantStatus == http.StatusNoContent) {
+				t.Errorf("got the database replaced %v", replaced)
+			}
+		})
+	}
+}
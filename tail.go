@@ -0,0 +1,58 @@
+package main
+
+import "time"
+
+// Size of the first chunk read from the end of the database, doubled until it reaches far enough back
+const TAIL_READ_SIZE = 16 * 1024
+
+// Returns the entries from the last one before since, reading only the end of the database
+// This keeps info, stop and start instant on years of history, as they only look at the last days
+// The whole database is read when the end is not enough: when it is encrypted or sealed (the chain
+// covers every entry), or when a malformed line is met, for it to be reported with its line number
+func getRecentRecords(since time.Time) ([]Record, error) {
+	if isEncryptionEnabled() || isIntegrityEnabled() {
+		return getRecords()
+	}
+	if err := pullGitDatabase(); err != nil {
+		return nil, err
+	}
+	if err := ensureCSVExists(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	size, err := files.Size(getDbPath())
+	if err != nil {
+		return nil, newDatabaseError("read", err)
+	}
+	head, err := files.ReadAt(getDbPath(), 0, int64(len(ENCRYPTED_DB_MAGIC)))
+	if err != nil {
+		return nil, newDatabaseError("read", err)
+	}
+	if isEncryptedDatabase(head) {
+		return getRecords()
+	}
+
+	for length := int64(TAIL_READ_SIZE); length < size; length *= 2 {
+		content, err := files.ReadAt(getDbPath(), size-length, length)
+		if err != nil {
+			return nil, newDatabaseError("read", err)
+		}
+		// The first line is cut in the middle, and skipped as the header would be
+		records, malformed := parseRecordsLeniently(content)
+		if len(malformed) != 0 {
+			break
+		}
+		if len(records) == 0 || !records[0].timestamp.Before(since) {
+			continue
+		}
+		i := len(records) - 1
+		for !records[i].timestamp.Before(since) {
+			i--
+		}
+		debugf("Read the last %d bytes of %s: %d entries since %s in %v",
+			len(content), getDbPath(), len(records)-i, records[i].timestamp.Format(TIME_FORMAT), time.Since(start))
+		return records[i:], nil
+	}
+	return getRecords()
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Replaces the titles of the tickets of the people not sharing them, in the reports of a multi-user server
+const PRIVATE_TITLE = "(private tickets)"
+
+// The time spent by a member of the team, read from a JSON export
+type PersonReport struct {
+	name     string
+	total    time.Duration
+	projects []ProjectReport
+}
+
+// The time spent by the team, per project then ticket and per person then project
+type TeamReport struct {
+	first    time.Time
+	last     time.Time
+	total    time.Duration
+	projects []ProjectReport
+	people   []PersonReport
+}
+
+// Splits an input of mate team report into the name of the person and the file or server to read,
+// "alice=path" naming the person explicitly, else the name being the one of the file or of the server
+func parseTeamInput(input string) (name string, source string) {
+	if parts := strings.SplitN(input, "=", 2); len(parts) == 2 && !strings.Contains(parts[0], "/") {
+		return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	}
+	if address, err := url.Parse(input); err == nil && address.Host != "" {
+		return address.Hostname(), input
+	}
+	base := filepath.Base(input)
+	return strings.TrimSuffix(base, filepath.Ext(base)), input
+}
+
+// Reads the entries of a member of the team between start and end (zero times meaning no bound),
+// from a JSON export (see mate export --format json) or from the /api/export of a mate server
+func readTeamRecords(source string, start time.Time, end time.Time) ([]Record, error) {
+	var export JSONExport
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		if err := callRemote(source, "GET", "/api/export?"+getPeriodQuery(start, end).Encode(), nil, &export); err != nil {
+			return nil, err
+		}
+	} else {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(content, &export); err != nil {
+			return nil, err
+		}
+	}
+	return getJSONRecords(export)
+}
+
+// Computes the report of the team over the days within [start, end[ (zero times meaning no bound),
+// from the entries of each member
+func computeTeamReport(members map[string][]Record, start time.Time, end time.Time) TeamReport {
+	var report TeamReport
+	for name, records := range members {
+		person := PersonReport{name: name}
+		personTickets := make(map[string]time.Duration)
+		for date, totals := range computeTicketTotalsPerDay(records) {
+			day, _ := time.Parse(DATE_FORMAT, date)
+			if !start.IsZero() && day.Before(start) || !end.IsZero() && !day.Before(end) {
+				continue
+			}
+			if report.first.IsZero() || day.Before(report.first) {
+				report.first = day
+			}
+			if day.After(report.last) {
+				report.last = day
+			}
+			for title, duration := range totals {
+				personTickets[title] += duration
+				person.total += duration
+			}
+		}
+		person.projects = groupTicketsByProject(personTickets)
+		report.total += person.total
+		report.people = append(report.people, person)
+	}
+	report.projects = mergePeopleProjects(report.people)
+	// The period asked for, rather than the days tracked, when it is bounded
+	if !start.IsZero() {
+		report.first = start
+	}
+	if !end.IsZero() {
+		report.last = end.AddDate(0, 0, -1)
+	}
+	sort.Slice(report.people, func(i, j int) bool {
+		a, b := report.people[i], report.people[j]
+		return a.total > b.total || a.total == b.total && a.name < b.name
+	})
+	return report
+}
+
+// Merges the projects of the people of the team, their tickets being summed up
+func mergePeopleProjects(people []PersonReport) (projects []ProjectReport) {
+	positions := make(map[string]int)
+	tickets := make(map[string]map[string]time.Duration)
+	for _, person := range people {
+		for _, project := range person.projects {
+			if _, found := positions[project.name]; !found {
+				positions[project.name] = len(projects)
+				projects = append(projects, ProjectReport{name: project.name})
+				tickets[project.name] = make(map[string]time.Duration)
+			}
+			projects[positions[project.name]].total += project.total
+			for _, ticket := range project.tickets {
+				tickets[project.name][ticket.title] += ticket.duration
+			}
+		}
+	}
+	for i := range projects {
+		for title, duration := range tickets[projects[i].name] {
+			projects[i].tickets = append(projects[i].tickets, TicketTotal{title, duration})
+		}
+	}
+	sortProjectReports(projects)
+	return projects
+}
+
+// Returns the report with the titles of the tickets of the people not sharing them replaced by PRIVATE_TITLE,
+// their time being kept under their projects
+func (report TeamReport) hideTitles(shared func(person string) bool) TeamReport {
+	people := make([]PersonReport, len(report.people))
+	for i, person := range report.people {
+		people[i] = person
+		if shared(person.name) {
+			continue
+		}
+		people[i].projects = make([]ProjectReport, len(person.projects))
+		for j, project := range person.projects {
+			people[i].projects[j] = ProjectReport{project.name, project.total, []TicketTotal{{PRIVATE_TITLE, project.total}}}
+		}
+	}
+	report.people = people
+	report.projects = mergePeopleProjects(people)
+	return report
+}
+
+// Formats the report of the team as plain text: the projects with the time of each person then of each ticket,
+// and the people with the time of each project
+func formatTeamReport(report TeamReport) string {
+	lines := []string{fmt.Sprintf("Team report of %s to %s: %s", report.first.Format(DATE_FORMAT), report.last.Format(DATE_FORMAT),
+		formatMinutes(report.total))}
+	lines = append(lines, "", "PER PROJECT")
+	for _, project := range report.projects {
+		var people []string
+		for _, person := range report.people {
+			for _, personProject := range person.projects {
+				if personProject.name == project.name {
+					people = append(people, person.name+" "+formatMinutes(personProject.total))
+				}
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s (%s)", project.name, formatMinutes(project.total), strings.Join(people, ", ")),
+			formatTicketTotals(project.tickets, "  -"))
+	}
+	lines = append(lines, "", "PER PERSON")
+	for _, person := range report.people {
+		lines = append(lines, fmt.Sprintf("%s: %s", person.name, formatMinutes(person.total)))
+		for _, project := range person.projects {
+			lines = append(lines, fmt.Sprintf("  - %s: %s", project.name, formatMinutes(project.total)))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Returns the days of a team report, [start, end[, the current week without since and until
+func getTeamPeriod(since string, until string) (start time.Time, end time.Time, err error) {
+	if since == "" && until == "" {
+		start = getWeekStart(getNow().Truncate(time.Hour * 24))
+		return start, start.AddDate(0, 0, 7), nil
+	}
+	return parseDateRange(since, until)
+}
+
+// Reads the report of the team of a multi-user mate server, with remote_token as the token of a reporter or an admin
+// The titles of the people not sharing them are hidden for the other roles
+func readServerTeamReport(server string, start time.Time, end time.Time) (report TeamReport, err error) {
+	var response TeamReportResponse
+	if err = callRemote(server, "GET", "/api/team/report?"+getPeriodQuery(start, end).Encode(), nil, &response); err != nil {
+		return
+	}
+	report.first, _ = time.Parse("2006-01-02", response.Since)
+	report.last, _ = time.Parse("2006-01-02", response.Until)
+	report.total = time.Duration(response.TotalSeconds) * time.Second
+	for _, project := range response.Projects {
+		projectReport := ProjectReport{name: project.Name, total: time.Duration(project.Seconds) * time.Second}
+		for _, ticket := range project.Tickets {
+			projectReport.tickets = append(projectReport.tickets, TicketTotal{ticket.Title, time.Duration(ticket.Seconds) * time.Second})
+		}
+		report.projects = append(report.projects, projectReport)
+	}
+	for _, person := range response.People {
+		personReport := PersonReport{name: person.Name, total: time.Duration(person.Seconds) * time.Second}
+		for _, project := range person.Projects {
+			personReport.projects = append(personReport.projects, ProjectReport{name: project.Name, total: time.Duration(project.Seconds) * time.Second})
+		}
+		report.people = append(report.people, personReport)
+	}
+	return
+}
+
+// Returns the since and until parameters of the API for the days within [start, end[
+func getPeriodQuery(start time.Time, end time.Time) url.Values {
+	query := url.Values{}
+	if !start.IsZero() {
+		query.Set("since", start.Format("2006-01-02"))
+	}
+	if !end.IsZero() {
+		query.Set("until", end.AddDate(0, 0, -1).Format("2006-01-02"))
+	}
+	return query
+}
+
+// Prints the report of the team merged from the exports of its members (comma separated files or servers),
+// or the one of a multi-user server, over the current week by default
+func showTeamReport(inputs string, server string, since string, until string) error {
+	start, end, err := getTeamPeriod(since, until)
+	if err != nil {
+		return err
+	}
+
+	var report TeamReport
+	if server != "" {
+		if report, err = readServerTeamReport(server, start, end); err != nil {
+			return err
+		}
+	} else {
+		members := make(map[string][]Record)
+		for _, input := range splitConfigList(inputs) {
+			name, source := parseTeamInput(input)
+			if _, found := members[name]; found {
+				return fmt.Errorf("Two inputs are named %s, name them with name=%s", name, source)
+			}
+			records, err := readTeamRecords(source, start, end)
+			if err != nil {
+				return fmt.Errorf("Cannot read the entries of %s: %w", name, err)
+			}
+			members[name] = records
+		}
+		report = computeTeamReport(members, start, end)
+	}
+	if report.total == 0 {
+		return withExitCode(EXIT_NO_DATA, errors.New("Nothing tracked by the team during the period"))
+	}
+	fmt.Print(formatTeamReport(report))
+	return nil
+}
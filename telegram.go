@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const DEFAULT_TELEGRAM_API_URL = "https://api.telegram.org"
+
+// How long a call to getUpdates waits for a message, below API_TIMEOUT
+const TELEGRAM_POLL_TIMEOUT = 20
+
+// An update of the Bot API, only messages being asked for
+type TelegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+// Calls a method of the Bot API for the bot of telegram.token, on telegram.api_url for a local Bot API server
+func callTelegram(method string, body interface{}, result interface{}) error {
+	var response struct {
+		OK          bool        `json:"ok"`
+		Description string      `json:"description"`
+		Result      interface{} `json:"result"`
+	}
+	response.Result = result
+	endpoint := strings.TrimSuffix(getConfig("telegram.api_url", DEFAULT_TELEGRAM_API_URL), "/") +
+		"/bot" + getConfig("telegram.token", "") + "/" + method
+	httpMethod := "GET"
+	if body != nil {
+		httpMethod = "POST"
+	}
+	name := strings.SplitN(method, "?", 2)[0]
+	if err := callJSONAPI(httpMethod, endpoint, nil, body, &response); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if !response.OK {
+		return fmt.Errorf("%s: %s", name, response.Description)
+	}
+	return nil
+}
+
+// The Telegram bot of telegram.token, answering the chats listed in telegram.chats
+// It polls the updates, so that no public address is needed
+type TelegramBot struct {
+	allowed map[string]bool
+	offset  int64
+}
+
+func connectTelegramBot() (*TelegramBot, error) {
+	if _, err := getRequiredConfig("telegram.token", "the token of the bot, given by @BotFather"); err != nil {
+		return nil, err
+	}
+	chatList, err := getRequiredConfig("telegram.chats", "the IDs of the chats allowed to control mate, comma separated")
+	if err != nil {
+		return nil, err
+	}
+	bot := &TelegramBot{allowed: make(map[string]bool)}
+	for _, chat := range splitConfigList(chatList) {
+		if _, err := strconv.ParseInt(chat, 10, 64); err != nil {
+			return nil, newConfigError(fmt.Sprintf("telegram.chats: invalid chat ID \"%s\"", chat))
+		}
+		bot.allowed[chat] = true
+	}
+
+	var me struct {
+		Username string `json:"username"`
+	}
+	if err = callTelegram("getMe", nil, &me); err != nil {
+		return nil, fmt.Errorf("Cannot reach the Telegram bot: %w", err)
+	}
+	fmt.Printf("Telegram bot @%s running, press Ctrl+C to stop\n", me.Username)
+	return bot, nil
+}
+
+func (bot *TelegramBot) poll() (messages []BotMessage, err error) {
+	var updates []TelegramUpdate
+	query := url.Values{"offset": {strconv.FormatInt(bot.offset, 10)}, "timeout": {strconv.Itoa(TELEGRAM_POLL_TIMEOUT)},
+		"allowed_updates": {`["message"]`}}
+	if err = callTelegram("getUpdates?"+query.Encode(), nil, &updates); err != nil {
+		return nil, err
+	}
+	for _, update := range updates {
+		bot.offset = update.UpdateID + 1
+		if update.Message == nil || !isBotCommand(update.Message.Text) {
+			continue
+		}
+		chat := strconv.FormatInt(update.Message.Chat.ID, 10)
+		if !bot.allowed[chat] {
+			fmt.Printf("%s Ignoring %s from chat %s, which is not in telegram.chats\n",
+				getNow().Format(CLOCK_FORMAT), strings.Fields(update.Message.Text)[0], chat)
+			continue
+		}
+		messages = append(messages, BotMessage{chat, update.Message.Text})
+	}
+	return
+}
+
+func (bot *TelegramBot) send(chat string, text string) error {
+	return callTelegram("sendMessage", map[string]string{"chat_id": chat, "text": text}, nil)
+}
+
+func (bot *TelegramBot) chats() []string {
+	return splitConfigList(getConfig("telegram.chats", ""))
+}
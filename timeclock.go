@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Writes the intervals as a timeclock file, as read by ledger and hledger:
+//
+//	i 2021/03/01 09:00:00 acme:website:Fix the login page  notes
+//	o 2021/03/01 10:30:00
+//
+// The account is the title, under the client and the project of the ticket when they are known
+func writeTimeclock(w io.Writer, intervals []Interval, ticketClients map[string]string, notes []Note) error {
+	for _, in := range intervals {
+		var account []string
+		for _, name := range []string{getClient(in.title, ticketClients), getTicketProject(in.title), in.title} {
+			if name != "" {
+				account = append(account, formatTimeclockAccount(name))
+			}
+		}
+		var texts []string
+		for _, n := range getNotesBetween(notes, in.start, in.end) {
+			texts = append(texts, strings.Join(strings.Fields(n.text), " "))
+		}
+		line := "i " + in.start.Format(TIME_FORMAT) + " " + strings.Join(account, ":")
+		if len(texts) != 0 {
+			line += "  " + strings.Join(texts, " | ")
+		}
+		if _, err := fmt.Fprintf(w, "%s\no %s\n", line, in.end.Format(TIME_FORMAT)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Makes a name fit in an account: colons separate the sub-accounts, and two spaces end the account
+func formatTimeclockAccount(name string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(name), " "), ":", "-")
+}
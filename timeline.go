@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const DATE_FORMAT = "2006/01/02"
+const CLOCK_FORMAT = "15:04"
+const TIMELINE_WIDTH = 60
+const MIN_GAP_DURATION = time.Minute * 5
+
+// A period spent on a ticket (STOP periods are not intervals)
+type Interval struct {
+	start time.Time
+	end   time.Time
+	title string
+}
+
+// A period between two intervals during which no ticket was running
+type Gap struct {
+	start time.Time
+	end   time.Time
+}
+
+// Computes the worked intervals, in the order of entries
+// The last interval ends now (or at auto_stop) if its ticket is still running
+func computeIntervals(records []Record) (intervals []Interval) {
+	for i, r := range records {
+		if r.title == STOP_TOKEN {
+			continue
+		}
+		end := getRunningEnd(r.timestamp)
+		if i+1 < len(records) {
+			end = records[i+1].timestamp
+		}
+		intervals = append(intervals, Interval{r.timestamp, end, r.title})
+	}
+	return
+}
+
+// Keeps the part of each interval that lies within the given day
+func clipIntervalsToDay(intervals []Interval, day time.Time) (outIntervals []Interval) {
+	dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	for _, in := range intervals {
+		if !in.end.After(dayStart) || !in.start.Before(dayEnd) {
+			continue
+		}
+		if in.start.Before(dayStart) {
+			in.start = dayStart
+		}
+		if in.end.After(dayEnd) {
+			in.end = dayEnd
+		}
+		outIntervals = append(outIntervals, in)
+	}
+	return
+}
+
+// Computes the time worked during the given day, minus the mandatory break if one is due
+func computeDayTotal(records []Record, day time.Time) (total time.Duration) {
+	intervals := clipIntervalsToDay(computeIntervals(records), day)
+	for _, in := range intervals {
+		total += in.end.Sub(in.start)
+	}
+	return total - computeBreakDeduction(intervals)
+}
+
+// Finds the untracked periods between consecutive intervals
+// Gaps shorter than minDuration are ignored
+func findGaps(intervals []Interval, minDuration time.Duration) (gaps []Gap) {
+	for i := 1; i < len(intervals); i++ {
+		previousEnd, nextStart := intervals[i-1].end, intervals[i].start
+		if nextStart.Sub(previousEnd) >= minDuration {
+			gaps = append(gaps, Gap{previousEnd, nextStart})
+		}
+	}
+	return
+}
+
+// Parses a day given by the user: today, yesterday, YYYY/MM/DD or YYYY-MM-DD
+// An empty string means today
+func parseDate(literal string) (time.Time, error) {
+	today := getNow().Truncate(time.Hour * 24)
+
+	switch literal {
+	case "", "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	for _, layout := range []string{DATE_FORMAT, "2006-01-02"} {
+		if day, err := time.Parse(layout, literal); err == nil {
+			return day, nil
+		}
+	}
+
+	// A weekday is its last occurrence, today included (e.g. "monday" is the start of the week)
+	if name := strings.ToLower(literal); len(name) >= 3 {
+		if weekday, found := WEEKDAYS[name[:3]]; found && strings.HasPrefix(strings.ToLower(weekday.String()), name) {
+			return today.AddDate(0, 0, -((int(today.Weekday()) - int(weekday) + 7) % 7)), nil
+		}
+	}
+	return time.Time{}, errors.New("Invalid date \"" + literal + "\" (expected YYYY/MM/DD, today, yesterday or a weekday)")
+}
+
+// Tells if the file is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Tells if colors can be used, i.e. stdout is a terminal and NO_COLOR is unset
+func useColors() bool {
+	return os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+}
+
+// Returns the block used to draw the nth ticket of the timeline
+// Without colors, tickets are told apart by a letter
+func timelineBlock(n int, colors bool) string {
+	if colors {
+		return fmt.Sprintf("\033[3%dm█\033[0m", n%6+1)
+	}
+	return string(rune('A' + n%26))
+}
+
+func showTimeline(date string) error {
+	day, err := parseDate(date)
+	if err != nil {
+		return err
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+
+	intervals := clipIntervalsToDay(computeIntervals(records), day)
+	if len(intervals) == 0 {
+		fmt.Printf("Nothing to show for %s\n", day.Format(DATE_FORMAT))
+		return nil
+	}
+
+	// The timeline spans whole hours, from the first start to the last end
+	first := intervals[0].start.Truncate(time.Hour)
+	last := intervals[len(intervals)-1].end
+	if last.Truncate(time.Hour) != last {
+		last = last.Truncate(time.Hour).Add(time.Hour)
+	}
+	if !last.After(first) {
+		last = first.Add(time.Hour)
+	}
+	step := last.Sub(first) / TIMELINE_WIDTH
+
+	colors := useColors()
+	var titles []string
+	indexes := make(map[string]int)
+	durations := make(map[string]time.Duration)
+	for _, in := range intervals {
+		if _, found := indexes[in.title]; !found {
+			indexes[in.title] = len(titles)
+			titles = append(titles, in.title)
+		}
+		durations[in.title] += in.end.Sub(in.start)
+	}
+
+	// Hour labels, placed on the column in which the hour starts
+	labels := []rune(strings.Repeat(" ", TIMELINE_WIDTH+5))
+	for hour := first; !hour.After(last); hour = hour.Add(time.Hour) {
+		column := int(hour.Sub(first) / step)
+		if column > 0 && labels[column-1] != ' ' {
+			continue
+		}
+		copy(labels[column:], []rune(hour.Format("15h")))
+	}
+
+	var line strings.Builder
+	for column := 0; column < TIMELINE_WIDTH; column++ {
+		middle := first.Add(step*time.Duration(column) + step/2)
+		block := "·"
+		for _, in := range intervals {
+			if !middle.Before(in.start) && middle.Before(in.end) {
+				block = timelineBlock(indexes[in.title], colors)
+				break
+			}
+		}
+		line.WriteString(block)
+	}
+
+	fmt.Println(day.Format(DATE_FORMAT))
+	fmt.Println(strings.TrimRight(string(labels), " "))
+	fmt.Println(line.String())
+	fmt.Println()
+	for i, title := range titles {
+		fmt.Printf("%s %s\t%v\n", timelineBlock(i, colors), title, durations[title])
+	}
+
+	gaps := findGaps(intervals, MIN_GAP_DURATION)
+	if len(gaps) != 0 {
+		fmt.Println()
+		fmt.Println("Untracked:")
+		for _, g := range gaps {
+			fmt.Printf("  %s - %s\t%v\n", g.start.Format(CLOCK_FORMAT), g.end.Format(CLOCK_FORMAT), g.end.Sub(g.start))
+		}
+	}
+	return nil
+}
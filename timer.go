@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"time"
+)
+
+const TIMER_NAME = ".mate.timer"
+
+// A STOP scheduled by start --for, for the ticket of the given entry
+type Timer struct {
+	deadline time.Time
+	entry    Record
+}
+
+func getTimerPath() string {
+	return getHomeFilePath(TIMER_NAME)
+}
+
+// Reads the scheduled STOP, if any
+// The timer is encrypted like the database
+func readTimer() (timer Timer, found bool, err error) {
+	content, err := files.ReadFile(getTimerPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Timer{}, false, nil
+		}
+		return Timer{}, false, fmt.Errorf("Cannot read the timer: %w", err)
+	}
+	if isEncryptedDatabase(content) {
+		if content, err = decryptDatabase(content); err != nil {
+			return Timer{}, false, err
+		}
+	}
+
+	fields, err := csv.NewReader(bytes.NewReader(content)).Read()
+	if err != nil || len(fields) != 3 {
+		// A broken timer is dropped rather than blocking every command
+		return Timer{}, false, removeTimer()
+	}
+	deadline, err1 := time.Parse(TIME_FORMAT, fields[0])
+	start, err2 := time.Parse(TIME_FORMAT, fields[1])
+	if err1 != nil || err2 != nil {
+		return Timer{}, false, removeTimer()
+	}
+
+	return Timer{deadline, Record{start, fields[2]}}, true, nil
+}
+
+func removeTimer() error {
+	if err := files.Remove(getTimerPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Cannot remove the timer: %w", err)
+	}
+	return nil
+}
+
+// Schedules a STOP of the current ticket after the given duration
+func scheduleStop(duration time.Duration) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	last := records[len(records)-1]
+	deadline := last.timestamp.Add(duration)
+
+	content := []byte(formatRecordFields(deadline.Format(TIME_FORMAT), last.timestamp.Format(TIME_FORMAT), last.title))
+	if isEncryptionEnabled() {
+		if content, err = encryptDatabase(content); err != nil {
+			return err
+		}
+	}
+	if err = files.WriteFile(getTimerPath(), content, 0644); err != nil {
+		return fmt.Errorf("Cannot write the timer: %w", err)
+	}
+	fmt.Printf("Will stop at %s\n", deadline.Format(CLOCK_FORMAT))
+	return nil
+}
+
+// Writes the STOP scheduled by start --for once its deadline is past
+// The timer is dropped if its ticket was stopped or switched in the meantime
+func reconcileTimer() error {
+	timer, found, err := readTimer()
+	if err != nil || !found {
+		return err
+	}
+
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return removeTimer()
+	}
+	last := records[len(records)-1]
+	if !last.timestamp.Equal(timer.entry.timestamp) || last.title != timer.entry.title {
+		return removeTimer()
+	}
+
+	if timer.deadline.After(getNow()) {
+		return nil
+	}
+	if err = writeTicketAt(timer.deadline, STOP_TOKEN); err != nil {
+		return err
+	}
+	if err = removeTimer(); err != nil {
+		return err
+	}
+	fmt.Printf("(%s was automatically stopped at %s)\n", last.title, timer.deadline.Format(CLOCK_FORMAT))
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Computes the time spent per ticket and per day, keyed by DATE_FORMAT then title
+// Intervals spanning midnight are split between their days
+func computeTicketTotalsPerDay(records []Record) map[string]map[string]time.Duration {
+	totals := make(map[string]map[string]time.Duration)
+	for _, in := range computeIntervals(records) {
+		for start := in.start; start.Before(in.end); {
+			end := start.Truncate(time.Hour*24).AddDate(0, 0, 1)
+			if end.After(in.end) {
+				end = in.end
+			}
+			key := start.Format(DATE_FORMAT)
+			if totals[key] == nil {
+				totals[key] = make(map[string]time.Duration)
+			}
+			totals[key][in.title] += end.Sub(start)
+			start = end
+		}
+	}
+	return totals
+}
+
+// Formats a duration as decimal hours, as timesheet systems expect them
+func formatHours(duration time.Duration) string {
+	if duration == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", duration.Hours())
+}
+
+// Builds the timesheet of the week: a header row, a row per ticket and a total row,
+// with a column per day and a total column
+func buildWeekTimesheet(records []Record, monday time.Time) (rows [][]string) {
+	totals := computeTicketTotalsPerDay(records)
+
+	var days []time.Time
+	header := []string{"Ticket"}
+	for i := 0; i < 7; i++ {
+		day := monday.AddDate(0, 0, i)
+		days = append(days, day)
+		header = append(header, day.Format("Mon 01/02"))
+	}
+	rows = append(rows, append(header, "Total"))
+
+	var titles []string
+	for _, day := range days {
+		for title := range totals[day.Format(DATE_FORMAT)] {
+			if !contains(titles, title) {
+				titles = append(titles, title)
+			}
+		}
+	}
+	sort.Strings(titles)
+
+	dayTotals := make([]time.Duration, len(days))
+	var weekTotal time.Duration
+	for _, title := range titles {
+		row := []string{title}
+		var ticketTotal time.Duration
+		for i, day := range days {
+			duration := totals[day.Format(DATE_FORMAT)][title]
+			row = append(row, formatHours(duration))
+			ticketTotal += duration
+			dayTotals[i] += duration
+		}
+		weekTotal += ticketTotal
+		rows = append(rows, append(row, formatHours(ticketTotal)))
+	}
+
+	totalRow := []string{"Total"}
+	for _, duration := range dayTotals {
+		totalRow = append(totalRow, formatHours(duration))
+	}
+	return append(rows, append(totalRow, formatHours(weekTotal)))
+}
+
+// Prints rows as a table aligned on columns (the first one aligned left, the others right)
+func printTable(rows [][]string) {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if width := len([]rune(cell)); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			padding := strings.Repeat(" ", widths[i]-len([]rune(cell)))
+			if i == 0 {
+				line.WriteString(cell + padding)
+			} else {
+				line.WriteString("  " + padding + cell)
+			}
+		}
+		fmt.Println(strings.TrimRight(line.String(), " "))
+	}
+}
+
+// Prints the timesheet of the week of the given day, as a table or as CSV
+func showTimesheet(date string, asCSV bool) error {
+	day, err := parseDate(date)
+	if err != nil {
+		return err
+	}
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+
+	rows := buildWeekTimesheet(records, getWeekStart(day))
+	if !asCSV {
+		printTable(rows)
+		return nil
+	}
+	return csv.NewWriter(os.Stdout).WriteAll(rows)
+}
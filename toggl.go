@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const TOGGL_API_URL = "https://api.track.toggl.com/api/v9"
+const TOGGL_NAME = ".mate.toggl.csv"
+const TOGGL_CSV_HEADER = "start,toggl_id\n"
+
+// A time entry of the Toggl Track API
+type TogglEntry struct {
+	ID          int64    `json:"id,omitempty"`
+	WorkspaceID int64    `json:"workspace_id"`
+	Description string   `json:"description"`
+	Start       string   `json:"start"`
+	Stop        *string  `json:"stop"`
+	Duration    int64    `json:"duration"`
+	Tags        []string `json:"tags"`
+	CreatedWith string   `json:"created_with,omitempty"`
+}
+
+// Returns the path of the table mapping the starts of the synchronized intervals to their Toggl time entry
+func getTogglPath() string {
+	return getHomeFilePath(TOGGL_NAME)
+}
+
+func getTogglHeaders() (map[string]string, error) {
+	token, err := getRequiredConfig("toggl.token", "see https://track.toggl.com/profile")
+	if err != nil {
+		return nil, err
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte(token + ":api_token"))
+	return map[string]string{"Authorization": "Basic " + credentials}, nil
+}
+
+// Returns toggl.workspace_id, or the default workspace of the user
+func getTogglWorkspace(headers map[string]string) (int64, error) {
+	if workspace := getConfigInt("toggl.workspace_id", "0"); workspace != 0 {
+		return int64(workspace), nil
+	}
+	var me struct {
+		DefaultWorkspaceID int64 `json:"default_workspace_id"`
+	}
+	err := callJSONAPI("GET", TOGGL_API_URL+"/me", headers, nil, &me)
+	return me.DefaultWorkspaceID, err
+}
+
+// Synchronizes the entries between since and until with Toggl Track
+// Local intervals not synchronized yet are pushed, and remote time entries unknown locally are pulled
+func syncToggl(since string, until string) error {
+	start, end, err := getSyncRange(since, until)
+	if err != nil {
+		return err
+	}
+
+	headers, err := getTogglHeaders()
+	if err != nil {
+		return err
+	}
+	workspace, err := getTogglWorkspace(headers)
+	if err != nil {
+		return fmt.Errorf("Cannot reach Toggl: %w", err)
+	}
+
+	mapping, err := readTable(getTogglPath())
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool)
+	for _, id := range mapping {
+		known[id] = true
+	}
+
+	// Pull first, so that the pulled entries are not pushed back
+	query := url.Values{}
+	query.Set("start_date", fromDbTime(start).Format(time.RFC3339))
+	query.Set("end_date", fromDbTime(end).Format(time.RFC3339))
+	var remote []TogglEntry
+	if err = callJSONAPI("GET", TOGGL_API_URL+"/me/time_entries?"+query.Encode(), headers, nil, &remote); err != nil {
+		return fmt.Errorf("Cannot fetch the Toggl time entries: %w", err)
+	}
+
+	var periods []Interval
+	pulledIDs := make(map[time.Time]string)
+	for _, entry := range remote {
+		id := fmt.Sprint(entry.ID)
+		if known[id] || entry.Stop == nil || entry.Duration < 0 {
+			continue
+		}
+		entryStart, err1 := time.Parse(time.RFC3339, entry.Start)
+		entryStop, err2 := time.Parse(time.RFC3339, *entry.Stop)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		p := Interval{toDbTime(entryStart.Local()), toDbTime(entryStop.Local()), buildImportedTitle(entry.Description, entry.Tags)}
+		periods = append(periods, p)
+		pulledIDs[p.start] = id
+	}
+	sortPeriods(periods)
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	pulled, skipped := convertPeriods(periods, records, false)
+	printSkippedPeriods(skipped, false)
+	pulledCount := 0
+	for _, r := range pulled {
+		if id, found := pulledIDs[r.timestamp]; found && r.title != STOP_TOKEN {
+			mapping[r.timestamp.Format(TIME_FORMAT)] = id
+			pulledCount++
+		}
+	}
+	if _, _, err = mergeRecords(pulled); err != nil {
+		return err
+	}
+	if records, err = getRecords(); err != nil {
+		return err
+	}
+
+	pushed, failed := 0, 0
+	for _, in := range getCompletedIntervals(records, start, end) {
+		key := in.start.Format(TIME_FORMAT)
+		if _, found := mapping[key]; found {
+			continue
+		}
+		stop := fromDbTime(in.end).Format(time.RFC3339)
+		entry := TogglEntry{
+			WorkspaceID: workspace,
+			Description: strings.TrimSpace(TAG_PATTERN.ReplaceAllString(in.title, "")),
+			Start:       fromDbTime(in.start).Format(time.RFC3339),
+			Stop:        &stop,
+			Duration:    int64(in.end.Sub(in.start).Seconds()),
+			Tags:        getTicketTags(in.title),
+			CreatedWith: "mate",
+		}
+		var created TogglEntry
+		if err = callJSONAPI("POST", fmt.Sprintf("%s/workspaces/%d/time_entries", TOGGL_API_URL, workspace), headers, entry, &created); err != nil {
+			fmt.Printf("Cannot push %s (%s): %v\n", in.title, key, err)
+			failed++
+			continue
+		}
+		mapping[key] = fmt.Sprint(created.ID)
+		pushed++
+	}
+
+	if err = writeTable(getTogglPath(), TOGGL_CSV_HEADER, mapping); err != nil {
+		return err
+	}
+	fmt.Printf("%d entries pushed to Toggl, %d pulled\n", pushed, pulledCount)
+	if failed != 0 {
+		return fmt.Errorf("%d entries could not be pushed to Toggl", failed)
+	}
+	return nil
+}
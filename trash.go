@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const TRASH_NAME = ".mate.trash.csv"
+const TRASH_HEADER = "id,deleted_at,timestamp,title\n"
+const TRASH_RETENTION = time.Hour * 24 * 30
+
+// Entries removed together by a delete or a clear
+type TrashBatch struct {
+	id        int
+	deletedAt time.Time
+	records   []Record
+}
+
+func getTrashPath() string {
+	return getHomeFilePath(TRASH_NAME)
+}
+
+// Reads the trash, oldest batch first, leaving out the batches older than TRASH_RETENTION
+// The trash is encrypted like the database
+func readTrash() (batches []TrashBatch, err error) {
+	content, err := files.ReadFile(getTrashPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read the trash: %w", err)
+	}
+	if isEncryptedDatabase(content) {
+		if content, err = decryptDatabase(content); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read the trash: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	expiry := getNow().Add(-TRASH_RETENTION)
+	positions := make(map[int]int)
+	for _, row := range rows[1:] {
+		id, err := strconv.Atoi(row[0])
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read the trash: %w", err)
+		}
+		deletedAt, err := time.Parse(TIME_FORMAT, row[1])
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read the trash: %w", err)
+		}
+		timestamp, err := time.Parse(TIME_FORMAT, row[2])
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read the trash: %w", err)
+		}
+		if deletedAt.Before(expiry) {
+			continue
+		}
+		position, found := positions[id]
+		if !found {
+			position = len(batches)
+			positions[id] = position
+			batches = append(batches, TrashBatch{id: id, deletedAt: deletedAt})
+		}
+		batches[position].records = append(batches[position].records, Record{timestamp, row[3]})
+	}
+	sort.SliceStable(batches, func(i, j int) bool { return batches[i].id < batches[j].id })
+	return batches, nil
+}
+
+func writeTrash(batches []TrashBatch) error {
+	var content strings.Builder
+	content.WriteString(TRASH_HEADER)
+	for _, batch := range batches {
+		for _, r := range batch.records {
+			content.WriteString(formatRecordFields(strconv.Itoa(batch.id), batch.deletedAt.Format(TIME_FORMAT),
+				r.timestamp.Format(TIME_FORMAT), r.title))
+		}
+	}
+
+	data := []byte(content.String())
+	if isEncryptionEnabled() {
+		var err error
+		if data, err = encryptDatabase(data); err != nil {
+			return err
+		}
+	}
+	if err := files.WriteFile(getTrashPath(), data, 0644); err != nil {
+		return fmt.Errorf("Cannot write the trash: %w", err)
+	}
+	return nil
+}
+
+// Moves entries to the trash, as a new batch
+// Returns the ID of the batch, to restore it with
+func trashRecords(records []Record) (int, error) {
+	batches, err := readTrash()
+	if err != nil {
+		return 0, err
+	}
+	id := 1
+	if len(batches) != 0 {
+		id = batches[len(batches)-1].id + 1
+	}
+	return id, writeTrash(append(batches, TrashBatch{id, getNow(), records}))
+}
+
+func showTrash() error {
+	batches, err := readTrash()
+	if err != nil {
+		return err
+	}
+	if len(batches) == 0 {
+		fmt.Println("The trash is empty")
+		return nil
+	}
+	for _, batch := range batches {
+		first, last := batch.records[0], batch.records[len(batch.records)-1]
+		fmt.Printf("%d\tdeleted %s\t%d entries\t%s - %s (%s)\n", batch.id, batch.deletedAt.Format(TIME_FORMAT),
+			len(batch.records), first.timestamp.Format(TIME_FORMAT), last.timestamp.Format(TIME_FORMAT), getEntryName(last))
+	}
+	return nil
+}
+
+// Puts the entries of a batch back into the database
+func restoreTrash(literal string) error {
+	id, err := strconv.Atoi(literal)
+	if err != nil {
+		return fmt.Errorf("Invalid ID \"%s\" (see mate trash list)", literal)
+	}
+
+	batches, err := readTrash()
+	if err != nil {
+		return err
+	}
+	for i, batch := range batches {
+		if batch.id != id {
+			continue
+		}
+		added, skipped, err := mergeRecords(batch.records)
+		if err != nil {
+			return err
+		}
+		if err = writeTrash(append(batches[:i], batches[i+1:]...)); err != nil {
+			return err
+		}
+		fmt.Printf("%d entries restored, %d already present\n", added, skipped)
+		return nil
+	}
+	return fmt.Errorf("No batch %d in the trash (see mate trash list)", id)
+}
+
+// Finds an entry: the last one by default, else the one of the given time (HH:MM, today)
+// or timestamp (YYYY/MM/DD HH:MM)
+func findEntry(records []Record, literal string) (int, error) {
+	if len(records) == 0 {
+		return 0, withExitCode(EXIT_NO_DATA, errors.New("No entry saved for now"))
+	}
+	if literal == "" {
+		return len(records) - 1, nil
+	}
+
+	var minute time.Time
+	if clock, err := parseClock(literal); err == nil {
+		minute = getNow().Truncate(time.Hour * 24).Add(clock)
+	} else if t, err := time.Parse("2006/01/02 15:04", literal); err == nil {
+		minute = t
+	} else {
+		return 0, fmt.Errorf("Invalid entry \"%s\" (expected HH:MM or \"YYYY/MM/DD HH:MM\")", literal)
+	}
+
+	var matches []int
+	for i, r := range records {
+		if r.timestamp.Truncate(time.Minute).Equal(minute) {
+			matches = append(matches, i)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("No entry at %s", minute.Format("2006/01/02 15:04"))
+	case 1:
+		return matches[0], nil
+	}
+	return 0, fmt.Errorf("%d entries at %s, handle them one by one from the last", len(matches), minute.Format("2006/01/02 15:04"))
+}
+
+// Moves an entry to the trash
+func deleteEntry(literal string) error {
+	records, err := getRecords()
+	if err != nil {
+		return err
+	}
+	i, err := findEntry(records, literal)
+	if err != nil {
+		return err
+	}
+
+	deleted := records[i]
+	if err = writeRecords(append(records[:i:i], records[i+1:]...)); err != nil {
+		return err
+	}
+	id, err := trashRecords([]Record{deleted})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %s %s (undo with mate trash restore %d)\n", deleted.timestamp.Format(TIME_FORMAT), getEntryName(deleted), id)
+	return nil
+}
+
+func getEntryName(r Record) string {
+	if r.title == STOP_TOKEN {
+		return "STOP"
+	}
+	return r.title
+}
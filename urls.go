@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const URLS_NAME = ".mate.urls.csv"
+const URLS_CSV_HEADER = "title,url\n"
+
+func getURLsPath() string {
+	return getHomeFilePath(URLS_NAME)
+}
+
+// Records the URL of a ticket, as given by start --url
+func setTicketURL(title string, address string) error {
+	if parsed, err := url.Parse(address); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("Invalid URL \"%s\" (expected e.g. https://example.com/PROJ-123)", address)
+	}
+	return mergeTable(getURLsPath(), URLS_CSV_HEADER, map[string]string{title: address})
+}
+
+// Returns the URL of a ticket: the one given by start --url, else the one of its key (PROJ-123) from urls.template,
+// in which {key} is replaced by the key, or from jira.url:
+//
+//	[urls]
+//	template = "https://jira.example.com/browse/{key}"
+func getTicketURL(title string) (string, error) {
+	ticketURLs, err := readTable(getURLsPath())
+	if err != nil {
+		return "", err
+	}
+	if address, found := ticketURLs[title]; found {
+		return address, nil
+	}
+	key := TICKET_KEY_PATTERN.FindString(title)
+	if key == "" {
+		return "", nil
+	}
+	if template := getConfig("urls.template", ""); template != "" {
+		return strings.ReplaceAll(template, "{key}", url.PathEscape(key)), nil
+	}
+	if jira := getConfig("jira.url", ""); jira != "" {
+		return strings.TrimSuffix(jira, "/") + "/browse/" + url.PathEscape(key), nil
+	}
+	return "", nil
+}
+
+// Opens the URL of the running ticket (or of the last one) in the browser, or with printOnly prints it
+func openTicketURL(printOnly bool) error {
+	records, err := getRecentRecords(getNow())
+	if err != nil {
+		return err
+	}
+	var title string
+	for i := len(records) - 1; i >= 0 && title == ""; i-- {
+		if records[i].title != STOP_TOKEN {
+			title = records[i].title
+		}
+	}
+	if title == "" {
+		return errNoRecord
+	}
+	address, err := getTicketURL(title)
+	if err != nil {
+		return err
+	}
+	if address == "" {
+		return withExitCode(EXIT_NO_DATA, fmt.Errorf("No URL for %s: start it with --url, or set urls.template in %s", title, getConfigPath()))
+	}
+	if printOnly {
+		fmt.Println(address)
+		return nil
+	}
+	if err = openInBrowser(address); err != nil {
+		return fmt.Errorf("Cannot open %s: %w", address, err)
+	}
+	fmt.Printf("Opening %s\n", address)
+	return nil
+}
+
+// Opens a URL with the browser of the desktop
+func openInBrowser(address string) error {
+	var command *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		command = exec.Command("open", address)
+	case "windows":
+		command = exec.Command("rundll32", "url.dll,FileProtocolHandler", address)
+	default:
+		if _, err := exec.LookPath("xdg-open"); err != nil {
+			return errors.New("xdg-open is not installed")
+		}
+		command = exec.Command("xdg-open", address)
+	}
+	return command.Start()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// The directory of the files of the users of mate serve, in the home directory or the profile of the owner
+const USERS_DIR_NAME = ".mate.users"
+
+// The user of the request being served by mate serve, empty for the owner of the server
+// Set while serverLock is held, so that the files of the user are read and written
+var serverUser string
+
+// The role of the token of the request being served, set with serverUser
+var serverRole string
+
+// Returns the directory of the files: the one of the current profile, or of the user of the request being served
+func getFilesPath() string {
+	return getUserFilesPath(serverUser)
+}
+
+// Returns the directory of the files of a user of mate serve, or of the current profile for the owner ("")
+func getUserFilesPath(user string) string {
+	if user != "" {
+		return getUsersPath() + "/" + user
+	}
+	return getProfilePath(getProfile())
+}
+
+func getUsersPath() string {
+	return getProfilePath(getProfile()) + "/" + USERS_DIR_NAME
+}
+
+// The accounts of mate serve: the owner of the server, with serve.token, is an admin, and the [users]
+// of the config have their own database:
+//
+//	[users]
+//	alice = "<token>"
+//	bob = "<token>"
+//	carol = "<token>"
+//
+//	[roles]
+//	bob = "reporter"
+//	carol = "admin"
+//
+//	[sharing]
+//	alice = "true"
+//
+// A user only reads and writes its own entries, and sees the report of the team without the titles of
+// the others unless they share them. A reporter reads the report of the team with all the titles, and
+// the status of the users. An admin also reads and writes the entries of the users, with a user parameter
+type ServerAccounts struct {
+	ownerToken string
+	// The names of the users, keyed by token
+	users map[string]string
+}
+
+// Reads the accounts of the config, creating the directories of the users
+func loadServerAccounts(ownerToken string) (ServerAccounts, error) {
+	accounts := ServerAccounts{ownerToken, make(map[string]string)}
+	for key, token := range config {
+		if !strings.HasPrefix(key, "users.") {
+			continue
+		}
+		name := strings.TrimPrefix(key, "users.")
+		if !PROFILE_NAME_PATTERN.MatchString(name) {
+			return accounts, newConfigError(fmt.Sprintf("%s: Invalid user name (expected letters, digits, \"-\", \"_\" or \".\")", key))
+		}
+		if token == "" {
+			return accounts, newConfigError(key + ": Missing the token of the user")
+		}
+		if _, found := accounts.users[token]; found || token == ownerToken {
+			return accounts, newConfigError(key + ": The token of the user is already the one of another account")
+		}
+		accounts.users[token] = name
+		if err := os.MkdirAll(getUsersPath()+"/"+name, 0700); err != nil {
+			return accounts, fmt.Errorf("Cannot create the directory of %s: %w", name, err)
+		}
+	}
+	for key := range config {
+		if name := strings.TrimPrefix(key, "roles."); name != key && !contains(accounts.names(), name) {
+			return accounts, newConfigError(key + ": Unknown user, missing from [users]")
+		}
+	}
+	return accounts, nil
+}
+
+// Returns the role of a user: user, reporter or admin (always for the owner)
+func (accounts ServerAccounts) getRole(user string) string {
+	if user == "" {
+		return "admin"
+	}
+	return getConfig("roles."+user, "user")
+}
+
+// Returns the names of the users, sorted
+func (accounts ServerAccounts) names() (names []string) {
+	for _, name := range accounts.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// Returns the user of a request ("" for the owner), from its "Authorization: Bearer <token>" header
+// As browsers cannot set headers on WebSockets, a token parameter is accepted too
+func (accounts ServerAccounts) authenticate(r *http.Request) (user string, found bool) {
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if given == "" {
+		given = r.URL.Query().Get("token")
+	}
+	if given == "" {
+		return "", false
+	}
+	if subtle.ConstantTimeCompare([]byte(given), []byte(accounts.ownerToken)) == 1 {
+		return "", true
+	}
+	// Compared to every token, for the time taken not to tell which one is close
+	for token, name := range accounts.users {
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1 {
+			user, found = name, true
+		}
+	}
+	return
+}
+
+// Runs a function on the files of a user ("" for the owner), serverLock being held
+func runAsUser(user string, fn func() error) error {
+	previous := serverUser
+	serverUser = user
+	defer func() { serverUser = previous }()
+	return fn()
+}
@@ -0,0 +1,184 @@
+"use strict";
+
+// The token of the API is kept in the browser, and sent as a Bearer token
+let token = localStorage.getItem("mate-token") || "";
+let status = null;
+let statusReceivedAt = 0;
+
+const $ = (id) => document.getElementById(id);
+
+async function api(method, path, body) {
+  const response = await fetch(path, {
+    method,
+    headers: { Authorization: "Bearer " + token, "Content-Type": "application/json" },
+    body: body ? JSON.stringify(body) : undefined,
+  });
+  const content = await response.json();
+  if (response.status === 401) {
+    showLogin();
+  }
+  if (!response.ok) {
+    throw new Error(content.error || response.statusText);
+  }
+  return content;
+}
+
+function formatDuration(seconds) {
+  seconds = Math.max(0, Math.floor(seconds));
+  const h = Math.floor(seconds / 3600);
+  const m = Math.floor((seconds % 3600) / 60);
+  const s = seconds % 60;
+  return h + ":" + String(m).padStart(2, "0") + ":" + String(s).padStart(2, "0");
+}
+
+function formatHours(seconds) {
+  return (seconds / 3600).toFixed(1) + "h";
+}
+
+function showLogin() {
+  $("login").hidden = false;
+  $("dashboard").hidden = true;
+}
+
+function renderTimer() {
+  if (!status) {
+    return;
+  }
+  const elapsed = (Date.now() - statusReceivedAt) / 1000;
+  $("title").textContent = status.running ? status.title : "No ticket running";
+  $("elapsed").textContent = status.running ? formatDuration(status.elapsed_seconds + elapsed) : " ";
+  const today = status.today_seconds + (status.running ? elapsed : 0);
+  $("today").textContent = "Today: " + formatDuration(today) +
+    (status.target_seconds ? " / " + formatDuration(status.target_seconds) : "");
+}
+
+function renderTimeline(intervals) {
+  const timeline = $("timeline");
+  timeline.replaceChildren();
+  for (const interval of intervals) {
+    const start = new Date(interval.start);
+    const end = new Date(interval.end);
+    const startOfDay = new Date(start).setHours(0, 0, 0, 0);
+    const block = document.createElement("div");
+    block.style.left = ((start - startOfDay) / 864e5) * 100 + "%";
+    block.style.width = ((end - start) / 864e5) * 100 + "%";
+    block.title = interval.title + " (" + start.toLocaleTimeString() + " - " + end.toLocaleTimeString() + ")";
+    timeline.appendChild(block);
+  }
+}
+
+function renderTickets(report) {
+  const list = $("tickets");
+  list.replaceChildren();
+  for (const ticket of report.tickets || []) {
+    const item = document.createElement("li");
+    item.textContent = ticket.title + ": " + formatDuration(ticket.seconds);
+    list.appendChild(item);
+  }
+}
+
+function renderWeek(week) {
+  const chart = $("week");
+  chart.replaceChildren();
+  const max = Math.max(1, ...week.days.map((d) => Math.max(d.total_seconds, d.target_seconds)));
+  for (const day of week.days) {
+    const column = document.createElement("div");
+    column.className = "day";
+    const bar = document.createElement("div");
+    bar.className = "bar" + (day.total_seconds < day.target_seconds ? " under" : "");
+    bar.style.height = (day.total_seconds / max) * 100 + "%";
+    bar.title = formatHours(day.total_seconds) + " / " + formatHours(day.target_seconds);
+    const label = document.createElement("div");
+    label.textContent = new Date(day.date + "T00:00:00").toLocaleDateString(undefined, { weekday: "short" });
+    column.append(bar, label);
+    chart.appendChild(column);
+  }
+}
+
+async function refresh() {
+  try {
+    status = await api("GET", "/api/status");
+    statusReceivedAt = Date.now();
+    renderTimer();
+    const [timeline, report, week] = await Promise.all([
+      api("GET", "/api/timeline"),
+      api("GET", "/api/report"),
+      api("GET", "/api/week"),
+    ]);
+    renderTimeline(timeline);
+    renderTickets(report);
+    renderWeek(week);
+    $("login").hidden = true;
+    $("dashboard").hidden = false;
+    $("error").textContent = "";
+  } catch (error) {
+    $("error").textContent = error.message;
+  }
+}
+
+async function act(action) {
+  const title = $("new-title").value.trim();
+  try {
+    await api("POST", "/api/" + action, action === "stop" ? undefined : { title });
+    $("new-title").value = "";
+    await refresh();
+  } catch (error) {
+    $("error").textContent = error.message;
+  }
+}
+
+$("login").addEventListener("submit", (event) => {
+  event.preventDefault();
+  token = $("token").value;
+  localStorage.setItem("mate-token", token);
+  refresh();
+  connect();
+});
+
+$("controls").addEventListener("submit", (event) => {
+  event.preventDefault();
+  act("start");
+});
+
+for (const button of document.querySelectorAll("#controls button[type=button]")) {
+  button.addEventListener("click", () => act(button.dataset.action));
+}
+
+// The server pushes the status whenever the running ticket changes; polling is only a fallback
+let pollTimer = null;
+
+function connect() {
+  const scheme = location.protocol === "https:" ? "wss:" : "ws:";
+  const socket = new WebSocket(scheme + "//" + location.host + "/api/events?token=" + encodeURIComponent(token));
+  socket.onopen = () => {
+    clearInterval(pollTimer);
+    pollTimer = null;
+  };
+  socket.onmessage = (event) => {
+    const message = JSON.parse(event.data);
+    if (message.type !== "status") {
+      return;
+    }
+    const changed = !status || status.running !== message.running || status.title !== message.title;
+    status = message;
+    statusReceivedAt = Date.now();
+    renderTimer();
+    if (changed) {
+      refresh();
+    }
+  };
+  socket.onclose = () => {
+    if (!pollTimer) {
+      pollTimer = setInterval(refresh, 30000);
+    }
+    setTimeout(connect, 5000);
+  };
+}
+
+if (token) {
+  refresh();
+  connect();
+} else {
+  showLogin();
+}
+setInterval(renderTimer, 1000);
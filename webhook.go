@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const DEFAULT_WEBHOOK_EVENTS = "start,stop,switch,day_complete"
+
+// The JSON payload posted to the webhooks
+type WebhookPayload struct {
+	Event           string `json:"event"`
+	Title           string `json:"title,omitempty"`
+	Start           string `json:"start,omitempty"`
+	End             string `json:"end,omitempty"`
+	DurationSeconds int64  `json:"duration_seconds,omitempty"`
+	PreviousTitle   string `json:"previous_title,omitempty"`
+	Date            string `json:"date,omitempty"`
+	TotalSeconds    int64  `json:"total_seconds,omitempty"`
+}
+
+// Posts the payload to the URLs of webhooks.urls, if its event is among webhooks.events
+func postWebhooks(payload WebhookPayload) {
+	urls := splitConfigList(getConfig("webhooks.urls", ""))
+	if len(urls) == 0 || !contains(splitConfigList(getConfig("webhooks.events", DEFAULT_WEBHOOK_EVENTS)), payload.Event) {
+		return
+	}
+	for _, url := range urls {
+		if err := callJSONAPI("POST", url, nil, payload, nil); err != nil {
+			fmt.Printf("The %s webhook failed: %v\n", payload.Event, err)
+		}
+	}
+}
+
+func getTicketEventPayload(event TicketEvent) WebhookPayload {
+	payload := WebhookPayload{
+		Event:         event.name,
+		Title:         event.title,
+		Start:         fromDbTime(event.start).Format(time.RFC3339),
+		PreviousTitle: event.previous.title,
+	}
+	if !event.end.IsZero() {
+		payload.End = fromDbTime(event.end).Format(time.RFC3339)
+		payload.DurationSeconds = int64(event.end.Sub(event.start).Seconds())
+	}
+	return payload
+}
+
+// Posts the day_complete webhook once the day target is reached (once per day)
+func checkDayCompleteWebhook() error {
+	if getConfig("webhooks.urls", "") == "" {
+		return nil
+	}
+	today := getNow().Truncate(time.Hour * 24)
+	target := getDayTarget(today)
+	records, err := getRecentRecords(today)
+	if err != nil {
+		return err
+	}
+	total := computeDayTotal(records, today)
+	if target == 0 || total < target {
+		return nil
+	}
+	if marked, err := markNotified("webhook day " + today.Format(DATE_FORMAT)); !marked {
+		return err
+	}
+	postWebhooks(WebhookPayload{Event: "day_complete", Date: today.Format("2006-01-02"), TotalSeconds: int64(total.Seconds())})
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestCheckDayCompleteWebhook(t *testing.T) {
+	tests := []struct {
+		name     string
+		database string
+		want     int32
+	}{
+		{"nothing tracked", "", 0},
+		{"short day", CSV_HEADER + "2026/10/14 09:00:00,A\n2026/10/14 10:00:00,mate:STOP\n", 0},
+		{"day complete", CSV_HEADER + "2026/10/14 04:00:00,A\n2026/10/14 11:30:00,mate:STOP\n", 1},
+		{"complete with a ticket running since yesterday", CSV_HEADER + "2026/10/13 23:00:00,A\n", 1},
+		{"complete yesterday only", CSV_HEADER + "2026/10/13 08:00:00,A\n2026/10/13 18:00:00,mate:STOP\n", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useMemoryStore(t, test.database)
+			posts := useWebhookServer(t)
+
+			// Posted once per day
+			for i := 0; i < 2; i++ {
+				if err := checkDayCompleteWebhook(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := atomic.LoadInt32(posts); got != test.want {
+				t.Errorf("got %d posts, want %d", got, test.want)
+			}
+		})
+	}
+}
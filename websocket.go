@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Appended to the key of the client to compute the accept header (RFC 6455)
+const WEBSOCKET_GUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	WEBSOCKET_TEXT  = 0x1
+	WEBSOCKET_CLOSE = 0x8
+	WEBSOCKET_PING  = 0x9
+	WEBSOCKET_PONG  = 0xA
+)
+
+// The max size of the frames of the clients, which are not expected to send anything but control frames
+const MAX_WEBSOCKET_FRAME = 64 * 1024
+
+// A minimal server side WebSocket connection: text messages are sent, received messages are discarded
+type WebSocket struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	lock   sync.Mutex // Serializes the writes
+}
+
+// Upgrades an HTTP request to a WebSocket connection
+func acceptWebSocket(w http.ResponseWriter, r *http.Request) (*WebSocket, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		return nil, errors.New("expected a WebSocket handshake")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("the connection cannot be upgraded")
+	}
+	conn, buffer, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	accept := sha1.Sum([]byte(key + WEBSOCKET_GUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n"
+	if _, err = conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &WebSocket{conn: conn, reader: buffer.Reader}, nil
+}
+
+// Sends a frame; server frames are not masked
+func (ws *WebSocket) writeFrame(opcode byte, payload []byte) error {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+	if _, err := ws.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ws *WebSocket) writeText(message []byte) error {
+	return ws.writeFrame(WEBSOCKET_TEXT, message)
+}
+
+// Reads the frames of the client until it closes the connection, answering its pings
+func (ws *WebSocket) readUntilClosed() error {
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(ws.reader, header[:]); err != nil {
+			return err
+		}
+		opcode, masked := header[0]&0x0F, header[1]&0x80 != 0
+		length := uint64(header[1] & 0x7F)
+		switch length {
+		case 126:
+			var extended [2]byte
+			if _, err := io.ReadFull(ws.reader, extended[:]); err != nil {
+				return err
+			}
+			length = uint64(binary.BigEndian.Uint16(extended[:]))
+		case 127:
+			var extended [8]byte
+			if _, err := io.ReadFull(ws.reader, extended[:]); err != nil {
+				return err
+			}
+			length = binary.BigEndian.Uint64(extended[:])
+		}
+		if length > MAX_WEBSOCKET_FRAME {
+			return errors.New("WebSocket frame too large")
+		}
+
+		var mask [4]byte
+		if masked {
+			if _, err := io.ReadFull(ws.reader, mask[:]); err != nil {
+				return err
+			}
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(ws.reader, payload); err != nil {
+			return err
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+
+		switch opcode {
+		case WEBSOCKET_CLOSE:
+			ws.writeFrame(WEBSOCKET_CLOSE, nil)
+			return nil
+		case WEBSOCKET_PING:
+			if err := ws.writeFrame(WEBSOCKET_PONG, payload); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (ws *WebSocket) close() {
+	ws.conn.Close()
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const WINDOWS_NAME = ".mate.windows.csv"
+const WINDOWS_HEADER = "at,title\n"
+
+// How long the samples of the active window are kept for mate retro
+const WINDOW_SAMPLES_KEPT = 30 * 24 * time.Hour
+
+// A ticket, and the pattern of the window titles it is suggested for
+type WindowRule struct {
+	pattern *regexp.Regexp
+	title   string
+}
+
+// The ticket suggested by the active window at a check of the daemon
+type WindowSample struct {
+	at    time.Time
+	title string
+}
+
+// Compiles the rules of the [windows] section, a regular expression (quoted if need be) of the window titles
+// for each ticket, in which $1 stands for the first group:
+//
+//	[windows]
+//	"Visual Studio Code.*(PROJ-[0-9]+)" = "$1"
+//	"(?i)zoom meeting" = "Meeting"
+//
+// The patterns are tried in their alphabetical order
+func compileWindowRules(options map[string]string) (rules []WindowRule, err error) {
+	var keys []string
+	for key := range options {
+		if strings.HasPrefix(key, "windows.") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		pattern, err := regexp.Compile(strings.TrimPrefix(key, "windows."))
+		if err != nil {
+			return nil, fmt.Errorf("%s: Invalid pattern (%v)", key, err)
+		}
+		rules = append(rules, WindowRule{pattern, options[key]})
+	}
+	return rules, nil
+}
+
+// Returns the ticket of the first rule matching a window title, empty if none matches
+func matchWindowRules(window string, rules []WindowRule) string {
+	for _, rule := range rules {
+		if match := rule.pattern.FindStringSubmatchIndex(window); match != nil {
+			return strings.TrimSpace(string(rule.pattern.ExpandString(nil, rule.title, window, match)))
+		}
+	}
+	return ""
+}
+
+// Returns the title of the focused window, prefixed by its application on macOS
+// Wayland has no generic API: Sway and Hyprland are supported
+func getActiveWindowTitle() (string, error) {
+	switch {
+	case runtime.GOOS == "darwin":
+		output, err := exec.Command("osascript", "-e", `tell application "System Events" to tell (first process whose frontmost is true)
+			return name & " - " & (name of front window)
+		end tell`).Output()
+		return strings.TrimSpace(string(output)), err
+	case os.Getenv("SWAYSOCK") != "":
+		output, err := exec.Command("swaymsg", "-t", "get_tree").Output()
+		if err != nil {
+			return "", err
+		}
+		return findFocusedSwayNode(output)
+	case os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "":
+		output, err := exec.Command("hyprctl", "activewindow", "-j").Output()
+		if err != nil {
+			return "", err
+		}
+		var window struct {
+			Title string `json:"title"`
+		}
+		err = json.Unmarshal(output, &window)
+		return window.Title, err
+	case os.Getenv("DISPLAY") != "":
+		output, err := exec.Command("xdotool", "getactivewindow", "getwindowname").Output()
+		if err != nil {
+			return "", errors.New("cannot read the active window (install xdotool on X11)")
+		}
+		return strings.TrimSpace(string(output)), nil
+	}
+	return "", errors.New("cannot read the active window (supported on macOS, X11, Sway and Hyprland)")
+}
+
+// Returns the name of the focused node of a Sway tree
+func findFocusedSwayNode(tree []byte) (string, error) {
+	type node struct {
+		Name          string `json:"name"`
+		Focused       bool   `json:"focused"`
+		Nodes         []node `json:"nodes"`
+		FloatingNodes []node `json:"floating_nodes"`
+	}
+	var root node
+	if err := json.Unmarshal(tree, &root); err != nil {
+		return "", err
+	}
+	nodes := []node{root}
+	for len(nodes) != 0 {
+		n := nodes[0]
+		nodes = append(append(nodes[1:], n.Nodes...), n.FloatingNodes...)
+		if n.Focused {
+			return n.Name, nil
+		}
+	}
+	return "", nil
+}
+
+func getWindowSamplesPath() string {
+	return getHomeFilePath(WINDOWS_NAME)
+}
+
+// Reads the samples of the active window, in chronological order
+// They are encrypted like the database
+func readWindowSamples() (samples []WindowSample, err error) {
+	content, err := files.ReadFile(getWindowSamplesPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read the window samples: %w", err)
+	}
+	if isEncryptedDatabase(content) {
+		if content, err = decryptDatabase(content); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read the window samples: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	for _, row := range rows[1:] {
+		at, err := time.Parse(TIME_FORMAT, row[0])
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read the window samples: %w", err)
+		}
+		samples = append(samples, WindowSample{at, row[1]})
+	}
+	return samples, nil
+}
+
+// Adds a sample, dropping those older than WINDOW_SAMPLES_KEPT
+func addWindowSample(sample WindowSample) error {
+	samples, err := readWindowSamples()
+	if err != nil {
+		return err
+	}
+	var content strings.Builder
+	content.WriteString(WINDOWS_HEADER)
+	for _, s := range append(samples, sample) {
+		if sample.at.Sub(s.at) < WINDOW_SAMPLES_KEPT {
+			content.WriteString(formatRecordFields(s.at.Format(TIME_FORMAT), s.title))
+		}
+	}
+
+	data := []byte(content.String())
+	if isEncryptionEnabled() {
+		if data, err = encryptDatabase(data); err != nil {
+			return err
+		}
+	}
+	if err = files.WriteFile(getWindowSamplesPath(), data, 0644); err != nil {
+		return fmt.Errorf("Cannot write the window samples: %w", err)
+	}
+	return nil
+}
+
+// Samples the active window at each check of the daemon, when daemon.track_windows is set
+type WindowWatcher struct {
+	disabled bool
+}
+
+// Saves the ticket suggested by the active window (see compileWindowRules) while no ticket is running,
+// for mate retro to suggest it for the untracked time
+// Only the suggested tickets are saved, never the window titles
+func (w *WindowWatcher) check() error {
+	if w.disabled || !getConfigBool("daemon.track_windows", false) {
+		return nil
+	}
+	records, err := getRecentRecords(getNow())
+	if err != nil {
+		return err
+	}
+	if _, untracked := getUntrackedSince(records); !untracked {
+		return nil
+	}
+	window, err := getActiveWindowTitle()
+	if err != nil {
+		fmt.Printf("Window tracking disabled: %v\n", err)
+		w.disabled = true
+		return nil
+	}
+	rules, _ := compileWindowRules(config)
+	title := matchWindowRules(window, rules)
+	if title == "" {
+		return nil
+	}
+	debugf("Active window \"%s\": %s", window, title)
+	return addWindowSample(WindowSample{getNow().Truncate(time.Second), title})
+}
+
+// Returns the ticket seen the most in the active window during a gap, and for about how long
+func suggestGapTicket(samples []WindowSample, gap Gap) (title string, seen time.Duration) {
+	counts := make(map[string]int)
+	for _, s := range samples {
+		if !s.at.Before(gap.start) && s.at.Before(gap.end) {
+			counts[s.title]++
+			if counts[s.title] > counts[title] || counts[s.title] == counts[title] && s.title < title {
+				title = s.title
+			}
+		}
+	}
+	return title, time.Duration(counts[title]) * getConfigDuration("daemon.interval", DEFAULT_DAEMON_INTERVAL)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Opcodes of the core protocol
+const (
+	X11_GRAB_KEY             = 33
+	X11_GET_INPUT_FOCUS      = 43
+	X11_GET_KEYBOARD_MAPPING = 101
+)
+
+// Masks of the modifiers in the state of the key events
+const (
+	X11_SHIFT = 1 << 0
+	X11_LOCK  = 1 << 1 // CapsLock
+	X11_CTRL  = 1 << 2
+	X11_MOD1  = 1 << 3 // Alt
+	X11_MOD2  = 1 << 4 // NumLock
+	X11_MOD4  = 1 << 6 // Super
+)
+
+const X11_KEY_PRESS = 2
+const X11_BAD_ACCESS = 10
+
+// A minimal connection to an X server: enough to grab keys on the root window and read their presses
+// It is only used by a single goroutine, requests and replies being in the byte order of the client
+type X11Conn struct {
+	conn       net.Conn
+	reader     *bufio.Reader
+	root       uint32
+	minKeycode byte
+	maxKeycode byte
+	sequence   uint16
+}
+
+// A press of a grabbed key, with the state of the modifiers
+type X11KeyPress struct {
+	keycode byte
+	state   uint16
+}
+
+// An error sent by the X server for a request
+type X11Error struct {
+	code     byte
+	sequence uint16
+}
+
+func (e X11Error) Error() string {
+	return fmt.Sprintf("X11 error %d for request %d", e.code, e.sequence)
+}
+
+// Connects to the display of $DISPLAY, authenticated by the cookie of the Xauthority file if there is one
+func connectX11() (*X11Conn, error) {
+	display := os.Getenv("DISPLAY")
+	colon := strings.LastIndex(display, ":")
+	if colon < 0 {
+		return nil, fmt.Errorf("no X11 display (DISPLAY is \"%s\")", display)
+	}
+	host, number := display[:colon], strings.SplitN(display[colon+1:], ".", 2)[0]
+	id, err := strconv.Atoi(number)
+	if err != nil {
+		return nil, fmt.Errorf("invalid display \"%s\"", display)
+	}
+	var conn net.Conn
+	if host == "" || host == "unix" {
+		conn, err = net.Dial("unix", "/tmp/.X11-unix/X"+number)
+	} else {
+		conn, err = net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(6000+id)))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	x := &X11Conn{conn: conn, reader: bufio.NewReader(conn)}
+	if err = x.setup(readXauthorityCookie(number)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return x, nil
+}
+
+// Returns the MIT-MAGIC-COOKIE-1 of a display in $XAUTHORITY (~/.Xauthority by default), nil if there is none
+func readXauthorityCookie(number string) []byte {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, ".Xauthority")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	// Entries of a family, an address, a display number, an authorization name and its data, each field prefixed by its length
+	for len(content) >= 2 {
+		var fields [4][]byte
+		content = content[2:]
+		for i := range fields {
+			if len(content) < 2 || len(content) < 2+int(binary.BigEndian.Uint16(content)) {
+				return nil
+			}
+			length := int(binary.BigEndian.Uint16(content))
+			fields[i], content = content[2:2+length], content[2+length:]
+		}
+		if string(fields[1]) == number && string(fields[2]) == "MIT-MAGIC-COOKIE-1" {
+			return fields[3]
+		}
+	}
+	return nil
+}
+
+// Pads a length to a multiple of 4 bytes
+func padX11(length int) int {
+	return (length + 3) &^ 3
+}
+
+// Sends the connection setup and reads the root window and the keycodes of the first screen
+func (x *X11Conn) setup(cookie []byte) error {
+	name := ""
+	if cookie != nil {
+		name = "MIT-MAGIC-COOKIE-1"
+	}
+	request := make([]byte, 12+padX11(len(name))+padX11(len(cookie)))
+	request[0] = 'l'
+	binary.LittleEndian.PutUint16(request[2:], 11)
+	binary.LittleEndian.PutUint16(request[6:], uint16(len(name)))
+	binary.LittleEndian.PutUint16(request[8:], uint16(len(cookie)))
+	copy(request[12:], name)
+	copy(request[12+padX11(len(name)):], cookie)
+	if _, err := x.conn.Write(request); err != nil {
+		return err
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(x.reader, header); err != nil {
+		return err
+	}
+	reply := make([]byte, 4*int(binary.LittleEndian.Uint16(header[6:])))
+	if _, err := io.ReadFull(x.reader, reply); err != nil {
+		return err
+	}
+	if header[0] != 1 {
+		reason := strings.TrimSpace(string(reply[:int(header[1])]))
+		return fmt.Errorf("the X server refused the connection: %s", reason)
+	}
+	if len(reply) < 32 {
+		return errors.New("invalid setup reply of the X server")
+	}
+	vendorLength, formats := int(binary.LittleEndian.Uint16(reply[16:])), int(reply[21])
+	x.minKeycode, x.maxKeycode = reply[26], reply[27]
+	screens := 32 + padX11(vendorLength) + 8*formats
+	if len(reply) < screens+4 {
+		return errors.New("no screen in the setup reply of the X server")
+	}
+	x.root = binary.LittleEndian.Uint32(reply[screens:])
+	return nil
+}
+
+// Sends a request, its data following the opcode and its length
+func (x *X11Conn) send(opcode byte, detail byte, data []byte) error {
+	request := make([]byte, 4+padX11(len(data)))
+	request[0], request[1] = opcode, detail
+	binary.LittleEndian.PutUint16(request[2:], uint16(len(request)/4))
+	copy(request[4:], data)
+	x.sequence++
+	_, err := x.conn.Write(request)
+	return err
+}
+
+// Reads the next reply, KeyPress event or error of the server, other events being skipped
+// Returns the reply with its 32 bytes header, an X11KeyPress or an X11Error
+func (x *X11Conn) read() (interface{}, error) {
+	for {
+		packet := make([]byte, 32)
+		if _, err := io.ReadFull(x.reader, packet); err != nil {
+			return nil, err
+		}
+		switch packet[0] & 0x7f {
+		case 0:
+			return X11Error{packet[1], binary.LittleEndian.Uint16(packet[2:])}, nil
+		case 1:
+			extra := make([]byte, 4*int(binary.LittleEndian.Uint32(packet[4:])))
+			if _, err := io.ReadFull(x.reader, extra); err != nil {
+				return nil, err
+			}
+			return append(packet, extra...), nil
+		case X11_KEY_PRESS:
+			return X11KeyPress{packet[1], binary.LittleEndian.Uint16(packet[28:])}, nil
+		}
+	}
+}
+
+// Waits for the reply of a request that was just sent, returning the first error of the requests before it
+// The key presses read meanwhile are dropped, as none are expected before the grabs are done
+func (x *X11Conn) waitReply() ([]byte, error) {
+	var failure error
+	for {
+		packet, err := x.read()
+		if err != nil {
+			return nil, err
+		}
+		switch packet := packet.(type) {
+		case X11Error:
+			if packet.sequence == x.sequence {
+				return nil, packet
+			}
+			if failure == nil {
+				failure = packet
+			}
+		case []byte:
+			return packet, failure
+		}
+	}
+}
+
+// Returns the keycodes producing each keysym, without modifiers
+func (x *X11Conn) getKeycodes() (map[uint32]byte, error) {
+	count := x.maxKeycode - x.minKeycode + 1
+	if err := x.send(X11_GET_KEYBOARD_MAPPING, 0, []byte{x.minKeycode, count, 0, 0}); err != nil {
+		return nil, err
+	}
+	reply, err := x.waitReply()
+	if err != nil {
+		return nil, err
+	}
+	perKeycode := int(reply[1])
+	keycodes := make(map[uint32]byte)
+	for i := 0; i < int(count) && 32+4*(i*perKeycode+1) <= len(reply); i++ {
+		// The first keysym of a keycode is the one without modifiers, the lower case of a letter
+		keysym := binary.LittleEndian.Uint32(reply[32+4*i*perKeycode:])
+		if _, found := keycodes[keysym]; keysym != 0 && !found {
+			keycodes[keysym] = x.minKeycode + byte(i)
+		}
+	}
+	return keycodes, nil
+}
+
+// Grabs a key with some modifiers on the root window, whatever the state of CapsLock and NumLock
+// Fails with the BadAccess error if another client already grabbed it
+func (x *X11Conn) grabKey(keycode byte, modifiers uint16) error {
+	for _, locks := range []uint16{0, X11_LOCK, X11_MOD2, X11_LOCK | X11_MOD2} {
+		data := make([]byte, 12)
+		binary.LittleEndian.PutUint32(data, x.root)
+		binary.LittleEndian.PutUint16(data[4:], modifiers|locks)
+		// Asynchronous pointer and keyboard modes
+		data[6], data[7], data[8] = keycode, 1, 1
+		if err := x.send(X11_GRAB_KEY, 0, data); err != nil {
+			return err
+		}
+	}
+	// The grabs have no reply, a request having one tells when they were all processed
+	if err := x.send(X11_GET_INPUT_FOCUS, 0, nil); err != nil {
+		return err
+	}
+	_, err := x.waitReply()
+	return err
+}
+
+func (x *X11Conn) close() {
+	x.conn.Close()
+}
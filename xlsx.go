@@ -0,0 +1,170 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const NO_PROJECT = "(no project)"
+
+// A cell of a sheet: a number if isNumber, a string otherwise
+type XLSXCell struct {
+	value    string
+	isNumber bool
+}
+
+type XLSXSheet struct {
+	name string
+	rows [][]XLSXCell
+}
+
+// Returns the letters of a column (0 is A, 26 is AA)
+func getColumnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+func escapeXML(literal string) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(literal))
+	return escaped.String()
+}
+
+func renderXLSXSheet(sheet XLSXSheet) string {
+	var content strings.Builder
+	content.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	content.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range sheet.rows {
+		content.WriteString(fmt.Sprintf(`<row r="%d">`, r+1))
+		for c, cell := range row {
+			ref := getColumnName(c) + strconv.Itoa(r+1)
+			if cell.isNumber {
+				content.WriteString(fmt.Sprintf(`<c r="%s"><v>%s</v></c>`, ref, cell.value))
+			} else if cell.value != "" {
+				content.WriteString(fmt.Sprintf(`<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, escapeXML(cell.value)))
+			}
+		}
+		content.WriteString(`</row>`)
+	}
+	content.WriteString(`</sheetData></worksheet>`)
+	return content.String()
+}
+
+// Writes a minimal Office Open XML workbook
+func writeXLSX(w io.Writer, sheets []XLSXSheet) error {
+	var sheetEntries, sheetRelationships, sheetOverrides strings.Builder
+	for i, sheet := range sheets {
+		sheetEntries.WriteString(fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escapeXML(sheet.name), i+1, i+1))
+		sheetRelationships.WriteString(fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1))
+		sheetOverrides.WriteString(fmt.Sprintf(`<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1))
+	}
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` + sheetOverrides.String() + `</Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheetEntries.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + sheetRelationships.String() + `</Relationships>`},
+	}
+	for i, sheet := range sheets {
+		files = append(files, struct {
+			name    string
+			content string
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), renderXLSXSheet(sheet)})
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err = f.Write([]byte(file.content)); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// Builds the sheet of the intervals, durations being numbers of hours
+func buildEntriesSheet(intervals []Interval, ticketClients map[string]string, notes []Note) XLSXSheet {
+	sheet := XLSXSheet{name: "Entries"}
+	for r, row := range buildIntervalRows(intervals, ticketClients, notes) {
+		var cells []XLSXCell
+		for c, value := range row {
+			cells = append(cells, XLSXCell{value, r != 0 && c == 2})
+		}
+		sheet.rows = append(sheet.rows, cells)
+	}
+	return sheet
+}
+
+// Builds the summary sheet: the hours per week (rows) and per project (columns), with totals
+func buildSummarySheet(intervals []Interval) XLSXSheet {
+	perWeek := make(map[string]map[string]time.Duration)
+	var weeks, projects []string
+	for _, in := range intervals {
+		week := getWeekStart(in.start.Truncate(time.Hour * 24)).Format(DATE_FORMAT)
+		project := getTicketProject(in.title)
+		if project == "" {
+			project = NO_PROJECT
+		}
+		if perWeek[week] == nil {
+			perWeek[week] = make(map[string]time.Duration)
+			weeks = append(weeks, week)
+		}
+		if !contains(projects, project) {
+			projects = append(projects, project)
+		}
+		perWeek[week][project] += in.end.Sub(in.start)
+	}
+	sort.Strings(weeks)
+	sort.Strings(projects)
+
+	hours := func(duration time.Duration) XLSXCell {
+		return XLSXCell{strconv.FormatFloat(duration.Hours(), 'f', 2, 64), true}
+	}
+
+	header := []XLSXCell{{"Week", false}}
+	for _, project := range projects {
+		header = append(header, XLSXCell{project, false})
+	}
+	sheet := XLSXSheet{name: "Summary", rows: [][]XLSXCell{append(header, XLSXCell{"Total", false})}}
+
+	projectTotals := make(map[string]time.Duration)
+	var total time.Duration
+	for _, week := range weeks {
+		row := []XLSXCell{{week, false}}
+		var weekTotal time.Duration
+		for _, project := range projects {
+			row = append(row, hours(perWeek[week][project]))
+			weekTotal += perWeek[week][project]
+			projectTotals[project] += perWeek[week][project]
+		}
+		total += weekTotal
+		sheet.rows = append(sheet.rows, append(row, hours(weekTotal)))
+	}
+
+	totalRow := []XLSXCell{{"Total", false}}
+	for _, project := range projects {
+		totalRow = append(totalRow, hours(projectTotals[project]))
+	}
+	sheet.rows = append(sheet.rows, append(totalRow, hours(total)))
+	return sheet
+}